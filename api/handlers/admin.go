@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/jobs"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/gin-gonic/gin"
+)
+
+func CollectGarbage(gc *jobs.GarbageCollector, dryRun bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := gc.Collect(c.Request.Context(), dryRun)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Something went wrong"})
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+func UpdateUserQuota(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+
+		var quota types.Quota
+		if err := c.ShouldBindJSON(&quota); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, err := db.UpdateUserQuota(c.Request.Context(), uuid, quota)
+		if err != nil {
+			c.Error(err)
+			switch {
+			case err == sql.ErrNoRows:
+				c.JSON(http.StatusNotFound, map[string]interface{}{"error": fmt.Sprintf("Invalid UUID: %s", uuid)})
+			default:
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Something went wrong"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, user.Quota())
+	}
+}
@@ -3,9 +3,9 @@ package handlers
 import (
 	"context"
 	"database/sql"
-	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -14,32 +14,29 @@ import (
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
-	"github.com/docker/docker/api/types/registry"
 	"github.com/gin-gonic/gin"
 )
 
-func GetDeployment(db *database.Database) gin.HandlerFunc {
+func GetDeployment(db database.Repository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		uuid := c.Param("uuid")
-
-		deployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		deployment, _, ok := ownedDeployment(c, db)
+		if !ok {
+			return
+		}
 
+		instances, err := db.GetDeploymentInstances(c.Request.Context(), *deployment.UUID)
 		if err != nil {
 			c.Error(err)
-			switch {
-			case err == sql.ErrNoRows:
-				c.JSON(http.StatusNotFound, map[string]interface{}{"error": fmt.Sprintf("Invalid UUID: %s", uuid)})
-			default:
-				c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Something went wrong"})
-			}
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Something went wrong"})
 			return
 		}
+		deployment.Instances = instances
 
-		c.JSON(http.StatusOK, deployment)
+		c.JSON(http.StatusOK, deployment.Redacted())
 	}
 }
 
-func GetAllDeploymentsForUser(db *database.Database) gin.HandlerFunc {
+func GetAllDeploymentsForUser(db database.Repository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userUUID, doesUserUUIDExists := c.Get("userUUID")
 
@@ -66,11 +63,15 @@ func GetAllDeploymentsForUser(db *database.Database) gin.HandlerFunc {
 			return
 		}
 
+		for i := range deployments {
+			deployments[i] = deployments[i].Redacted()
+		}
+
 		c.JSON(http.StatusOK, deployments)
 	}
 }
 
-func CreateDeployment(db *database.Database, docker *services.DockerService, taskDispatcher *queue.TaskDispatcher) gin.HandlerFunc {
+func CreateDeployment(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var deploymentReq types.CreateDeploymentRequest
 		if err := c.ShouldBindJSON(&deploymentReq); err != nil {
@@ -85,11 +86,84 @@ func CreateDeployment(db *database.Database, docker *services.DockerService, tas
 			return
 		}
 
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if err := types.ValidateSubdomain(deploymentReq.Subdomain); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		if _, err := db.GetDeployment(c.Request.Context(), deploymentReq.Subdomain); err == nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Subdomain already exists"})
 			return
 		}
 
+		var resources types.ResourceSpec
+		if deploymentReq.Resources != nil {
+			resources = *deploymentReq.Resources
+		}
+
+		resources = resources.WithDefaults()
+		if err := resources.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		replicas := 1
+		if deploymentReq.Replicas != nil {
+			replicas = *deploymentReq.Replicas
+		}
+
+		var healthCheck *types.HealthCheck
+		if deploymentReq.HealthCheck != nil {
+			withDefaults := deploymentReq.HealthCheck.WithDefaults()
+			if err := withDefaults.Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			healthCheck = &withDefaults
+		}
+
+		var middlewares *types.MiddlewareSpec
+		if deploymentReq.Middlewares != nil && !deploymentReq.Middlewares.IsZero() {
+			withDefaults, err := deploymentReq.Middlewares.WithDefaults()
+			if err != nil {
+				c.Error(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if err := withDefaults.Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			middlewares = &withDefaults
+		}
+
+		routing := deploymentReq.Routing.ForSubdomain(deploymentReq.Subdomain)
+		if routing != nil {
+			if err := routing.Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		existingDeployments, err := db.GetAllDeploymentsForUser(c.Request.Context(), *user.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if conflict := routingConflict(existingDeployments, "", deploymentReq.Subdomain, routing); conflict != "" {
+			c.JSON(http.StatusConflict, gin.H{"error": conflict})
+			return
+		}
+
 		if len(deploymentReq.EnvConfig) == 0 {
 			deploymentReq.EnvConfig = make(map[string]string)
 		}
@@ -115,37 +189,55 @@ func CreateDeployment(db *database.Database, docker *services.DockerService, tas
 			deploymentReq.EnvConfig["PORT"] = strconv.Itoa(availablePort)
 		}
 
-		var envArray []string
-		for key, value := range deploymentReq.EnvConfig {
-			envArray = append(envArray, fmt.Sprintf("%s=%s", key, value))
+		envConfig := types.EnvConfig(deploymentReq.EnvConfig)
+		if !secretsExist(c, db, *user.UUID, envConfig) {
+			return
 		}
 
-		authString := ""
-		if deploymentReq.DockerAuth != nil {
-			authConfig := registry.AuthConfig{Username: deploymentReq.DockerAuth.Username, Password: deploymentReq.DockerAuth.Password}
+		envArray := envConfig.Array()
 
-			encodedJSON, err := json.Marshal(authConfig)
-			if err != nil {
-				panic(err)
+		if deploymentReq.DockerAuth != nil && !dockerAuthAllowed(c, db, user, deploymentReq.ImageTag, deploymentReq.DockerAuth.Username, deploymentReq.DockerAuth.Password, deploymentReq.DockerAuth.Replace) {
+			return
+		}
+
+		deployment, err := db.CreateDeployment(c.Request.Context(), types.DeploymentAttributes{UserUUID: *user.UUID, Subdomain: deploymentReq.Subdomain, ImageTag: deploymentReq.ImageTag, Status: types.DEPLOYMENT_STATUS_PENDING, Port: &containerPort, Replicas: replicas, ResourceSpec: resources, HealthCheck: healthCheck, Routing: routing, Middlewares: middlewares, EnvConfig: envConfig})
+		if err != nil {
+			c.Error(err)
+			var quotaErr types.QuotaExceededError
+			switch {
+			case errors.As(err, &quotaErr):
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			}
+			return
+		}
 
-			authString = base64.URLEncoding.EncodeToString(encodedJSON)
+		// The credentials are only stored once the deployment is accepted
+		registryCredential := ""
+		if deploymentReq.DockerAuth != nil {
+			registryCredential, err = storeDockerAuth(c.Request.Context(), db, user, deploymentReq.ImageTag, deploymentReq.DockerAuth.Username, deploymentReq.DockerAuth.Password)
+			if err != nil {
+				c.Error(err)
+				failDeployment(c.Request.Context(), db, *deployment.UUID, fmt.Sprintf("Could not store the registry credentials: %s", err.Error()))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not store the registry credentials"})
+				return
+			}
 		}
 
-		deployment, err := db.CreateDeployment(c.Request.Context(), types.DeploymentAttributes{UserUUID: userUUID.(string), Subdomain: deploymentReq.Subdomain, ImageTag: deploymentReq.ImageTag, ContainerId: nil, Status: "PENDING", Port: &containerPort})
+		taskUUID, err := taskDispatcher.Enqueue(queue.CreateDeploymentTask{Db: db, Docker: docker, DeploymentUUID: *deployment.UUID, ImageTag: *deployment.ImageTag, Subdomain: *deployment.Subdomain, EnvArray: envArray, ContainerPort: containerPort, RegistryCredential: registryCredential, Resources: resources, Replicas: replicas, HealthCheck: healthCheck, Routing: routing, Middlewares: middlewares, CreatedBy: user.UUID})
 		if err != nil {
 			c.Error(err)
+			failDeployment(c.Request.Context(), db, *deployment.UUID, fmt.Sprintf("Could not queue the task: %s", err.Error()))
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		taskDispatcher.Enqueue(queue.CreateDeploymentTask{Db: db, Docker: docker, DeploymentAttributes: &deployment, ImageTag: *deployment.ImageTag, Subdomain: *deployment.Subdomain, EnvArray: envArray, ContainerPort: containerPort, AuthString: authString})
-
-		c.JSON(http.StatusOK, gin.H{"uuid": deployment.UUID, "status": deployment.Status})
+		c.JSON(http.StatusOK, gin.H{"uuid": deployment.UUID, "status": deployment.Status, "taskId": taskUUID})
 	}
 }
 
-func UpdateDeployment(db *database.Database, docker *services.DockerService, taskDispatcher *queue.TaskDispatcher) gin.HandlerFunc {
+func UpdateDeployment(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		uuid := c.Param("uuid")
 		var updateDeploymentReq types.UpdateDeploymentRequest
@@ -180,24 +272,66 @@ func UpdateDeployment(db *database.Database, docker *services.DockerService, tas
 			return
 		}
 
-		existingContainerEnv, err := docker.GetContainerEnv(c, *existingDeployment.ContainerId)
+		if err := types.CheckTransition(*existingDeployment.Status, types.DEPLOYMENT_STATUS_UPDATING); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		// The update builds on the updates still queued, their tasks haven't changed the stored configuration yet
+		desired, err := queue.DesiredConfig(c.Request.Context(), db, existingDeployment)
 		if err != nil {
 			c.Error(err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		var envMap map[string]string = existingContainerEnv
-		var imageTag string = *existingDeployment.ImageTag
-		var subdomain string = *existingDeployment.Subdomain
-		var containerPort int = *existingDeployment.Port
+		var envConfig types.EnvConfig = desired.EnvConfig
+		var imageTag string = desired.ImageTag
+		var subdomain string = desired.Subdomain
+		var containerPort int = desired.Port
+		var resources types.ResourceSpec = desired.Resources
+		var replicas int = desired.Replicas
+		var healthCheck *types.HealthCheck = desired.HealthCheck
+		var routing *types.RoutingSpec = desired.Routing
+		var middlewares *types.MiddlewareSpec = desired.Middlewares
+
+		if updateDeploymentReq.HealthCheck != nil {
+			withDefaults := updateDeploymentReq.HealthCheck.WithDefaults()
+			if err := withDefaults.Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			healthCheck = &withDefaults
+		}
 
-		if len(*updateDeploymentReq.EnvConfig) != 0 {
-			envMap = utils.MergeMaps(existingContainerEnv, *updateDeploymentReq.EnvConfig)
+		if updateDeploymentReq.Replicas != nil {
+			replicas = *updateDeploymentReq.Replicas
+		}
+
+		// The quota is only checked when the update changes what the deployment uses
+		var usage *types.Usage
+		if updateDeploymentReq.Resources != nil || updateDeploymentReq.Replicas != nil {
+			if updateDeploymentReq.Resources != nil {
+				resources = updateDeploymentReq.Resources.WithDefaults()
+				if err := resources.Validate(); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+			}
 
-			providedPortStr, exists := (*updateDeploymentReq.EnvConfig)["PORT"]
+			resourceUsage := types.ResourceUsage(resources, replicas)
+			usage = &resourceUsage
+		}
+
+		if len(updateDeploymentReq.EnvConfig) != 0 {
+			providedPortStr, exists := updateDeploymentReq.EnvConfig["PORT"]
 			if exists {
-				providedPort, err := strconv.Atoi(providedPortStr)
+				if providedPortStr == nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "PORT can't be removed"})
+					return
+				}
+
+				providedPort, err := strconv.Atoi(*providedPortStr)
 				if err != nil {
 					c.Error(err)
 					c.JSON(http.StatusBadRequest, gin.H{"error": "The value of port must be a valid port number"})
@@ -205,18 +339,24 @@ func UpdateDeployment(db *database.Database, docker *services.DockerService, tas
 				}
 				containerPort = providedPort
 			}
+
+			envConfig = utils.PatchMap(envConfig, updateDeploymentReq.EnvConfig)
 		}
 
-		var envArray []string
-		for key, value := range envMap {
-			envArray = append(envArray, fmt.Sprintf("%s=%s", key, value))
+		if !secretsExist(c, db, *user.UUID, envConfig) {
+			return
 		}
 
 		if updateDeploymentReq.ImageTag != nil {
 			imageTag = *updateDeploymentReq.ImageTag
 		}
 
-		if updateDeploymentReq.Subdomain != nil && *updateDeploymentReq.Subdomain != *existingDeployment.Subdomain {
+		if updateDeploymentReq.Subdomain != nil && *updateDeploymentReq.Subdomain != subdomain {
+			if err := types.ValidateSubdomain(*updateDeploymentReq.Subdomain); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
 			if _, err := db.GetDeployment(c.Request.Context(), *updateDeploymentReq.Subdomain); err == nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "Subdomain already exists"})
 				return
@@ -225,25 +365,80 @@ func UpdateDeployment(db *database.Database, docker *services.DockerService, tas
 			}
 		}
 
-		authString := ""
-		if updateDeploymentReq.DockerAuth != nil {
-			authConfig := registry.AuthConfig{Username: updateDeploymentReq.DockerAuth.Username, Password: updateDeploymentReq.DockerAuth.Password}
+		if updateDeploymentReq.Routing != nil {
+			if err := updateDeploymentReq.Routing.Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			routing = updateDeploymentReq.Routing
+		}
+		routing = routing.ForSubdomain(subdomain)
 
-			encodedJSON, err := json.Marshal(authConfig)
-			if err != nil {
-				panic(err)
+		if updateDeploymentReq.Middlewares != nil {
+			middlewares = nil
+			if !updateDeploymentReq.Middlewares.IsZero() {
+				withDefaults, err := updateDeploymentReq.Middlewares.WithDefaults()
+				if err != nil {
+					c.Error(err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				if err := withDefaults.Validate(); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				middlewares = &withDefaults
 			}
+		}
+
+		existingDeployments, err := db.GetAllDeploymentsForUser(c.Request.Context(), *user.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if conflict := routingConflict(existingDeployments, *existingDeployment.UUID, subdomain, routing); conflict != "" {
+			c.JSON(http.StatusConflict, gin.H{"error": conflict})
+			return
+		}
+
+		if subdomain != *existingDeployment.Subdomain {
+			if dependent := routedOn(existingDeployments, existingDeployment); dependent != "" {
+				c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Deployment %s is routed on this deployment's subdomain", dependent)})
+				return
+			}
+		}
+
+		if updateDeploymentReq.DockerAuth != nil && !dockerAuthAllowed(c, db, user, imageTag, updateDeploymentReq.DockerAuth.Username, updateDeploymentReq.DockerAuth.Password, updateDeploymentReq.DockerAuth.Replace) {
+			return
+		}
 
-			authString = base64.URLEncoding.EncodeToString(encodedJSON)
+		update := types.DeploymentConfig{Token: desired.Token, ImageTag: imageTag, Subdomain: subdomain, Port: containerPort, Replicas: replicas, EnvConfig: envConfig, Resources: resources, HealthCheck: healthCheck, Routing: routing, Middlewares: middlewares}
+		if !reserveUpdate(c, db, existingDeployment, update, usage) {
+			return
 		}
 
-		taskDispatcher.Enqueue(queue.UpdateDeploymentTask{Db: db, Docker: docker, DeploymentAttributes: &existingDeployment, ImageTag: imageTag, Subdomain: subdomain, EnvArray: envArray, ContainerPort: containerPort, AuthString: authString})
+		task := updateTask(db, docker, *existingDeployment.UUID, update)
+		task.CreatedBy = user.UUID
+		if updateDeploymentReq.DockerAuth != nil {
+			registryCredential, ok := storeUpdateDockerAuth(c, db, user, existingDeployment, imageTag, updateDeploymentReq.DockerAuth.Username, updateDeploymentReq.DockerAuth.Password)
+			if !ok {
+				return
+			}
+			task.RegistryCredential = registryCredential
+		}
 
-		c.JSON(http.StatusOK, gin.H{"uuid": existingDeployment.UUID, "update_queued": true})
+		taskUUID, ok := enqueue(c, db, taskDispatcher, existingDeployment, task)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"uuid": existingDeployment.UUID, "update_queued": true, "taskId": taskUUID})
 	}
 }
 
-func DeleteDeployment(db *database.Database, docker *services.DockerService, taskDispatcher *queue.TaskDispatcher) gin.HandlerFunc {
+func DeleteDeployment(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		uuid := c.Param("uuid")
 
@@ -271,19 +466,509 @@ func DeleteDeployment(db *database.Database, docker *services.DockerService, tas
 			return
 		}
 
-		if *existingDeployment.Status == "DELETING" {
+		if *existingDeployment.Status == types.DEPLOYMENT_STATUS_DELETING {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Deployment is already being deleted"})
 			return
 		}
 
-		if _, err := db.UpdateDeployment(context.Background(), types.DeploymentAttributes{UUID: *existingDeployment.UUID, ImageTag: *existingDeployment.ImageTag, Subdomain: *existingDeployment.Subdomain, Port: existingDeployment.Port, ContainerId: existingDeployment.ContainerId, Status: "DELETING"}); err != nil {
+		existingDeployments, err := db.GetAllDeploymentsForUser(c.Request.Context(), *user.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if dependent := routedOn(existingDeployments, existingDeployment); dependent != "" {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Deployment %s is routed on this deployment's subdomain", dependent)})
+			return
+		}
+
+		taskUUID, ok := queueWithStatus(c, db, taskDispatcher, existingDeployment, types.DEPLOYMENT_STATUS_DELETING, queue.DeleteDeploymentTask{Db: db, Docker: docker, DeploymentUUID: *existingDeployment.UUID})
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"uuid": existingDeployment.UUID, "taskId": taskUUID})
+	}
+}
+
+func ScaleDeployment(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+		var scaleDeploymentReq types.ScaleDeploymentRequest
+		if err := c.ShouldBindJSON(&scaleDeploymentReq); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingDeployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if *existingDeployment.UserId != *user.ID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		if err := types.CheckTransition(*existingDeployment.Status, types.DEPLOYMENT_STATUS_UPDATING); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		update, err := queue.DesiredConfig(c.Request.Context(), db, existingDeployment)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		update.Replicas = scaleDeploymentReq.Replicas
+
+		usage := types.ResourceUsage(update.Resources, update.Replicas)
+		taskUUID, ok := queueUpdate(c, db, taskDispatcher, existingDeployment, update, &usage, queue.ScaleDeploymentTask{Db: db, Docker: docker, DeploymentUUID: *existingDeployment.UUID, Replicas: update.Replicas, Token: update.Token})
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"uuid": existingDeployment.UUID, "replicas": scaleDeploymentReq.Replicas, "taskId": taskUUID})
+	}
+}
+
+func GetDeploymentRevisions(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		existingDeployment, _, ok := ownedDeployment(c, db)
+		if !ok {
+			return
+		}
+
+		revisions, err := db.GetDeploymentRevisions(c.Request.Context(), *existingDeployment.UUID)
+		if err != nil {
 			c.Error(err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		taskDispatcher.Enqueue(queue.DeleteDeploymentTask{Db: db, Docker: docker, DeploymentAttributes: &existingDeployment})
+		for i := range revisions {
+			revisions[i] = revisions[i].Redacted()
+		}
+
+		c.JSON(http.StatusOK, revisions)
+	}
+}
+
+// RollbackDeployment redeploys the configuration of an earlier revision through an update task,
+// keeping the current number of replicas. The rollback itself becomes a new revision.
+func RollbackDeployment(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rollbackDeploymentReq types.RollbackDeploymentRequest
+		if err := c.ShouldBindJSON(&rollbackDeploymentReq); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		existingDeployment, user, ok := ownedDeployment(c, db)
+		if !ok {
+			return
+		}
+
+		if err := types.CheckTransition(*existingDeployment.Status, types.DEPLOYMENT_STATUS_UPDATING); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		revision, err := db.GetDeploymentRevision(c.Request.Context(), *existingDeployment.UUID, rollbackDeploymentReq.Revision)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid revision"})
+			return
+		}
+
+		if *revision.Subdomain != *existingDeployment.Subdomain {
+			if _, err := db.GetDeployment(c.Request.Context(), *revision.Subdomain); err == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Subdomain of the revision is now used by another deployment"})
+				return
+			}
+		}
+
+		existingDeployments, err := db.GetAllDeploymentsForUser(c.Request.Context(), *user.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if conflict := routingConflict(existingDeployments, *existingDeployment.UUID, *revision.Subdomain, revision.Routing); conflict != "" {
+			c.JSON(http.StatusConflict, gin.H{"error": conflict})
+			return
+		}
+
+		if *revision.Subdomain != *existingDeployment.Subdomain {
+			if dependent := routedOn(existingDeployments, existingDeployment); dependent != "" {
+				c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Deployment %s is routed on this deployment's subdomain", dependent)})
+				return
+			}
+		}
+
+		if !secretsExist(c, db, *user.UUID, revision.EnvConfig) {
+			return
+		}
+
+		if rollbackDeploymentReq.DockerAuth != nil && !dockerAuthAllowed(c, db, user, *revision.ImageTag, rollbackDeploymentReq.DockerAuth.Username, rollbackDeploymentReq.DockerAuth.Password, rollbackDeploymentReq.DockerAuth.Replace) {
+			return
+		}
+
+		desired, err := queue.DesiredConfig(c.Request.Context(), db, existingDeployment)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		update := types.DeploymentConfig{Token: desired.Token, ImageTag: *revision.ImageTag, Subdomain: *revision.Subdomain, Port: *revision.Port, Replicas: desired.Replicas, EnvConfig: revision.EnvConfig, Resources: revision.ResourceSpec, HealthCheck: revision.HealthCheck, Routing: revision.Routing, Middlewares: revision.Middlewares}
+		usage := types.ResourceUsage(update.Resources, update.Replicas)
+		if !reserveUpdate(c, db, existingDeployment, update, &usage) {
+			return
+		}
+
+		task := updateTask(db, docker, *existingDeployment.UUID, update)
+		task.RolledBackFrom = revision.Revision
+		task.CreatedBy = user.UUID
+		// Credentials sent with a request aren't part of a revision, they are stored like any other registry credential
+		if rollbackDeploymentReq.DockerAuth != nil {
+			registryCredential, ok := storeUpdateDockerAuth(c, db, user, existingDeployment, *revision.ImageTag, rollbackDeploymentReq.DockerAuth.Username, rollbackDeploymentReq.DockerAuth.Password)
+			if !ok {
+				return
+			}
+			task.RegistryCredential = registryCredential
+		}
+
+		taskUUID, ok := enqueue(c, db, taskDispatcher, existingDeployment, task)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"uuid": existingDeployment.UUID, "revision": revision.Revision, "taskId": taskUUID})
+	}
+}
+
+// ownedDeployment loads the deployment of the uuid parameter and the requesting user. It responds and returns false
+// when the deployment doesn't exist or belongs to someone else.
+func ownedDeployment(c *gin.Context, db database.Repository) (types.Deployment, types.User, bool) {
+	userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+	if !doesUserUUIDExists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return types.Deployment{}, types.User{}, false
+	}
+
+	existingDeployment, err := db.GetDeployment(c.Request.Context(), c.Param("uuid"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+		return types.Deployment{}, types.User{}, false
+	}
+
+	user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return types.Deployment{}, types.User{}, false
+	}
+
+	if *existingDeployment.UserId != *user.ID {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return types.Deployment{}, types.User{}, false
+	}
+
+	return existingDeployment, user, true
+}
+
+// routingConflict returns why a deployment can't be routed on subdomain with routing, or an empty string when it can.
+// Only the user's own subdomains can be shared, and no two deployments on one can match exactly the same requests.
+// deploymentUUID is empty for a deployment that doesn't exist yet.
+func routingConflict(userDeployments []types.Deployment, deploymentUUID string, subdomain string, routing *types.RoutingSpec) string {
+	candidate := types.Deployment{Subdomain: &subdomain, Routing: routing}
+	host := candidate.RoutingSubdomain()
+
+	owned := host == subdomain
+	for _, deployment := range userDeployments {
+		if *deployment.UUID == deploymentUUID {
+			continue
+		}
+
+		if *deployment.Subdomain == host {
+			owned = true
+		}
+
+		if deployment.RoutingSubdomain() == host && deployment.RoutingMatchers() == candidate.RoutingMatchers() {
+			return fmt.Sprintf("Deployment %s is already routed on %s with the same rule", *deployment.Subdomain, host)
+		}
+	}
+
+	if !owned {
+		return fmt.Sprintf("Subdomain %s doesn't belong to any of your deployments", host)
+	}
+
+	return ""
+}
+
+// routedOn returns the subdomain of another deployment served on the deployment's subdomain, which has to stay
+// while it is. It's empty when there is none.
+func routedOn(userDeployments []types.Deployment, deployment types.Deployment) string {
+	for _, other := range userDeployments {
+		if *other.UUID != *deployment.UUID && other.RoutingSubdomain() == *deployment.Subdomain {
+			return *other.Subdomain
+		}
+	}
+	return ""
+}
+
+func StopDeployment(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher) gin.HandlerFunc {
+	return deploymentAction(db, taskDispatcher, types.DEPLOYMENT_STATUS_STOPPED, func(deploymentUUID string) queue.Task {
+		return queue.StopDeploymentTask{Db: db, Docker: docker, DeploymentUUID: deploymentUUID}
+	})
+}
+
+func StartDeployment(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher) gin.HandlerFunc {
+	return deploymentAction(db, taskDispatcher, types.DEPLOYMENT_STATUS_READY, func(deploymentUUID string) queue.Task {
+		return queue.StartDeploymentTask{Db: db, Docker: docker, DeploymentUUID: deploymentUUID}
+	})
+}
+
+func RestartDeployment(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher) gin.HandlerFunc {
+	return deploymentAction(db, taskDispatcher, types.DEPLOYMENT_STATUS_READY, func(deploymentUUID string) queue.Task {
+		return queue.RestartDeploymentTask{Db: db, Docker: docker, DeploymentUUID: deploymentUUID}
+	})
+}
+
+// deploymentAction enqueues the task built by newTask if the deployment can move to status once it's done.
+func deploymentAction(db database.Repository, taskDispatcher queue.Dispatcher, status string, newTask func(deploymentUUID string) queue.Task) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingDeployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if *existingDeployment.UserId != *user.ID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		// READY can be reached from PROVISIONING and UPDATING, but starting or restarting a deployment halfway
+		// through being provisioned would act on containers the task is still replacing
+		if types.InProgress(*existingDeployment.Status) {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Deployment is %s, wait for it to finish", *existingDeployment.Status)})
+			return
+		}
+
+		if err := types.CheckTransition(*existingDeployment.Status, status); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Starting a deployment without containers, e.g. one whose create failed, would mark it READY with nothing running
+		if status == types.DEPLOYMENT_STATUS_READY {
+			instances, err := db.GetDeploymentInstances(c.Request.Context(), *existingDeployment.UUID)
+			if err != nil {
+				c.Error(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			if len(instances) == 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": queue.ErrNoInstances.Error()})
+				return
+			}
+		}
+
+		taskUUID, err := taskDispatcher.Enqueue(newTask(*existingDeployment.UUID))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"uuid": existingDeployment.UUID, "taskId": taskUUID})
+	}
+}
+
+// redeployDeployment queues an update with the deployment's current configuration, since labels can only be changed
+// by replacing the containers, e.g. after a domain or port was added. Nothing is queued when a pending task or the next
+// provisioning of a deployment that can't be updated right now will pick up the change anyway, the returned task uuid
+// is empty then.
+func redeployDeployment(ctx context.Context, db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher, deployment types.Deployment, userUUID *string) (string, error) {
+	pending, err := db.HasPendingTasks(ctx, *deployment.UUID)
+	if err != nil {
+		return "", err
+	}
+
+	if pending || types.CheckTransition(*deployment.Status, types.DEPLOYMENT_STATUS_UPDATING) != nil {
+		return "", nil
+	}
+
+	update, err := queue.DesiredConfig(ctx, db, deployment)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := db.ReserveDeploymentUpdate(ctx, *deployment.UUID, update, nil); err != nil {
+		var transitionErr types.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	task := updateTask(db, docker, *deployment.UUID, update)
+	task.CreatedBy = userUUID
+	task.Redeploy = true
+
+	taskUUID, err := taskDispatcher.Enqueue(task)
+	if err != nil {
+		revertStatus(ctx, db, deployment, err)
+		return "", err
+	}
+
+	return taskUUID, nil
+}
+
+// updateTask builds the task that moves a deployment to update.
+func updateTask(db database.Repository, docker services.ContainerRuntime, deploymentUUID string, update types.DeploymentConfig) queue.UpdateDeploymentTask {
+	return queue.UpdateDeploymentTask{Db: db, Docker: docker, DeploymentUUID: deploymentUUID, ImageTag: update.ImageTag, Subdomain: update.Subdomain, EnvArray: update.EnvConfig.Array(), ContainerPort: update.Port, Resources: update.Resources, Replicas: update.Replicas, HealthCheck: update.HealthCheck, Routing: update.Routing, Middlewares: update.Middlewares, Token: update.Token}
+}
+
+// queueWithStatus moves the deployment to status and queues task, so that the status reflects the task as soon as
+// it's accepted. It responds and returns false when the deployment can't move to status or the task can't be queued.
+func queueWithStatus(c *gin.Context, db database.Repository, taskDispatcher queue.Dispatcher, deployment types.Deployment, status string, task queue.Task) (string, bool) {
+	if _, err := db.UpdateDeploymentStatus(c.Request.Context(), *deployment.UUID, status, nil); err != nil {
+		respondStatusError(c, err)
+		return "", false
+	}
+
+	return enqueue(c, db, taskDispatcher, deployment, task)
+}
+
+// queueUpdate queues the task of an update like queueWithStatus does for UPDATING. The update becomes the deployment's
+// pending update, which later updates build on, and usage, when set, is reserved against the user's quota. It also
+// responds and returns false when the usage exceeds the quota.
+func queueUpdate(c *gin.Context, db database.Repository, taskDispatcher queue.Dispatcher, deployment types.Deployment, update types.DeploymentConfig, usage *types.Usage, task queue.Task) (string, bool) {
+	if !reserveUpdate(c, db, deployment, update, usage) {
+		return "", false
+	}
+
+	return enqueue(c, db, taskDispatcher, deployment, task)
+}
+
+// reserveUpdate makes update the pending update of a deployment, responding and returning false when it can't.
+func reserveUpdate(c *gin.Context, db database.Repository, deployment types.Deployment, update types.DeploymentConfig, usage *types.Usage) bool {
+	if _, err := db.ReserveDeploymentUpdate(c.Request.Context(), *deployment.UUID, update, usage); err != nil {
+		respondStatusError(c, err)
+		return false
+	}
+
+	return true
+}
+
+// storeUpdateDockerAuth stores the dockerAuth of an update that was reserved, putting the deployment back when it can't.
+func storeUpdateDockerAuth(c *gin.Context, db database.Repository, user types.User, deployment types.Deployment, image string, username string, password string) (string, bool) {
+	registryCredential, err := storeDockerAuth(c.Request.Context(), db, user, image, username, password)
+	if err != nil {
+		c.Error(err)
+		revertStatus(c.Request.Context(), db, deployment, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not store the registry credentials"})
+		return "", false
+	}
+
+	return registryCredential, true
+}
+
+func respondStatusError(c *gin.Context, err error) {
+	c.Error(err)
+	var transitionErr types.InvalidTransitionError
+	var quotaErr types.QuotaExceededError
+	switch {
+	case errors.As(err, &transitionErr):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.As(err, &quotaErr):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// enqueue queues task for a deployment whose status was already moved, putting it back when the task can't be queued.
+func enqueue(c *gin.Context, db database.Repository, taskDispatcher queue.Dispatcher, deployment types.Deployment, task queue.Task) (string, bool) {
+	taskUUID, err := taskDispatcher.Enqueue(task)
+	if err != nil {
+		c.Error(err)
+		revertStatus(c.Request.Context(), db, deployment, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return "", false
+	}
+
+	return taskUUID, true
+}
+
+// failDeployment marks a deployment that was just created FAILED, nothing would ever move it out of PENDING.
+func failDeployment(ctx context.Context, db database.Repository, deploymentUUID string, reason string) {
+	if _, err := db.UpdateDeploymentStatus(ctx, deploymentUUID, types.DEPLOYMENT_STATUS_FAILED, &reason); err != nil {
+		log.Printf("error updating deployment status: %s\n", err.Error())
+	}
+}
+
+// revertStatus puts a deployment back in the status it had before a task that couldn't be queued. When the transition
+// table doesn't allow going back, e.g. from UPDATING to CRASHED, the deployment is marked FAILED so it can be retried.
+// A deployment that was marked DELETING stays so, the delete has to be retried. When an earlier update is still queued,
+// its configuration becomes the pending update again.
+func revertStatus(ctx context.Context, db database.Repository, deployment types.Deployment, cause error) {
+	if deployment.PendingUpdate != nil {
+		if _, err := db.ReserveDeploymentUpdate(ctx, *deployment.UUID, *deployment.PendingUpdate, nil); err != nil {
+			log.Printf("error reverting deployment status: %s\n", err.Error())
+		}
+		return
+	}
+
+	_, err := db.UpdateDeploymentStatus(ctx, *deployment.UUID, *deployment.Status, deployment.StatusReason)
+
+	var transitionErr types.InvalidTransitionError
+	if errors.As(err, &transitionErr) {
+		reason := fmt.Sprintf("Could not queue the task: %s", cause.Error())
+		_, err = db.UpdateDeploymentStatus(ctx, *deployment.UUID, types.DEPLOYMENT_STATUS_FAILED, &reason)
+	}
 
-		c.Status(http.StatusOK)
+	if err != nil {
+		log.Printf("error reverting deployment status: %s\n", err.Error())
 	}
 }
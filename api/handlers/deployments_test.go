@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/queue"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/gin-gonic/gin"
+)
+
+// recordingDispatcher keeps the enqueued tasks so that tests can process them one by one.
+type recordingDispatcher struct {
+	tasks []queue.Task
+}
+
+func (d *recordingDispatcher) Enqueue(task queue.Task) (string, error) {
+	d.tasks = append(d.tasks, task)
+	return "task", nil
+}
+
+func (d *recordingDispatcher) Start(ctx context.Context) error {
+	return nil
+}
+
+// newFakeRuntime returns a runtime whose containers are past the startup grace period as soon as they start.
+func newFakeRuntime() *services.FakeRuntime {
+	docker := services.NewFakeRuntime(services.TraefikLabelProvider{})
+	docker.Uptime = time.Hour
+	return docker
+}
+
+// newDeploymentRouter serves the deployment endpoints to the given user, without going through authentication.
+func newDeploymentRouter(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher, userUUID string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("userUUID", userUUID) })
+	router.POST("/deployments", CreateDeployment(db, docker, taskDispatcher))
+	router.GET("/deployments/:uuid", GetDeployment(db))
+	router.POST("/deployments/:uuid", UpdateDeployment(db, docker, taskDispatcher))
+	router.POST("/deployments/:uuid/scale", ScaleDeployment(db, docker, taskDispatcher))
+	router.POST("/deployments/:uuid/start", StartDeployment(db, docker, taskDispatcher))
+	router.POST("/deployments/:uuid/restart", RestartDeployment(db, docker, taskDispatcher))
+
+	return router
+}
+
+// createDeployment stores a deployment of one replica in status for a new user.
+func createDeployment(t *testing.T, db database.Repository, status string) (types.User, types.Deployment) {
+	t.Helper()
+	ctx := context.Background()
+
+	user, err := db.CreateUser(ctx, types.CreateUserRequest{Username: "web", ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := 8080
+	deployment, err := db.CreateDeployment(ctx, types.DeploymentAttributes{UserUUID: *user.UUID, Subdomain: "web", ImageTag: "nginx", Status: status, Port: &port, Replicas: 1, ResourceSpec: types.ResourceSpec{}.WithDefaults(), EnvConfig: types.EnvConfig{"PORT": "8080"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return user, deployment
+}
+
+func post(router *gin.Engine, path string, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestQueuedUpdatesBuildOnEachOther(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+	dispatcher := &recordingDispatcher{}
+	user, deployment := createDeployment(t, db, types.DEPLOYMENT_STATUS_READY)
+	router := newDeploymentRouter(db, docker, dispatcher, *user.UUID)
+
+	for _, body := range []string{`{"imageTag":"nginx:2"}`, `{"envConfig":{"GREETING":"hello"}}`, `{"replicas":2}`} {
+		if recorder := post(router, "/deployments/"+*deployment.UUID, body); recorder.Code != http.StatusOK {
+			t.Fatalf("expected the update %s to be queued, got %d: %s", body, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	if recorder := post(router, "/deployments/"+*deployment.UUID+"/scale", `{"replicas":3}`); recorder.Code != http.StatusOK {
+		t.Fatalf("expected the scale to be queued, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// A queue that coalesces keeps only the last update, which has to carry the earlier ones
+	last := dispatcher.tasks[2].(queue.UpdateDeploymentTask)
+	if last.ImageTag != "nginx:2" || types.ParseEnv(last.EnvArray)["GREETING"] != "hello" || last.Replicas != 2 {
+		t.Fatalf("expected the last update to build on the queued ones, got %s with %v and %d replicas", last.ImageTag, last.EnvArray, last.Replicas)
+	}
+
+	for i, task := range dispatcher.tasks {
+		if err := task.Process(); err != nil {
+			t.Fatal(err)
+		}
+
+		deployment, err := db.GetDeployment(ctx, *deployment.UUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i < len(dispatcher.tasks)-1 && *deployment.Status != types.DEPLOYMENT_STATUS_UPDATING {
+			t.Fatalf("expected the deployment to stay UPDATING while tasks are queued, got %s after task %d", *deployment.Status, i)
+		}
+	}
+
+	deployment, err := db.GetDeployment(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *deployment.Status != types.DEPLOYMENT_STATUS_READY || *deployment.ImageTag != "nginx:2" || deployment.EnvConfig["GREETING"] != "hello" || deployment.ReplicaCount() != 3 {
+		t.Errorf("expected every queued update to be applied, got %s on %s with %v and %d replicas", *deployment.Status, *deployment.ImageTag, deployment.EnvConfig, deployment.ReplicaCount())
+	}
+	if deployment.PendingUpdate != nil || deployment.ReservedCPUs != nil {
+		t.Errorf("expected the pending update and its reservation to be dropped, got %v and %v", deployment.PendingUpdate, deployment.ReservedCPUs)
+	}
+
+	instances, err := db.GetDeploymentInstances(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 3 {
+		t.Errorf("expected 3 instances, got %d", len(instances))
+	}
+}
+
+func TestScaleOverQuotaIsForbidden(t *testing.T) {
+	db := database.NewMemoryDatabase()
+	user, deployment := createDeployment(t, db, types.DEPLOYMENT_STATUS_READY)
+	router := newDeploymentRouter(db, newFakeRuntime(), &recordingDispatcher{}, *user.UUID)
+
+	if recorder := post(router, "/deployments/"+*deployment.UUID+"/scale", `{"replicas":100}`); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected scaling past the quota to be forbidden, got %d", recorder.Code)
+	}
+
+	deployment, err := db.GetDeployment(context.Background(), *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Status != types.DEPLOYMENT_STATUS_READY || deployment.PendingUpdate != nil {
+		t.Errorf("expected the deployment to stay READY without a pending update, got %s", *deployment.Status)
+	}
+}
+
+func TestGetDeploymentOfAnotherUser(t *testing.T) {
+	db := database.NewMemoryDatabase()
+	_, deployment := createDeployment(t, db, types.DEPLOYMENT_STATUS_READY)
+
+	other, err := db.CreateUser(context.Background(), types.CreateUserRequest{Username: "other", ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newDeploymentRouter(db, newFakeRuntime(), &recordingDispatcher{}, *other.UUID)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/deployments/"+*deployment.UUID, nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("expected another user's deployment to be refused, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestCreateFailsDeploymentWhenTaskCannotBeQueued(t *testing.T) {
+	db := database.NewMemoryDatabase()
+	user, err := db.CreateUser(context.Background(), types.CreateUserRequest{Username: "web", ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newDeploymentRouter(db, newFakeRuntime(), &queue.TaskDispatcher{Finished: true}, *user.UUID)
+
+	if recorder := post(router, "/deployments", `{"subdomain":"web","imageTag":"nginx"}`); recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("expected a closed queue to fail the request, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	deployment, err := db.GetDeployment(context.Background(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Status != types.DEPLOYMENT_STATUS_FAILED || deployment.StatusReason == nil {
+		t.Errorf("expected the deployment to be FAILED with a reason, got %s", *deployment.Status)
+	}
+}
+
+func TestScaleRevertsStatusWhenTaskCannotBeQueued(t *testing.T) {
+	cases := map[string]string{
+		types.DEPLOYMENT_STATUS_READY: types.DEPLOYMENT_STATUS_READY,
+		// UPDATING can't go back to CRASHED, the deployment is marked FAILED so that it can be retried
+		types.DEPLOYMENT_STATUS_CRASHED: types.DEPLOYMENT_STATUS_FAILED,
+	}
+
+	for status, expected := range cases {
+		db := database.NewMemoryDatabase()
+		user, deployment := createDeployment(t, db, status)
+		router := newDeploymentRouter(db, newFakeRuntime(), &queue.TaskDispatcher{Finished: true}, *user.UUID)
+
+		if recorder := post(router, "/deployments/"+*deployment.UUID+"/scale", `{"replicas":2}`); recorder.Code != http.StatusInternalServerError {
+			t.Fatalf("%s: expected a closed queue to fail the request, got %d", status, recorder.Code)
+		}
+
+		deployment, err := db.GetDeployment(context.Background(), *deployment.UUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *deployment.Status != expected || deployment.ReservedCPUs != nil || deployment.PendingUpdate != nil {
+			t.Errorf("%s: expected the deployment to be %s without a pending update, got %s", status, expected, *deployment.Status)
+		}
+	}
+}
+
+func TestStartWithoutContainersIsRefused(t *testing.T) {
+	db := database.NewMemoryDatabase()
+	user, deployment := createDeployment(t, db, types.DEPLOYMENT_STATUS_FAILED)
+	dispatcher := &recordingDispatcher{}
+	router := newDeploymentRouter(db, newFakeRuntime(), dispatcher, *user.UUID)
+
+	for _, action := range []string{"start", "restart"} {
+		if recorder := post(router, "/deployments/"+*deployment.UUID+"/"+action, ""); recorder.Code != http.StatusConflict {
+			t.Errorf("expected %s without containers to conflict, got %d", action, recorder.Code)
+		}
+	}
+
+	if len(dispatcher.tasks) != 0 {
+		t.Errorf("expected no task to be queued, got %d", len(dispatcher.tasks))
+	}
+}
+
+func TestQueuedEnvUpdatesAreMerged(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	dispatcher := &recordingDispatcher{}
+	user, deployment := createDeployment(t, db, types.DEPLOYMENT_STATUS_READY)
+	router := newDeploymentRouter(db, newFakeRuntime(), dispatcher, *user.UUID)
+
+	for _, body := range []string{`{"envConfig":{"GREETING":"hello","NAME":"web"}}`, `{"envConfig":{"GREETING":null,"COLOR":"blue"}}`} {
+		if recorder := post(router, "/deployments/"+*deployment.UUID, body); recorder.Code != http.StatusOK {
+			t.Fatalf("expected the update %s to be queued, got %d: %s", body, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	expected := types.EnvConfig{"PORT": "8080", "NAME": "web", "COLOR": "blue"}
+	env := types.ParseEnv(dispatcher.tasks[1].(queue.UpdateDeploymentTask).EnvArray)
+	if len(env) != len(expected) {
+		t.Fatalf("expected the second update to carry %v, got %v", expected, env)
+	}
+	for variable, value := range expected {
+		if env[variable] != value {
+			t.Fatalf("expected the second update to carry %v, got %v", expected, env)
+		}
+	}
+
+	for _, task := range dispatcher.tasks {
+		if err := task.Process(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deployment, err := db.GetDeployment(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := deployment.EnvConfig["GREETING"]; exists || deployment.EnvConfig["NAME"] != "web" || deployment.EnvConfig["COLOR"] != "blue" {
+		t.Errorf("expected both env updates to be applied, got %v", deployment.EnvConfig)
+	}
+}
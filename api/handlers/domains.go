@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/queue"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func GetDeploymentDomains(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingDeployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if *existingDeployment.UserId != *user.ID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		domains, err := db.GetDeploymentDomains(c.Request.Context(), *existingDeployment.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		for i := range domains {
+			domains[i] = domains[i].WithChallenge()
+		}
+
+		c.JSON(http.StatusOK, domains)
+	}
+}
+
+// AddDeploymentDomain attaches an unverified domain to a deployment and returns the challenge that verifies it.
+func AddDeploymentDomain(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+		var createDomainReq types.CreateDomainRequest
+		if err := c.ShouldBindJSON(&createDomainReq); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingDeployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if *existingDeployment.UserId != *user.ID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		hostname := types.NormalizeHostname(createDomainReq.Hostname)
+		if err := types.ValidateCustomHostname(hostname); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		method := createDomainReq.Method
+		if method == "" {
+			method = types.DOMAIN_VERIFICATION_TXT
+		}
+
+		if _, err := db.GetVerifiedDomain(c.Request.Context(), hostname); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Domain is already in use"})
+			return
+		}
+
+		existingDomains, err := db.GetDeploymentDomains(c.Request.Context(), *existingDeployment.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		for _, domain := range existingDomains {
+			if *domain.Hostname == hostname {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Domain is already attached to this deployment"})
+				return
+			}
+		}
+
+		token, err := utils.GenerateUUID()
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		domain, err := db.CreateDeploymentDomain(c.Request.Context(), *existingDeployment.UUID, hostname, method, token)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, domain.WithChallenge())
+	}
+}
+
+// VerifyDeploymentDomain checks the domain's challenge and, once it passes, redeploys the deployment so that it's routed on the domain.
+func VerifyDeploymentDomain(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher, resolver services.DomainResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+		domainUUID := c.Param("domain")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingDeployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if *existingDeployment.UserId != *user.ID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		domain, err := db.GetDeploymentDomain(c.Request.Context(), *existingDeployment.UUID, domainUUID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid domain uuid"})
+			return
+		}
+
+		if domain.Verified() {
+			c.JSON(http.StatusOK, gin.H{"domain": domain})
+			return
+		}
+
+		if _, err := db.GetVerifiedDomain(c.Request.Context(), *domain.Hostname); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Domain is already in use"})
+			return
+		}
+
+		if err := services.VerifyDomain(c.Request.Context(), resolver, domain); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "challenge": domain.WithChallenge().Challenge})
+			return
+		}
+
+		domain, err = db.SetDomainVerified(c.Request.Context(), *domain.UUID)
+		if err != nil {
+			// Another deployment verified the hostname since it was checked above
+			if database.IsUniqueViolation(err) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Domain is already in use"})
+				return
+			}
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		taskUUID, err := redeployDeployment(c.Request.Context(), db, docker, taskDispatcher, existingDeployment, user.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"domain": domain, "taskId": taskUUID})
+	}
+}
+
+func DeleteDeploymentDomain(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+		domainUUID := c.Param("domain")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingDeployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if *existingDeployment.UserId != *user.ID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		domain, err := db.GetDeploymentDomain(c.Request.Context(), *existingDeployment.UUID, domainUUID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid domain uuid"})
+			return
+		}
+
+		if err := db.DeleteDeploymentDomain(c.Request.Context(), *existingDeployment.UUID, *domain.UUID); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Unverified domains were never routed
+		taskUUID := ""
+		if domain.Verified() {
+			taskUUID, err = redeployDeployment(c.Request.Context(), db, docker, taskDispatcher, existingDeployment, user.UUID)
+			if err != nil {
+				c.Error(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"uuid": domain.UUID, "taskId": taskUUID})
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/gin-gonic/gin"
+)
+
+func GetDeploymentEvents(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingDeployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if *existingDeployment.UserId != *user.ID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		events, err := db.GetDeploymentEvents(c.Request.Context(), *existingDeployment.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Something went wrong"})
+			return
+		}
+
+		c.JSON(http.StatusOK, events)
+	}
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/gin-gonic/gin"
+)
+
+// StreamDeploymentLogs streams the container logs of a deployment as Server-Sent Events,
+// one "stdout" or "stderr" event per line.
+func StreamDeploymentLogs(db database.Repository, docker services.ContainerRuntime) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingDeployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if *existingDeployment.UserId != *user.ID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		replica, err := strconv.Atoi(c.DefaultQuery("replica", "0"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "replica must be a number"})
+			return
+		}
+
+		instances, err := db.GetDeploymentInstances(c.Request.Context(), *existingDeployment.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		var containerId string
+		for _, instance := range instances {
+			if *instance.Replica == replica {
+				containerId = *instance.ContainerId
+			}
+		}
+
+		if containerId == "" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Deployment has no running container for this replica"})
+			return
+		}
+
+		opts := services.LogOptions{
+			Tail:  c.DefaultQuery("tail", "100"),
+			Since: c.Query("since"),
+			Until: c.Query("until"),
+		}
+
+		since, err := parseLogTime(opts.Since, time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("since %s", err.Error())})
+			return
+		}
+
+		until, err := parseLogTime(opts.Until, time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("until %s", err.Error())})
+			return
+		}
+
+		if !since.IsZero() && !until.IsZero() && until.Before(since) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until must not be before since"})
+			return
+		}
+
+		if opts.Tail != "all" {
+			if _, err := strconv.ParseUint(opts.Tail, 10, 64); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "tail must be a number or \"all\""})
+				return
+			}
+		}
+
+		if opts.Timestamps, err = strconv.ParseBool(c.DefaultQuery("timestamps", "false")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timestamps must be a boolean"})
+			return
+		}
+
+		if opts.Follow, err = strconv.ParseBool(c.DefaultQuery("follow", "false")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "follow must be a boolean"})
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		stream := &sseLogStream{c: c}
+		stdout := &sseLineWriter{stream: stream, event: "stdout"}
+		stderr := &sseLineWriter{stream: stream, event: "stderr"}
+
+		err = docker.StreamContainerLogs(c.Request.Context(), containerId, opts, stdout, stderr)
+
+		stdout.Close()
+		stderr.Close()
+
+		if err != nil {
+			c.Error(err)
+			stream.send("error", err.Error())
+			return
+		}
+
+		stream.send("end", "")
+	}
+}
+
+// parseLogTime parses a since or until parameter in one of the formats Docker accepts, an RFC 3339 timestamp or date,
+// a Unix timestamp or a duration before now such as 10m. An empty value is the zero time.
+func parseLogTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(seconds) && !math.IsInf(seconds, 0) {
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+
+	return time.Time{}, errors.New("must be an RFC 3339 timestamp, a Unix timestamp or a duration")
+}
+
+// sseLogStream serializes events from the stdout and stderr writers onto the response.
+type sseLogStream struct {
+	mu sync.Mutex
+	c  *gin.Context
+}
+
+func (s *sseLogStream) send(event string, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.c.SSEvent(event, data)
+	s.c.Writer.Flush()
+}
+
+// sseLineWriter buffers writes until a full line is available and sends each line as one event.
+type sseLineWriter struct {
+	stream *sseLogStream
+	event  string
+	buf    bytes.Buffer
+}
+
+func (w *sseLineWriter) Write(p []byte) (int, error) {
+	if err := w.stream.c.Request.Context().Err(); err != nil {
+		return 0, err
+	}
+
+	w.buf.Write(p)
+
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i == -1 {
+			break
+		}
+
+		line := w.buf.Next(i + 1)
+		w.stream.send(w.event, string(bytes.TrimRight(line, "\r\n")))
+	}
+
+	return len(p), nil
+}
+
+// Close sends whatever is left in the buffer when the stream ended without a trailing newline.
+func (w *sseLineWriter) Close() {
+	if w.buf.Len() > 0 {
+		w.stream.send(w.event, w.buf.String())
+		w.buf.Reset()
+	}
+}
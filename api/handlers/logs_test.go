@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLogTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := map[string]time.Time{
+		"":                     {},
+		"2024-05-01T10:30:00Z": time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+		"2024-04-30":           time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
+		"1714564800":           time.Unix(1714564800, 0),
+		"1714564800.5":         time.Unix(1714564800, 500000000),
+		"10m":                  now.Add(-10 * time.Minute),
+	}
+
+	for value, expected := range cases {
+		parsed, err := parseLogTime(value, now)
+		if err != nil {
+			t.Errorf("%q: %s", value, err)
+			continue
+		}
+		if !parsed.Equal(expected) {
+			t.Errorf("%q: expected %s, got %s", value, expected, parsed)
+		}
+	}
+
+	for _, value := range []string{"yesterday", "NaN", "2024-13-01"} {
+		if _, err := parseLogTime(value, now); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
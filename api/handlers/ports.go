@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/queue"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/gin-gonic/gin"
+)
+
+func GetDeploymentPorts(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingDeployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if *existingDeployment.UserId != *user.ID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		ports, err := db.GetDeploymentPorts(c.Request.Context(), *existingDeployment.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		for i := range ports {
+			ports[i] = ports[i].WithHostname(*existingDeployment.Subdomain)
+		}
+
+		c.JSON(http.StatusOK, ports)
+	}
+}
+
+// AddDeploymentPort exposes a TCP or UDP port of the deployment's containers and redeploys it with the new routers.
+func AddDeploymentPort(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+		var createPortReq types.CreatePortRequest
+		if err := c.ShouldBindJSON(&createPortReq); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := createPortReq.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingDeployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if *existingDeployment.UserId != *user.ID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingPorts, err := db.GetDeploymentPorts(c.Request.Context(), *existingDeployment.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if len(existingPorts) >= config.MAX_PORTS_PER_DEPLOYMENT {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A deployment can expose at most %d ports", config.MAX_PORTS_PER_DEPLOYMENT)})
+			return
+		}
+
+		for _, port := range existingPorts {
+			if *port.Name == createPortReq.Name {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Port name already exists"})
+				return
+			}
+		}
+
+		// Ports without TLS are given a public port of their own, which counts against the user's quota
+		var tls *string
+		if createPortReq.TLS != "" {
+			tls = &createPortReq.TLS
+		}
+
+		port, err := db.CreateDeploymentPort(c.Request.Context(), *existingDeployment.UUID, createPortReq.Name, createPortReq.Protocol, createPortReq.ContainerPort, tls)
+		if err != nil {
+			c.Error(err)
+			var quotaErr types.QuotaExceededError
+			switch {
+			case err == types.ErrPortPoolExhausted:
+				c.JSON(http.StatusConflict, gin.H{"error": "No public ports are left"})
+			case errors.As(err, &quotaErr):
+				c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		taskUUID, err := redeployDeployment(c.Request.Context(), db, docker, taskDispatcher, existingDeployment, user.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"port": port.WithHostname(*existingDeployment.Subdomain), "taskId": taskUUID})
+	}
+}
+
+// DeleteDeploymentPort stops exposing a port, its public port goes back to the pool.
+func DeleteDeploymentPort(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+		portUUID := c.Param("port")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingDeployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if *existingDeployment.UserId != *user.ID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		if err := db.DeleteDeploymentPort(c.Request.Context(), *existingDeployment.UUID, portUUID); err != nil {
+			c.Error(err)
+			switch {
+			case err == sql.ErrNoRows:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Invalid port uuid"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		taskUUID, err := redeployDeployment(c.Request.Context(), db, docker, taskDispatcher, existingDeployment, user.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"uuid": portUUID, "taskId": taskUUID})
+	}
+}
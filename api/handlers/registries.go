@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func GetRegistryCredentials(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		credentials, err := db.GetRegistryCredentialsForUser(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, credentials)
+	}
+}
+
+// CreateRegistryCredential stores credentials that images from host are pulled with from then on.
+func CreateRegistryCredential(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var createCredentialReq types.CreateRegistryCredentialRequest
+		if err := c.ShouldBindJSON(&createCredentialReq); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		host := types.NormalizeRegistryHost(createCredentialReq.Host)
+		if host == "" || strings.ContainsAny(host, "/ ") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "host must be a registry hostname, e.g. ghcr.io or registry.example.com:5000"})
+			return
+		}
+
+		existingCredentials, err := db.GetRegistryCredentialsForUser(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		for _, credential := range existingCredentials {
+			if *credential.Host == host {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Credentials for this registry already exist"})
+				return
+			}
+		}
+
+		key, err := utils.SecretsKey()
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Secrets are not configured"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		ciphertext, encryptedKey, err := utils.SealEnvelope(key, []byte(createCredentialReq.Token), types.RegistryCredentialAdditionalData(*user.ID, host))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+
+		credential, err := db.CreateRegistryCredential(c.Request.Context(), userUUID.(string), host, createCredentialReq.Username, ciphertext, encryptedKey)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, credential)
+	}
+}
+
+func UpdateRegistryCredential(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+		var updateCredentialReq types.UpdateRegistryCredentialRequest
+		if err := c.ShouldBindJSON(&updateCredentialReq); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingCredential, err := db.GetRegistryCredential(c.Request.Context(), userUUID.(string), uuid)
+		if err != nil {
+			c.Error(err)
+			switch {
+			case err == sql.ErrNoRows:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Invalid registry credential uuid"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		key, err := utils.SecretsKey()
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Secrets are not configured"})
+			return
+		}
+
+		ciphertext, encryptedKey, err := utils.SealEnvelope(key, []byte(updateCredentialReq.Token), types.RegistryCredentialAdditionalData(*existingCredential.UserId, *existingCredential.Host))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+
+		credential, err := db.UpdateRegistryCredential(c.Request.Context(), userUUID.(string), uuid, updateCredentialReq.Username, ciphertext, encryptedKey)
+		if err != nil {
+			c.Error(err)
+			switch {
+			case err == sql.ErrNoRows:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Invalid registry credential uuid"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, credential)
+	}
+}
+
+func DeleteRegistryCredential(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		if err := db.DeleteRegistryCredential(c.Request.Context(), userUUID.(string), uuid); err != nil {
+			c.Error(err)
+			switch {
+			case err == sql.ErrNoRows:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Invalid registry credential uuid"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"uuid": uuid})
+	}
+}
+
+// dockerAuthAllowed responds and returns false when the dockerAuth of a request would overwrite a different
+// credential the user stored for the image's registry, which only happens when the request sets replace.
+func dockerAuthAllowed(c *gin.Context, db database.Repository, user types.User, image string, username string, password string, replace bool) bool {
+	if replace {
+		return true
+	}
+
+	host := types.ImageRegistryHost(image)
+
+	existingCredentials, err := db.GetRegistryCredentialsForUser(c.Request.Context(), *user.UUID)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	for _, credential := range existingCredentials {
+		if *credential.Host != host {
+			continue
+		}
+
+		if *credential.Username == username {
+			key, err := utils.SecretsKey()
+			if err != nil {
+				c.Error(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return false
+			}
+
+			token, err := utils.OpenEnvelope(key, credential.Ciphertext, credential.EncryptedKey, types.RegistryCredentialAdditionalData(*user.ID, host))
+			if err == nil && string(token) == password {
+				return true
+			}
+		}
+
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("A different registry credential is stored for %s, set dockerAuth.replace to overwrite it", host)})
+		return false
+	}
+
+	return true
+}
+
+// storeDockerAuth saves the dockerAuth of a request as the user's credential for the image's registry, so that it
+// is encrypted at rest like any other credential, and returns the uuid the task refers to it by. It is only called
+// once the deployment accepted the request, after dockerAuthAllowed.
+func storeDockerAuth(ctx context.Context, db database.Repository, user types.User, image string, username string, password string) (string, error) {
+	key, err := utils.SecretsKey()
+	if err != nil {
+		return "", err
+	}
+
+	host := types.ImageRegistryHost(image)
+
+	ciphertext, encryptedKey, err := utils.SealEnvelope(key, []byte(password), types.RegistryCredentialAdditionalData(*user.ID, host))
+	if err != nil {
+		return "", err
+	}
+
+	existingCredentials, err := db.GetRegistryCredentialsForUser(ctx, *user.UUID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, credential := range existingCredentials {
+		if *credential.Host == host {
+			credential, err := db.UpdateRegistryCredential(ctx, *user.UUID, *credential.UUID, username, ciphertext, encryptedKey)
+			if err != nil {
+				return "", err
+			}
+			return *credential.UUID, nil
+		}
+	}
+
+	credential, err := db.CreateRegistryCredential(ctx, *user.UUID, host, username, ciphertext, encryptedKey)
+	if err != nil {
+		return "", err
+	}
+
+	return *credential.UUID, nil
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/queue"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func TestDockerAuthDoesNotOverwriteStoredCredential(t *testing.T) {
+	t.Setenv("SECRETS_KEY", base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	dispatcher := &recordingDispatcher{}
+	user, deployment := createDeployment(t, db, types.DEPLOYMENT_STATUS_READY)
+	router := newDeploymentRouter(db, newFakeRuntime(), dispatcher, *user.UUID)
+
+	stored, err := storeDockerAuth(ctx, db, user, "nginx", "ci", "ci-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if recorder := post(router, "/deployments/"+*deployment.UUID, `{"dockerAuth":{"username":"web","password":"web-token"}}`); recorder.Code != http.StatusConflict {
+		t.Fatalf("expected a different credential for the registry to conflict, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	credential, err := db.GetRegistryCredential(ctx, *user.UUID, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *credential.Username != "ci" {
+		t.Errorf("expected the stored credential to be kept, got %s", *credential.Username)
+	}
+
+	refused, err := db.GetDeployment(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *refused.Status != types.DEPLOYMENT_STATUS_READY || len(dispatcher.tasks) != 0 {
+		t.Fatalf("expected the update to be refused before it was queued, got %s with %d tasks", *refused.Status, len(dispatcher.tasks))
+	}
+
+	// The credential that is already stored can be sent again
+	if recorder := post(router, "/deployments/"+*deployment.UUID, `{"dockerAuth":{"username":"ci","password":"ci-token"}}`); recorder.Code != http.StatusOK {
+		t.Fatalf("expected the stored credential to be accepted, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if task := dispatcher.tasks[0].(queue.UpdateDeploymentTask); task.RegistryCredential != stored {
+		t.Errorf("expected the task to use the stored credential, got %q", task.RegistryCredential)
+	}
+
+	if recorder := post(router, "/deployments/"+*deployment.UUID, `{"dockerAuth":{"username":"web","password":"web-token","replace":true}}`); recorder.Code != http.StatusOK {
+		t.Fatalf("expected replace to overwrite the stored credential, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	credential, err = db.GetRegistryCredential(ctx, *user.UUID, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *credential.Username != "web" {
+		t.Errorf("expected the stored credential to be replaced, got %s", *credential.Username)
+	}
+}
+
+func TestRefusedCreateDoesNotStoreDockerAuth(t *testing.T) {
+	t.Setenv("SECRETS_KEY", base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	user, err := db.CreateUser(ctx, types.CreateUserRequest{Username: "web", ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newDeploymentRouter(db, newFakeRuntime(), &recordingDispatcher{}, *user.UUID)
+
+	if recorder := post(router, "/deployments", `{"subdomain":"web","imageTag":"nginx","replicas":100,"dockerAuth":{"username":"web","password":"web-token"}}`); recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected creating past the quota to be forbidden, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	credentials, err := db.GetRegistryCredentialsForUser(ctx, *user.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(credentials) != 0 {
+		t.Errorf("expected no credential to be stored for a refused deployment, got %d", len(credentials))
+	}
+}
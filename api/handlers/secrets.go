@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func GetSecrets(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		secrets, err := db.GetSecretsForUser(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, secrets)
+	}
+}
+
+func CreateSecret(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var createSecretReq types.CreateSecretRequest
+		if err := c.ShouldBindJSON(&createSecretReq); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		if !types.ValidSecretName(createSecretReq.Name) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Secret names may only contain letters, digits, '_', '.' and '-' and must not start with a digit"})
+			return
+		}
+
+		if _, err := db.GetSecret(c.Request.Context(), userUUID.(string), createSecretReq.Name); err == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Secret already exists"})
+			return
+		}
+
+		key, err := utils.SecretsKey()
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Secrets are not configured"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		ciphertext, encryptedKey, err := utils.SealEnvelope(key, []byte(createSecretReq.Value), types.SecretAdditionalData(*user.ID, createSecretReq.Name))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+
+		secret, err := db.CreateSecret(c.Request.Context(), userUUID.(string), createSecretReq.Name, ciphertext, encryptedKey)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, secret)
+	}
+}
+
+// UpdateSecret replaces the value of a secret. Running deployments keep the old value until their containers are provisioned again.
+func UpdateSecret(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		var updateSecretReq types.UpdateSecretRequest
+		if err := c.ShouldBindJSON(&updateSecretReq); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingSecret, err := db.GetSecret(c.Request.Context(), userUUID.(string), name)
+		if err != nil {
+			c.Error(err)
+			switch {
+			case err == sql.ErrNoRows:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Invalid secret name"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		key, err := utils.SecretsKey()
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Secrets are not configured"})
+			return
+		}
+
+		ciphertext, encryptedKey, err := utils.SealEnvelope(key, []byte(updateSecretReq.Value), types.SecretAdditionalData(*existingSecret.UserId, name))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+
+		secret, err := db.UpdateSecret(c.Request.Context(), userUUID.(string), name, ciphertext, encryptedKey)
+		if err != nil {
+			c.Error(err)
+			switch {
+			case err == sql.ErrNoRows:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Invalid secret name"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, secret)
+	}
+}
+
+func DeleteSecret(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		deployments, err := db.GetAllDeploymentsForUser(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Queued updates and revisions are checked too, applying them would fail on the missing secret otherwise
+		for _, deployment := range deployments {
+			if refersTo(deployment.EnvConfig, name) || deployment.PendingUpdate != nil && refersTo(deployment.PendingUpdate.EnvConfig, name) {
+				c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Secret is used by deployment %s", *deployment.UUID)})
+				return
+			}
+
+			revisions, err := db.GetDeploymentRevisions(c.Request.Context(), *deployment.UUID)
+			if err != nil {
+				c.Error(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			for _, revision := range revisions {
+				if refersTo(revision.EnvConfig, name) {
+					c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Secret is used by revision %d of deployment %s", *revision.Revision, *deployment.UUID)})
+					return
+				}
+			}
+		}
+
+		if err := db.DeleteSecret(c.Request.Context(), userUUID.(string), name); err != nil {
+			c.Error(err)
+			switch {
+			case err == sql.ErrNoRows:
+				c.JSON(http.StatusNotFound, gin.H{"error": "Invalid secret name"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"name": name})
+	}
+}
+
+// secretsExist responds with 400 and returns false when the env config refers to a secret the user doesn't have.
+func secretsExist(c *gin.Context, db database.Repository, userUUID string, envConfig types.EnvConfig) bool {
+	references := envConfig.SecretReferences()
+	if len(references) == 0 {
+		return true
+	}
+
+	secrets, err := db.GetSecretsForUser(c.Request.Context(), userUUID)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+
+	existing := make(map[string]bool, len(secrets))
+	for _, secret := range secrets {
+		existing[*secret.Name] = true
+	}
+
+	for _, reference := range references {
+		if !existing[reference] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Secret %s does not exist", reference)})
+			return false
+		}
+	}
+
+	return true
+}
+
+func refersTo(envConfig types.EnvConfig, name string) bool {
+	for _, reference := range envConfig.SecretReferences() {
+		if reference == name {
+			return true
+		}
+	}
+
+	return false
+}
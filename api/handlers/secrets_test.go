@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/gin-gonic/gin"
+)
+
+func deleteSecret(db database.Repository, userUUID string, name string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("userUUID", userUUID) })
+	router.DELETE("/secrets/:name", DeleteSecret(db))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/secrets/"+name, nil))
+	return recorder
+}
+
+func TestDeleteSecretUsedByRevision(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	user, deployment := createDeployment(t, db, types.DEPLOYMENT_STATUS_READY)
+
+	if _, err := db.CreateSecret(ctx, *user.UUID, "db_password", []byte("ciphertext"), []byte("key")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The deployment no longer refers to the secret, an earlier revision it can be rolled back to does
+	if _, err := db.CreateDeploymentRevision(ctx, types.DeploymentRevisionAttributes{DeploymentUUID: *deployment.UUID, ImageTag: "nginx", Subdomain: "web", Port: 8080, EnvConfig: types.EnvConfig{"PORT": "8080", "DB_PASSWORD": "${secret:db_password}"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if recorder := deleteSecret(db, *user.UUID, "db_password"); recorder.Code != http.StatusConflict {
+		t.Fatalf("expected deleting a secret a revision refers to to conflict, got %d", recorder.Code)
+	}
+
+	if _, err := db.GetSecret(ctx, *user.UUID, "db_password"); err != nil {
+		t.Errorf("expected the secret to be kept, got %s", err)
+	}
+
+	if recorder := deleteSecret(db, *user.UUID, "api_token"); recorder.Code != http.StatusNotFound {
+		t.Errorf("expected deleting a missing secret to be not found, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/gin-gonic/gin"
+)
+
+func GetDeadLetterTasks(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tasks, err := db.GetDeadLetterTasks(c.Request.Context())
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Something went wrong"})
+			return
+		}
+
+		c.JSON(http.StatusOK, tasks)
+	}
+}
+
+func RedriveTask(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+
+		task, err := db.RedriveTask(c.Request.Context(), uuid)
+		if err != nil {
+			c.Error(err)
+			switch {
+			case err == sql.ErrNoRows:
+				c.JSON(http.StatusNotFound, map[string]interface{}{"error": fmt.Sprintf("No dead-lettered task with UUID: %s", uuid)})
+			default:
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Something went wrong"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, task)
+	}
+}
+
+func GetTask(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		task, err := db.GetTask(c.Request.Context(), uuid)
+		if err != nil {
+			c.Error(err)
+			switch {
+			case err == sql.ErrNoRows:
+				c.JSON(http.StatusNotFound, map[string]interface{}{"error": fmt.Sprintf("Invalid UUID: %s", uuid)})
+			default:
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Something went wrong"})
+			}
+			return
+		}
+
+		if task.UserId == nil || *task.UserId != *user.ID {
+			c.JSON(http.StatusNotFound, map[string]interface{}{"error": fmt.Sprintf("Invalid UUID: %s", uuid)})
+			return
+		}
+
+		c.JSON(http.StatusOK, task)
+	}
+}
+
+func GetTasksForDeployment(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		existingDeployment, err := db.GetDeployment(c.Request.Context(), uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid deployment uuid"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), userUUID.(string))
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+
+		if *existingDeployment.UserId != *user.ID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		tasks, err := db.GetTasksForDeployment(c.Request.Context(), *existingDeployment.UUID)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Something went wrong"})
+			return
+		}
+
+		c.JSON(http.StatusOK, tasks)
+	}
+}
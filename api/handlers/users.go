@@ -15,7 +15,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GetUser(db *database.Database) gin.HandlerFunc {
+func GetUser(db database.Repository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		uuid := c.Param("uuid")
 
@@ -35,7 +35,7 @@ func GetUser(db *database.Database) gin.HandlerFunc {
 		c.JSON(http.StatusOK, user)
 	}
 }
-func CreateUser(db *database.Database) gin.HandlerFunc {
+func CreateUser(db database.Repository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var userReq types.CreateUserRequest
 		if err := c.ShouldBindJSON(&userReq); err != nil {
@@ -64,7 +64,7 @@ func CreateUser(db *database.Database) gin.HandlerFunc {
 	}
 }
 
-func GetAuthToken(db *database.Database) gin.HandlerFunc {
+func GetAuthToken(db database.Repository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var authTokenReq types.AuthTokenRequest
 		if err := c.ShouldBindJSON(&authTokenReq); err != nil {
@@ -117,3 +117,44 @@ func GetAuthToken(db *database.Database) gin.HandlerFunc {
 
 	}
 }
+
+func GetUserUsage(db database.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uuid := c.Param("uuid")
+
+		userUUID, doesUserUUIDExists := c.Get("userUUID")
+
+		if !doesUserUUIDExists || userUUID.(string) != uuid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		user, err := db.GetUserByUUID(c.Request.Context(), uuid)
+		if err != nil {
+			c.Error(err)
+			switch {
+			case err == sql.ErrNoRows:
+				c.JSON(http.StatusBadRequest, map[string]interface{}{"error": fmt.Sprintf("Invalid UUID: %s", uuid)})
+			default:
+				c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Something went wrong"})
+			}
+			return
+		}
+
+		deployments, err := db.GetAllDeploymentsForUser(c.Request.Context(), uuid)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Something went wrong"})
+			return
+		}
+
+		usage := types.DeploymentsUsage(deployments)
+		if usage.PublicPorts, err = db.CountPublicPortsForUser(c.Request.Context(), uuid); err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, map[string]interface{}{"error": "Something went wrong"})
+			return
+		}
+
+		c.JSON(http.StatusOK, types.UsageReport{Quota: user.Quota(), Usage: usage})
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/api/handlers"
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/jobs"
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/middlewares"
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/queue"
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
@@ -17,12 +18,14 @@ type Server struct {
 	port           string
 	gin            *gin.Engine
 	server         *http.Server
-	db             *database.Database
-	docker         *services.DockerService
-	taskDispatcher *queue.TaskDispatcher
+	db             database.Repository
+	docker         services.ContainerRuntime
+	taskDispatcher queue.Dispatcher
+	gc             *jobs.GarbageCollector
+	resolver       services.DomainResolver
 }
 
-func NewServer(port string, db *database.Database, docker *services.DockerService, taskDispatcher *queue.TaskDispatcher) *Server {
+func NewServer(port string, db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher, gc *jobs.GarbageCollector, resolver services.DomainResolver) *Server {
 	ginRouter := gin.New()
 
 	ginRouter.Use(gin.Logger())
@@ -40,6 +43,8 @@ func NewServer(port string, db *database.Database, docker *services.DockerServic
 		db:             db,
 		docker:         docker,
 		taskDispatcher: taskDispatcher,
+		gc:             gc,
+		resolver:       resolver,
 	}
 }
 
@@ -51,6 +56,7 @@ func (s *Server) Start() error {
 
 	{
 		v1.GET("/users/:uuid", handlers.GetUser(s.db))
+		v1.GET("/users/:uuid/usage", middlewares.AuthRequired, handlers.GetUserUsage(s.db))
 		v1.POST("/users", handlers.CreateUser(s.db))
 		v1.POST("/auth", handlers.GetAuthToken(s.db))
 
@@ -64,6 +70,66 @@ func (s *Server) Start() error {
 
 			deployments.GET("/:uuid", middlewares.AuthRequired, handlers.GetDeployment(s.db))
 			deployments.DELETE("/:uuid", middlewares.AuthRequired, handlers.DeleteDeployment(s.db, s.docker, s.taskDispatcher))
+
+			deployments.POST("/:uuid/scale", middlewares.AuthRequired, handlers.ScaleDeployment(s.db, s.docker, s.taskDispatcher))
+			deployments.GET("/:uuid/revisions", middlewares.AuthRequired, handlers.GetDeploymentRevisions(s.db))
+			deployments.POST("/:uuid/rollback", middlewares.AuthRequired, handlers.RollbackDeployment(s.db, s.docker, s.taskDispatcher))
+			deployments.POST("/:uuid/stop", middlewares.AuthRequired, handlers.StopDeployment(s.db, s.docker, s.taskDispatcher))
+			deployments.POST("/:uuid/start", middlewares.AuthRequired, handlers.StartDeployment(s.db, s.docker, s.taskDispatcher))
+			deployments.POST("/:uuid/restart", middlewares.AuthRequired, handlers.RestartDeployment(s.db, s.docker, s.taskDispatcher))
+
+			deployments.GET("/:uuid/domains", middlewares.AuthRequired, handlers.GetDeploymentDomains(s.db))
+			deployments.POST("/:uuid/domains", middlewares.AuthRequired, handlers.AddDeploymentDomain(s.db))
+			deployments.POST("/:uuid/domains/:domain/verify", middlewares.AuthRequired, handlers.VerifyDeploymentDomain(s.db, s.docker, s.taskDispatcher, s.resolver))
+			deployments.DELETE("/:uuid/domains/:domain", middlewares.AuthRequired, handlers.DeleteDeploymentDomain(s.db, s.docker, s.taskDispatcher))
+
+			deployments.GET("/:uuid/ports", middlewares.AuthRequired, handlers.GetDeploymentPorts(s.db))
+			deployments.POST("/:uuid/ports", middlewares.AuthRequired, handlers.AddDeploymentPort(s.db, s.docker, s.taskDispatcher))
+			deployments.DELETE("/:uuid/ports/:port", middlewares.AuthRequired, handlers.DeleteDeploymentPort(s.db, s.docker, s.taskDispatcher))
+
+			deployments.GET("/:uuid/tasks", middlewares.AuthRequired, handlers.GetTasksForDeployment(s.db))
+			deployments.GET("/:uuid/events", middlewares.AuthRequired, handlers.GetDeploymentEvents(s.db))
+			deployments.GET("/:uuid/logs", middlewares.AuthRequired, handlers.StreamDeploymentLogs(s.db, s.docker))
+		}
+
+		secrets := v1.Group("/secrets")
+
+		secrets.Use(middlewares.AuthRequired)
+		{
+			secrets.GET("/", handlers.GetSecrets(s.db))
+			secrets.POST("/", handlers.CreateSecret(s.db))
+			secrets.PUT("/:name", handlers.UpdateSecret(s.db))
+			secrets.DELETE("/:name", handlers.DeleteSecret(s.db))
+		}
+
+		registries := v1.Group("/registries")
+
+		registries.Use(middlewares.AuthRequired)
+		{
+			registries.GET("/", handlers.GetRegistryCredentials(s.db))
+			registries.POST("/", handlers.CreateRegistryCredential(s.db))
+			registries.PUT("/:uuid", handlers.UpdateRegistryCredential(s.db))
+			registries.DELETE("/:uuid", handlers.DeleteRegistryCredential(s.db))
+		}
+
+		tasks := v1.Group("/tasks")
+
+		tasks.Use(middlewares.AuthRequired)
+		{
+			tasks.GET("/:uuid", handlers.GetTask(s.db))
+		}
+
+		admin := v1.Group("/admin")
+
+		admin.Use(middlewares.AdminRequired)
+		{
+			admin.GET("/tasks/dead-letters", handlers.GetDeadLetterTasks(s.db))
+			admin.POST("/tasks/:uuid/redrive", handlers.RedriveTask(s.db))
+
+			admin.PUT("/users/:uuid/quota", handlers.UpdateUserQuota(s.db))
+
+			admin.GET("/gc", handlers.CollectGarbage(s.gc, true))
+			admin.POST("/gc", handlers.CollectGarbage(s.gc, false))
 		}
 	}
 
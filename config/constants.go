@@ -6,4 +6,40 @@ const (
 	DEFAULT_HOSTNAME                string        = "docker.localhost"
 	JWT_EXPIRY_DURATION_HOURS       time.Duration = time.Hour * 48
 	JWT_COOKIE_EXPIRY_DURATION_DAYS float64       = 30
+	RECONCILE_INTERVAL              time.Duration = time.Second * 30
+	GC_INTERVAL                     time.Duration = time.Minute * 10
+	GC_GRACE_PERIOD                 time.Duration = time.Minute * 15
+	HEALTHY_TIMEOUT                 time.Duration = time.Minute * 2
+	// Longest a health check may take to fail a container. It has to stay well under GC_GRACE_PERIOD, otherwise the
+	// garbage collector could remove the containers of an update that is still waiting for them
+	MAX_HEALTH_CHECK_DEADLINE time.Duration = time.Minute * 5
+	// A container the reconciler restarted this many times within the window is crash looping, it's left exited and
+	// its deployment marked CRASHED until the window has passed
+	CRASH_LOOP_RESTARTS int           = 3
+	CRASH_LOOP_WINDOW   time.Duration = time.Minute * 10
+	// Containers without a health check are only considered ready once they kept running for this long
+	STARTUP_GRACE_PERIOD     time.Duration = time.Second * 5
+	DOMAIN_CHALLENGE_TIMEOUT time.Duration = time.Second * 10
+	// A running task is taken over by another worker once its worker stops renewing the lease for this long
+	TASK_LEASE_DURATION time.Duration = time.Second * 30
+	// How often the file based ingress provider is synced with the deployments and their containers
+	INGRESS_SYNC_INTERVAL time.Duration = time.Second * 2
+
+	// Public ports handed out to TCP and UDP ports of deployments, Traefik needs an entrypoint for each of them
+	// (see docker/docker-compose.yml)
+	PORT_POOL_START int = 30000
+	PORT_POOL_END   int = 30009
+	// Pool ports are shared by every user, so each deployment only gets a few of them
+	MAX_PORTS_PER_DEPLOYMENT int = 5
+
+	// Quotas of users that don't have one set explicitly
+	DEFAULT_MAX_DEPLOYMENTS int     = 10
+	DEFAULT_MAX_MEMORY      int64   = 4 * 1024 * 1024 * 1024
+	DEFAULT_MAX_CPUS        float64 = 4
+	// The pool is shared by every user, so a single user can't take more than a few of its ports
+	DEFAULT_MAX_PUBLIC_PORTS int = 2
+
+	// Limits applied to deployments that don't set their own, so that every deployment counts against the quota
+	DEFAULT_DEPLOYMENT_MEMORY_LIMIT int64 = 512 * 1024 * 1024
+	DEFAULT_DEPLOYMENT_CPU_QUOTA    int64 = 100000
 )
@@ -8,10 +8,13 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// Database is the Postgres implementation of Repository.
 type Database struct {
 	Client *sqlx.DB
 }
 
+var _ Repository = (*Database)(nil)
+
 func NewDatabase() (*Database, error) {
 	connectionString := fmt.Sprintf(
 		"host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/jmoiron/sqlx"
 )
 
 func (d *Database) GetDeployment(ctx context.Context, uuidOrSubdomain string) (types.Deployment, error) {
@@ -29,35 +30,100 @@ func (d *Database) GetAllDeploymentsForUser(ctx context.Context, userUUID string
 	return deployments, nil
 }
 
+func (d *Database) GetAllDeployments(ctx context.Context) ([]types.Deployment, error) {
+	deployments := []types.Deployment{}
+	query := `SELECT * FROM deployments`
+
+	if err := d.Client.SelectContext(ctx, &deployments, query); err != nil {
+		return []types.Deployment{}, err
+	}
+
+	return deployments, nil
+}
+
+func (d *Database) GetDeploymentsByStatus(ctx context.Context, status string) ([]types.Deployment, error) {
+	deployments := []types.Deployment{}
+	query := `SELECT * FROM deployments WHERE status = $1`
+
+	if err := d.Client.SelectContext(ctx, &deployments, query, status); err != nil {
+		return []types.Deployment{}, err
+	}
+
+	return deployments, nil
+}
+
+// CreateDeployment returns a types.QuotaExceededError when the deployment doesn't fit in its user's quota. The user's
+// row stays locked until the deployment is inserted, so that concurrent requests can't both take the rest of the quota.
 func (d *Database) CreateDeployment(ctx context.Context, deploymentAttributes types.DeploymentAttributes) (types.Deployment, error) {
-	user, err := d.GetUserByUUID(ctx, deploymentAttributes.UserUUID)
+	tx, err := d.Client.BeginTxx(ctx, nil)
 	if err != nil {
 		return types.Deployment{}, err
 	}
 
+	defer tx.Rollback()
+
+	var user types.User
+	if err := tx.GetContext(ctx, &user, `SELECT * FROM users WHERE uuid = $1 FOR UPDATE`, deploymentAttributes.UserUUID); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if err := checkQuota(ctx, tx, user, "", types.ResourceUsage(deploymentAttributes.ResourceSpec, deploymentAttributes.Replicas)); err != nil {
+		return types.Deployment{}, err
+	}
+
 	deployment := types.Deployment{
-		UserId:      user.ID,
-		Subdomain:   &deploymentAttributes.Subdomain,
-		ImageTag:    &deploymentAttributes.ImageTag,
-		ContainerId: deploymentAttributes.ContainerId,
-		Port:        deploymentAttributes.Port,
-		Status:      &deploymentAttributes.Status,
+		UserId:       user.ID,
+		Subdomain:    &deploymentAttributes.Subdomain,
+		ImageTag:     &deploymentAttributes.ImageTag,
+		Port:         deploymentAttributes.Port,
+		Replicas:     &deploymentAttributes.Replicas,
+		Status:       &deploymentAttributes.Status,
+		StatusReason: deploymentAttributes.StatusReason,
+
+		ResourceSpec: deploymentAttributes.ResourceSpec,
+		HealthCheck:  deploymentAttributes.HealthCheck,
+		Routing:      deploymentAttributes.Routing,
+		Middlewares:  deploymentAttributes.Middlewares,
+		EnvConfig:    deploymentAttributes.EnvConfig,
 	}
 
-	if _, err := d.Client.NamedExecContext(ctx, `INSERT INTO deployments (user_id, sub_domain, image_tag, container_id, status, port) VALUES (:user_id, :sub_domain, :image_tag, :container_id, :status, :port)`, deployment); err != nil {
+	if _, err := tx.NamedExecContext(ctx, `INSERT INTO deployments (user_id, sub_domain, image_tag, status, status_reason, port, replicas, cpu_shares, cpu_quota, cpu_period, memory_limit, memory_reservation, pids_limit, ulimits, health_check, routing, middlewares, env_config)
+		VALUES (:user_id, :sub_domain, :image_tag, :status, :status_reason, :port, :replicas, :cpu_shares, :cpu_quota, :cpu_period, :memory_limit, :memory_reservation, :pids_limit, :ulimits, :health_check, :routing, :middlewares, :env_config)`, deployment); err != nil {
 		return types.Deployment{}, err
 	}
 
-	createdDeployment, err := d.GetDeployment(ctx, deploymentAttributes.Subdomain)
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		return types.Deployment{}, err
 	}
 
-	return createdDeployment, nil
+	return d.GetDeployment(ctx, deploymentAttributes.Subdomain)
 }
 
+// UpdateDeployment overwrites every column of the deployment. The status change is checked against
+// the transition table and an InvalidTransitionError is returned when it isn't allowed.
 func (d *Database) UpdateDeployment(ctx context.Context, deploymentAttributes types.DeploymentAttributes) (types.Deployment, error) {
-	if _, err := d.Client.NamedExecContext(ctx, `UPDATE deployments SET image_tag = :image_tag, sub_domain = :sub_domain, port = :port, container_id = :container_id, status = :status WHERE uuid = :uuid`, deploymentAttributes); err != nil {
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return types.Deployment{}, err
+	}
+
+	defer tx.Rollback()
+
+	if err := checkTransition(ctx, tx, deploymentAttributes.UUID, deploymentAttributes.Status); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if _, err := tx.NamedExecContext(ctx, `UPDATE deployments SET image_tag = :image_tag, sub_domain = :sub_domain, port = :port, replicas = :replicas, status = :status, status_reason = :status_reason, health_check = :health_check, routing = :routing, middlewares = :middlewares, env_config = :env_config,
+		cpu_shares = :cpu_shares, cpu_quota = :cpu_quota, cpu_period = :cpu_period, memory_limit = :memory_limit, memory_reservation = :memory_reservation, pids_limit = :pids_limit, ulimits = :ulimits
+		WHERE uuid = :uuid`, deploymentAttributes); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if err := releaseReservation(ctx, tx, deploymentAttributes.UUID, deploymentAttributes.Status); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
 		return types.Deployment{}, err
 	}
 
@@ -70,6 +136,149 @@ func (d *Database) UpdateDeployment(ctx context.Context, deploymentAttributes ty
 
 }
 
+// UpdateDeploymentStatus only changes the status and its reason, with the same checks as UpdateDeployment.
+func (d *Database) UpdateDeploymentStatus(ctx context.Context, uuid string, status string, statusReason *string) (types.Deployment, error) {
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return types.Deployment{}, err
+	}
+
+	defer tx.Rollback()
+
+	if err := checkTransition(ctx, tx, uuid, status); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE deployments SET status = $2, status_reason = $3 WHERE uuid = $1`, uuid, status, statusReason); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if err := releaseReservation(ctx, tx, uuid, status); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return types.Deployment{}, err
+	}
+
+	return d.GetDeployment(ctx, uuid)
+}
+
+// ReserveDeploymentUpdate moves the deployment to UPDATING like UpdateDeploymentStatus and records update as its pending
+// update. A usage is reserved until it leaves UPDATING, it returns a types.QuotaExceededError when that doesn't fit in
+// the user's quota.
+func (d *Database) ReserveDeploymentUpdate(ctx context.Context, uuid string, update types.DeploymentConfig, usage *types.Usage) (types.Deployment, error) {
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return types.Deployment{}, err
+	}
+
+	defer tx.Rollback()
+
+	var user types.User
+	query := `SELECT users.* FROM users JOIN deployments ON deployments.user_id = users.id WHERE deployments.uuid = $1 FOR UPDATE OF users`
+	if err := tx.GetContext(ctx, &user, query, uuid); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if err := checkTransition(ctx, tx, uuid, types.DEPLOYMENT_STATUS_UPDATING); err != nil {
+		return types.Deployment{}, err
+	}
+
+	var deployment types.Deployment
+	if err := tx.GetContext(ctx, &deployment, `SELECT * FROM deployments WHERE uuid = $1`, uuid); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if usage != nil {
+		reservation := deployment.Reservation(*usage)
+		if err := checkQuota(ctx, tx, user, uuid, reservation); err != nil {
+			return types.Deployment{}, err
+		}
+		deployment.ReservedMemory = &reservation.Memory
+		deployment.ReservedCPUs = &reservation.CPUs
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE deployments SET status = $2, status_reason = NULL, reserved_memory = $3, reserved_cpus = $4, pending_update = $5 WHERE uuid = $1`, uuid, types.DEPLOYMENT_STATUS_UPDATING, deployment.ReservedMemory, deployment.ReservedCPUs, update); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return types.Deployment{}, err
+	}
+
+	return d.GetDeployment(ctx, uuid)
+}
+
+// SettleDeploymentUpdate moves the deployment out of UPDATING once the update queued with token is done. When a newer
+// update was queued in the meantime the deployment is left UPDATING, with its pending update and reservation, for it.
+func (d *Database) SettleDeploymentUpdate(ctx context.Context, uuid string, token string, status string, statusReason *string) (types.Deployment, error) {
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return types.Deployment{}, err
+	}
+
+	defer tx.Rollback()
+
+	var deployment types.Deployment
+	if err := tx.GetContext(ctx, &deployment, `SELECT * FROM deployments WHERE uuid = $1 FOR UPDATE`, uuid); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if deployment.PendingUpdate != nil && deployment.PendingUpdate.Token != token {
+		return deployment, nil
+	}
+
+	if err := types.CheckTransition(*deployment.Status, status); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE deployments SET status = $2, status_reason = $3 WHERE uuid = $1`, uuid, status, statusReason); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if err := releaseReservation(ctx, tx, uuid, status); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return types.Deployment{}, err
+	}
+
+	return d.GetDeployment(ctx, uuid)
+}
+
+// checkQuota checks the user's quota with the deployment with uuid, or a new one when uuid is empty, using usage.
+// The user's row must be locked by tx, so that no other deployment of the user changes until tx ends.
+func checkQuota(ctx context.Context, tx *sqlx.Tx, user types.User, uuid string, usage types.Usage) error {
+	deployments := []types.Deployment{}
+	if err := tx.SelectContext(ctx, &deployments, `SELECT * FROM deployments WHERE user_id = $1`, user.ID); err != nil {
+		return err
+	}
+
+	return user.Quota().CheckDeployments(deployments, uuid, usage)
+}
+
+// releaseReservation drops the pending update and what it reserved once the deployment leaves UPDATING.
+func releaseReservation(ctx context.Context, tx *sqlx.Tx, uuid string, status string) error {
+	if status == types.DEPLOYMENT_STATUS_UPDATING {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE deployments SET reserved_memory = NULL, reserved_cpus = NULL, pending_update = NULL WHERE uuid = $1`, uuid)
+	return err
+}
+
+// checkTransition locks the deployment row until tx ends and checks that it can move to status.
+func checkTransition(ctx context.Context, tx *sqlx.Tx, uuid string, status string) error {
+	var currentStatus string
+	if err := tx.GetContext(ctx, &currentStatus, `SELECT status FROM deployments WHERE uuid = $1 FOR UPDATE`, uuid); err != nil {
+		return err
+	}
+
+	return types.CheckTransition(currentStatus, status)
+}
+
 func (d *Database) DeleteDeployment(ctx context.Context, uuid string) error {
 	if _, err := d.Client.ExecContext(ctx, `DELETE FROM deployments WHERE uuid = $1`, uuid); err != nil {
 		return err
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func (d *Database) GetDeploymentDomains(ctx context.Context, deploymentUUID string) ([]types.Domain, error) {
+	domains := []types.Domain{}
+	query := `SELECT * FROM deployment_domains WHERE deployment_id = (SELECT id FROM deployments WHERE uuid = $1) ORDER BY hostname`
+
+	if err := d.Client.SelectContext(ctx, &domains, query, deploymentUUID); err != nil {
+		return []types.Domain{}, err
+	}
+
+	return domains, nil
+}
+
+func (d *Database) GetDeploymentDomain(ctx context.Context, deploymentUUID string, uuid string) (types.Domain, error) {
+	var domain types.Domain
+	query := `SELECT * FROM deployment_domains WHERE deployment_id = (SELECT id FROM deployments WHERE uuid = $1) AND uuid = $2`
+
+	if err := d.Client.GetContext(ctx, &domain, query, deploymentUUID, uuid); err != nil {
+		return types.Domain{}, err
+	}
+
+	return domain, nil
+}
+
+// GetVerifiedDomain returns the verified domain with this hostname, whichever deployment it belongs to.
+func (d *Database) GetVerifiedDomain(ctx context.Context, hostname string) (types.Domain, error) {
+	var domain types.Domain
+	query := `SELECT * FROM deployment_domains WHERE hostname = $1 AND verified_at IS NOT NULL`
+
+	if err := d.Client.GetContext(ctx, &domain, query, hostname); err != nil {
+		return types.Domain{}, err
+	}
+
+	return domain, nil
+}
+
+func (d *Database) CreateDeploymentDomain(ctx context.Context, deploymentUUID string, hostname string, method string, token string) (types.Domain, error) {
+	var domain types.Domain
+	query := `INSERT INTO deployment_domains (deployment_id, hostname, verification_method, verification_token) VALUES ((SELECT id FROM deployments WHERE uuid = $1), $2, $3, $4) RETURNING *`
+
+	if err := d.Client.GetContext(ctx, &domain, query, deploymentUUID, hostname, method, token); err != nil {
+		return types.Domain{}, err
+	}
+
+	return domain, nil
+}
+
+func (d *Database) SetDomainVerified(ctx context.Context, uuid string) (types.Domain, error) {
+	var domain types.Domain
+	query := `UPDATE deployment_domains SET verified_at = now() WHERE uuid = $1 RETURNING *`
+
+	if err := d.Client.GetContext(ctx, &domain, query, uuid); err != nil {
+		return types.Domain{}, err
+	}
+
+	return domain, nil
+}
+
+func (d *Database) DeleteDeploymentDomain(ctx context.Context, deploymentUUID string, uuid string) error {
+	result, err := d.Client.ExecContext(ctx, `DELETE FROM deployment_domains WHERE deployment_id = (SELECT id FROM deployments WHERE uuid = $1) AND uuid = $2`, deploymentUUID, uuid)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
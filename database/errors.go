@@ -0,0 +1,21 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+const UNIQUE_VIOLATION pq.ErrorCode = "23505"
+
+// IsUniqueViolation reports whether err is a unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == UNIQUE_VIOLATION
+}
+
+// uniqueViolation is the error MemoryDatabase returns in place of a Postgres unique violation.
+func uniqueViolation(constraint string) error {
+	return &pq.Error{Code: UNIQUE_VIOLATION, Constraint: constraint, Message: fmt.Sprintf(`duplicate key value violates unique constraint "%s"`, constraint)}
+}
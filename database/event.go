@@ -0,0 +1,27 @@
+package database
+
+import (
+	"context"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// @TODO: Paginate this
+func (d *Database) GetDeploymentEvents(ctx context.Context, deploymentUUID string) ([]types.DeploymentEvent, error) {
+	events := []types.DeploymentEvent{}
+	query := `SELECT * FROM deployment_events WHERE deployment_uuid = $1 ORDER BY id DESC`
+
+	if err := d.Client.SelectContext(ctx, &events, query, deploymentUUID); err != nil {
+		return []types.DeploymentEvent{}, err
+	}
+
+	return events, nil
+}
+
+func (d *Database) CreateDeploymentEvent(ctx context.Context, deploymentUUID string, eventType string, message string) error {
+	if _, err := d.Client.ExecContext(ctx, `INSERT INTO deployment_events (deployment_uuid, type, message) VALUES ($1, $2, $3)`, deploymentUUID, eventType, message); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func (d *Database) GetDeploymentInstances(ctx context.Context, deploymentUUID string) ([]types.DeploymentInstance, error) {
+	instances := []types.DeploymentInstance{}
+	query := `SELECT * FROM deployment_instances WHERE deployment_id = (SELECT id FROM deployments WHERE uuid = $1) ORDER BY replica`
+
+	if err := d.Client.SelectContext(ctx, &instances, query, deploymentUUID); err != nil {
+		return []types.DeploymentInstance{}, err
+	}
+
+	return instances, nil
+}
+
+func (d *Database) GetAllDeploymentInstances(ctx context.Context) ([]types.DeploymentInstance, error) {
+	instances := []types.DeploymentInstance{}
+	query := `SELECT * FROM deployment_instances`
+
+	if err := d.Client.SelectContext(ctx, &instances, query); err != nil {
+		return []types.DeploymentInstance{}, err
+	}
+
+	return instances, nil
+}
+
+// SetDeploymentInstances replaces the instances of a deployment in one transaction, containerIds
+// maps each replica number to its container.
+func (d *Database) SetDeploymentInstances(ctx context.Context, deploymentUUID string, containerIds map[int]string) ([]types.DeploymentInstance, error) {
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return []types.DeploymentInstance{}, err
+	}
+
+	defer tx.Rollback()
+
+	var deploymentId int
+	if err := tx.GetContext(ctx, &deploymentId, `SELECT id FROM deployments WHERE uuid = $1 FOR UPDATE`, deploymentUUID); err != nil {
+		return []types.DeploymentInstance{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM deployment_instances WHERE deployment_id = $1`, deploymentId); err != nil {
+		return []types.DeploymentInstance{}, err
+	}
+
+	for replica, containerId := range containerIds {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO deployment_instances (deployment_id, replica, container_id) VALUES ($1, $2, $3)`, deploymentId, replica, containerId); err != nil {
+			return []types.DeploymentInstance{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return []types.DeploymentInstance{}, err
+	}
+
+	return d.GetDeploymentInstances(ctx, deploymentUUID)
+}
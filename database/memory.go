@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// MemoryDatabase is an in-memory implementation of Repository for the single binary dev mode and tests.
+// Nothing is persisted, everything is lost when the process exits.
+type MemoryDatabase struct {
+	mu sync.Mutex
+
+	users       []types.User
+	deployments []types.Deployment
+	instances   []types.DeploymentInstance
+	revisions   []types.DeploymentRevision
+	domains     []types.Domain
+	ports       []types.DeploymentPort
+	tasks       []types.TaskRecord
+	events      []types.DeploymentEvent
+	secrets     []types.Secret
+
+	registryCredentials []types.RegistryCredential
+
+	nextID int
+}
+
+var _ Repository = (*MemoryDatabase)(nil)
+
+func NewMemoryDatabase() *MemoryDatabase {
+	return &MemoryDatabase{}
+}
+
+func (m *MemoryDatabase) Ping(ctx context.Context) error {
+	return nil
+}
+
+// newID returns the next row id, shared across tables. Must be called with mu held.
+func (m *MemoryDatabase) newID() *int {
+	m.nextID++
+	return ptr(m.nextID)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func copyPtr[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+
+	return ptr(*v)
+}
+
+func now() *time.Time {
+	return ptr(time.Now())
+}
@@ -0,0 +1,389 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+)
+
+func (m *MemoryDatabase) GetDeployment(ctx context.Context, uuidOrSubdomain string) (types.Deployment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.findDeployment(uuidOrSubdomain)
+	if index == -1 {
+		return types.Deployment{}, sql.ErrNoRows
+	}
+
+	return m.deployments[index], nil
+}
+
+func (m *MemoryDatabase) GetAllDeploymentsForUser(ctx context.Context, userUUID string) ([]types.Deployment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deployments := []types.Deployment{}
+
+	user, err := m.findUser(func(user types.User) bool { return *user.UUID == userUUID })
+	if err != nil {
+		return deployments, nil
+	}
+
+	for _, deployment := range m.deployments {
+		if *deployment.UserId == *user.ID {
+			deployments = append(deployments, deployment)
+		}
+	}
+
+	return deployments, nil
+}
+
+func (m *MemoryDatabase) GetAllDeployments(ctx context.Context) ([]types.Deployment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]types.Deployment{}, m.deployments...), nil
+}
+
+func (m *MemoryDatabase) GetDeploymentsByStatus(ctx context.Context, status string) ([]types.Deployment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deployments := []types.Deployment{}
+	for _, deployment := range m.deployments {
+		if *deployment.Status == status {
+			deployments = append(deployments, deployment)
+		}
+	}
+
+	return deployments, nil
+}
+
+func (m *MemoryDatabase) CreateDeployment(ctx context.Context, deploymentAttributes types.DeploymentAttributes) (types.Deployment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, err := m.findUser(func(user types.User) bool { return *user.UUID == deploymentAttributes.UserUUID })
+	if err != nil {
+		return types.Deployment{}, err
+	}
+
+	if err := m.checkDeploymentConstraints("", deploymentAttributes); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if err := m.checkQuota(user, "", types.ResourceUsage(deploymentAttributes.ResourceSpec, deploymentAttributes.Replicas)); err != nil {
+		return types.Deployment{}, err
+	}
+
+	uuid, err := utils.GenerateUUID()
+	if err != nil {
+		return types.Deployment{}, err
+	}
+
+	deployment := types.Deployment{
+		ID:        m.newID(),
+		UserId:    user.ID,
+		UUID:      &uuid,
+		Subdomain: ptr(deploymentAttributes.Subdomain),
+		ImageTag:  ptr(deploymentAttributes.ImageTag),
+		Port:      copyPtr(deploymentAttributes.Port),
+		Replicas:  ptr(deploymentAttributes.Replicas),
+		Status:    ptr(deploymentAttributes.Status),
+
+		ResourceSpec: copyResourceSpec(deploymentAttributes.ResourceSpec),
+		HealthCheck:  copyHealthCheck(deploymentAttributes.HealthCheck),
+		Routing:      copyRouting(deploymentAttributes.Routing),
+		Middlewares:  copyMiddlewares(deploymentAttributes.Middlewares),
+		EnvConfig:    copyEnvConfig(deploymentAttributes.EnvConfig),
+
+		CreatedAt: now(),
+		UpdatedAt: now(),
+	}
+
+	m.deployments = append(m.deployments, deployment)
+
+	return deployment, nil
+}
+
+func (m *MemoryDatabase) UpdateDeployment(ctx context.Context, deploymentAttributes types.DeploymentAttributes) (types.Deployment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.findDeployment(deploymentAttributes.UUID)
+	if index == -1 {
+		return types.Deployment{}, sql.ErrNoRows
+	}
+
+	if err := types.CheckTransition(*m.deployments[index].Status, deploymentAttributes.Status); err != nil {
+		return types.Deployment{}, err
+	}
+
+	if err := m.checkDeploymentConstraints(deploymentAttributes.UUID, deploymentAttributes); err != nil {
+		return types.Deployment{}, err
+	}
+
+	deployment := m.deployments[index]
+	deployment.Subdomain = ptr(deploymentAttributes.Subdomain)
+	deployment.ImageTag = ptr(deploymentAttributes.ImageTag)
+	deployment.Port = copyPtr(deploymentAttributes.Port)
+	deployment.Replicas = ptr(deploymentAttributes.Replicas)
+	deployment.Status = ptr(deploymentAttributes.Status)
+	deployment.StatusReason = copyPtr(deploymentAttributes.StatusReason)
+	deployment.HealthCheck = copyHealthCheck(deploymentAttributes.HealthCheck)
+	deployment.Routing = copyRouting(deploymentAttributes.Routing)
+	deployment.Middlewares = copyMiddlewares(deploymentAttributes.Middlewares)
+	deployment.ResourceSpec = copyResourceSpec(deploymentAttributes.ResourceSpec)
+	deployment.EnvConfig = copyEnvConfig(deploymentAttributes.EnvConfig)
+	deployment.UpdatedAt = now()
+	dropReservation(&deployment)
+
+	m.deployments[index] = deployment
+
+	return deployment, nil
+}
+
+func (m *MemoryDatabase) UpdateDeploymentStatus(ctx context.Context, uuid string, status string, statusReason *string) (types.Deployment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.findDeployment(uuid)
+	if index == -1 {
+		return types.Deployment{}, sql.ErrNoRows
+	}
+
+	if err := types.CheckTransition(*m.deployments[index].Status, status); err != nil {
+		return types.Deployment{}, err
+	}
+
+	deployment := m.deployments[index]
+	deployment.Status = ptr(status)
+	deployment.StatusReason = copyPtr(statusReason)
+	deployment.UpdatedAt = now()
+	dropReservation(&deployment)
+
+	m.deployments[index] = deployment
+
+	return deployment, nil
+}
+
+func (m *MemoryDatabase) ReserveDeploymentUpdate(ctx context.Context, uuid string, update types.DeploymentConfig, usage *types.Usage) (types.Deployment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.findDeployment(uuid)
+	if index == -1 {
+		return types.Deployment{}, sql.ErrNoRows
+	}
+
+	if err := types.CheckTransition(*m.deployments[index].Status, types.DEPLOYMENT_STATUS_UPDATING); err != nil {
+		return types.Deployment{}, err
+	}
+
+	deployment := m.deployments[index]
+
+	if usage != nil {
+		user, err := m.findUser(func(user types.User) bool { return *user.ID == *deployment.UserId })
+		if err != nil {
+			return types.Deployment{}, err
+		}
+
+		reservation := deployment.Reservation(*usage)
+		if err := m.checkQuota(user, uuid, reservation); err != nil {
+			return types.Deployment{}, err
+		}
+		deployment.ReservedMemory = ptr(reservation.Memory)
+		deployment.ReservedCPUs = ptr(reservation.CPUs)
+	}
+
+	deployment.Status = ptr(types.DEPLOYMENT_STATUS_UPDATING)
+	deployment.StatusReason = nil
+	deployment.PendingUpdate = copyDeploymentConfig(&update)
+	deployment.UpdatedAt = now()
+
+	m.deployments[index] = deployment
+
+	return deployment, nil
+}
+
+func (m *MemoryDatabase) SettleDeploymentUpdate(ctx context.Context, uuid string, token string, status string, statusReason *string) (types.Deployment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.findDeployment(uuid)
+	if index == -1 {
+		return types.Deployment{}, sql.ErrNoRows
+	}
+
+	deployment := m.deployments[index]
+	if deployment.PendingUpdate != nil && deployment.PendingUpdate.Token != token {
+		return deployment, nil
+	}
+
+	if err := types.CheckTransition(*deployment.Status, status); err != nil {
+		return types.Deployment{}, err
+	}
+
+	deployment.Status = ptr(status)
+	deployment.StatusReason = copyPtr(statusReason)
+	deployment.UpdatedAt = now()
+	dropReservation(&deployment)
+
+	m.deployments[index] = deployment
+
+	return deployment, nil
+}
+
+// checkQuota checks the user's quota with the deployment with uuid, or a new one when uuid is empty, using usage.
+// The caller holds mu.
+func (m *MemoryDatabase) checkQuota(user types.User, uuid string, usage types.Usage) error {
+	deployments := []types.Deployment{}
+	for _, deployment := range m.deployments {
+		if *deployment.UserId == *user.ID {
+			deployments = append(deployments, deployment)
+		}
+	}
+
+	return user.Quota().CheckDeployments(deployments, uuid, usage)
+}
+
+// dropReservation clears the pending update and what it reserved once the deployment left UPDATING.
+func dropReservation(deployment *types.Deployment) {
+	if *deployment.Status != types.DEPLOYMENT_STATUS_UPDATING {
+		deployment.ReservedMemory = nil
+		deployment.ReservedCPUs = nil
+		deployment.PendingUpdate = nil
+	}
+}
+
+func (m *MemoryDatabase) DeleteDeployment(ctx context.Context, uuid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, deployment := range m.deployments {
+		if *deployment.UUID == uuid {
+			m.removeInstancesOf(*deployment.ID)
+			m.removeRevisionsOf(*deployment.ID)
+			m.removeDomainsOf(*deployment.ID)
+			m.removePortsOf(*deployment.ID)
+			m.deployments = append(m.deployments[:i], m.deployments[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// findDeployment returns the index of the deployment or -1. Must be called with mu held.
+func (m *MemoryDatabase) findDeployment(uuidOrSubdomain string) int {
+	for i, deployment := range m.deployments {
+		if *deployment.UUID == uuidOrSubdomain || *deployment.Subdomain == uuidOrSubdomain {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// checkDeploymentConstraints mirrors the UNIQUE constraints of the deployments table. Must be called with mu held.
+func (m *MemoryDatabase) checkDeploymentConstraints(uuid string, deploymentAttributes types.DeploymentAttributes) error {
+	for _, deployment := range m.deployments {
+		if *deployment.UUID == uuid {
+			continue
+		}
+
+		switch {
+		case *deployment.Subdomain == deploymentAttributes.Subdomain:
+			return uniqueViolation("deployments_sub_domain_key")
+		case deployment.Port != nil && deploymentAttributes.Port != nil && *deployment.Port == *deploymentAttributes.Port:
+			return uniqueViolation("deployments_port_key")
+		}
+	}
+
+	return nil
+}
+
+func copyResourceSpec(resources types.ResourceSpec) types.ResourceSpec {
+	return types.ResourceSpec{
+		CPUShares:         copyPtr(resources.CPUShares),
+		CPUQuota:          copyPtr(resources.CPUQuota),
+		CPUPeriod:         copyPtr(resources.CPUPeriod),
+		MemoryLimit:       copyPtr(resources.MemoryLimit),
+		MemoryReservation: copyPtr(resources.MemoryReservation),
+		PidsLimit:         copyPtr(resources.PidsLimit),
+		Ulimits:           append(types.Ulimits(nil), resources.Ulimits...),
+	}
+}
+
+func copyEnvConfig(envConfig types.EnvConfig) types.EnvConfig {
+	if envConfig == nil {
+		return nil
+	}
+
+	copied := make(types.EnvConfig, len(envConfig))
+	for key, value := range envConfig {
+		copied[key] = value
+	}
+	return copied
+}
+
+func copyDeploymentConfig(config *types.DeploymentConfig) *types.DeploymentConfig {
+	if config == nil {
+		return nil
+	}
+
+	copied := *config
+	copied.EnvConfig = copyEnvConfig(config.EnvConfig)
+	copied.Resources = copyResourceSpec(config.Resources)
+	copied.HealthCheck = copyHealthCheck(config.HealthCheck)
+	copied.Routing = copyRouting(config.Routing)
+	copied.Middlewares = copyMiddlewares(config.Middlewares)
+	return &copied
+}
+
+func copyHealthCheck(healthCheck *types.HealthCheck) *types.HealthCheck {
+	if healthCheck == nil {
+		return nil
+	}
+
+	copied := *healthCheck
+	copied.Port = copyPtr(healthCheck.Port)
+	copied.Command = append([]string(nil), healthCheck.Command...)
+	return &copied
+}
+
+func copyRouting(routing *types.RoutingSpec) *types.RoutingSpec {
+	if routing == nil {
+		return nil
+	}
+
+	copied := *routing
+	if routing.Headers != nil {
+		copied.Headers = make(map[string]string, len(routing.Headers))
+		for name, value := range routing.Headers {
+			copied.Headers[name] = value
+		}
+	}
+	return &copied
+}
+
+// copyMiddlewares round trips the spec through JSON, it's nested too deeply to copy by hand.
+func copyMiddlewares(middlewares *types.MiddlewareSpec) *types.MiddlewareSpec {
+	if middlewares == nil {
+		return nil
+	}
+
+	encoded, err := json.Marshal(middlewares)
+	if err != nil {
+		panic(err)
+	}
+
+	var copied types.MiddlewareSpec
+	if err := json.Unmarshal(encoded, &copied); err != nil {
+		panic(err)
+	}
+	return &copied
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func TestDeploymentQuotaReservations(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryDatabase()
+
+	user, err := db.CreateUser(ctx, types.CreateUserRequest{Username: "quota", ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpdateUserQuota(ctx, *user.UUID, types.Quota{MaxDeployments: 5, MaxMemory: 1 << 40, MaxCPUs: 3, MaxPublicPorts: 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	port := 8000
+	create := func(subdomain string, replicas int) (types.Deployment, error) {
+		port++
+		return db.CreateDeployment(ctx, types.DeploymentAttributes{UserUUID: *user.UUID, Subdomain: subdomain, ImageTag: "nginx", Port: ptr(port), Status: types.DEPLOYMENT_STATUS_READY, Replicas: replicas, ResourceSpec: types.ResourceSpec{}.WithDefaults()})
+	}
+
+	first, err := create("first", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usage := types.ResourceUsage(first.ResourceSpec, 2)
+	if _, err := db.ReserveDeploymentUpdate(ctx, *first.UUID, first.Config(), &usage); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first deployment still runs one replica, the update in progress has reserved a second CPU
+	var quotaErr types.QuotaExceededError
+	if _, err := create("second", 2); !errors.As(err, &quotaErr) {
+		t.Fatalf("expected the reserved CPU to count against the quota, got %v", err)
+	}
+
+	if _, err := db.UpdateDeploymentStatus(ctx, *first.UUID, types.DEPLOYMENT_STATUS_READY, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := create("second", 2); err != nil {
+		t.Fatalf("expected the reservation to be released once the update is done, got %s", err)
+	}
+
+	if _, err := db.ReserveDeploymentUpdate(ctx, *first.UUID, first.Config(), &usage); !errors.As(err, &quotaErr) {
+		t.Fatalf("expected scaling past the quota to be refused, got %v", err)
+	}
+
+	deployment, err := db.GetDeployment(ctx, *first.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Status != types.DEPLOYMENT_STATUS_READY || deployment.ReservedCPUs != nil {
+		t.Errorf("expected a refused update to leave the deployment as it was, got %s with %v reserved", *deployment.Status, deployment.ReservedCPUs)
+	}
+
+	if _, err := db.CreateDeploymentPort(ctx, *first.UUID, "tcp", types.PORT_PROTOCOL_TCP, 5432, nil); !errors.As(err, &quotaErr) {
+		t.Errorf("expected a public port past the quota to be refused, got %v", err)
+	}
+}
+
+func TestQueuedUpdatesKeepTheReservation(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryDatabase()
+
+	user, err := db.CreateUser(ctx, types.CreateUserRequest{Username: "quota", ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deployment, err := db.CreateDeployment(ctx, types.DeploymentAttributes{UserUUID: *user.UUID, Subdomain: "web", ImageTag: "nginx", Port: ptr(8080), Status: types.DEPLOYMENT_STATUS_READY, Replicas: 1, ResourceSpec: types.ResourceSpec{}.WithDefaults()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := deployment.Config()
+	first.Token = "first"
+	first.Replicas = 3
+	usage := types.ResourceUsage(deployment.ResourceSpec, 3)
+	if _, err := db.ReserveDeploymentUpdate(ctx, *deployment.UUID, first, &usage); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second update needs less, but the first one still runs three replicas before it
+	second := first
+	second.Token = "second"
+	second.Replicas = 2
+	usage = types.ResourceUsage(deployment.ResourceSpec, 2)
+	if _, err := db.ReserveDeploymentUpdate(ctx, *deployment.UUID, second, &usage); err != nil {
+		t.Fatal(err)
+	}
+
+	deployment, err = db.SettleDeploymentUpdate(ctx, *deployment.UUID, "first", types.DEPLOYMENT_STATUS_READY, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Status != types.DEPLOYMENT_STATUS_UPDATING || deployment.ReservedCPUs == nil || *deployment.ReservedCPUs != 3 {
+		t.Fatalf("expected the second update to keep the deployment UPDATING with 3 CPUs reserved, got %s with %v", *deployment.Status, deployment.ReservedCPUs)
+	}
+	if deployment.PendingUpdate == nil || deployment.PendingUpdate.Token != "second" {
+		t.Fatalf("expected the second update to stay pending, got %v", deployment.PendingUpdate)
+	}
+
+	deployment, err = db.SettleDeploymentUpdate(ctx, *deployment.UUID, "second", types.DEPLOYMENT_STATUS_READY, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Status != types.DEPLOYMENT_STATUS_READY || deployment.ReservedCPUs != nil || deployment.PendingUpdate != nil {
+		t.Errorf("expected the last update to release the reservation, got %s with %v", *deployment.Status, deployment.ReservedCPUs)
+	}
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+)
+
+func (m *MemoryDatabase) GetDeploymentDomains(ctx context.Context, deploymentUUID string) ([]types.Domain, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	domains := []types.Domain{}
+
+	i := m.findDeployment(deploymentUUID)
+	if i == -1 {
+		return domains, nil
+	}
+
+	for _, domain := range m.domains {
+		if *domain.DeploymentId == *m.deployments[i].ID {
+			domains = append(domains, copyDomain(domain))
+		}
+	}
+
+	sort.Slice(domains, func(i, j int) bool { return *domains[i].Hostname < *domains[j].Hostname })
+
+	return domains, nil
+}
+
+func (m *MemoryDatabase) GetDeploymentDomain(ctx context.Context, deploymentUUID string, uuid string) (types.Domain, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, err := m.findDomain(deploymentUUID, uuid)
+	if err != nil {
+		return types.Domain{}, err
+	}
+
+	return copyDomain(m.domains[j]), nil
+}
+
+func (m *MemoryDatabase) GetVerifiedDomain(ctx context.Context, hostname string) (types.Domain, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, domain := range m.domains {
+		if *domain.Hostname == hostname && domain.Verified() {
+			return copyDomain(domain), nil
+		}
+	}
+
+	return types.Domain{}, sql.ErrNoRows
+}
+
+func (m *MemoryDatabase) CreateDeploymentDomain(ctx context.Context, deploymentUUID string, hostname string, method string, token string) (types.Domain, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.findDeployment(deploymentUUID)
+	if i == -1 {
+		return types.Domain{}, sql.ErrNoRows
+	}
+
+	for _, domain := range m.domains {
+		if *domain.DeploymentId == *m.deployments[i].ID && *domain.Hostname == hostname {
+			return types.Domain{}, uniqueViolation("deployment_domains_deployment_id_hostname_key")
+		}
+	}
+
+	uuid, err := utils.GenerateUUID()
+	if err != nil {
+		return types.Domain{}, err
+	}
+
+	domain := types.Domain{
+		ID:                 m.newID(),
+		UUID:               &uuid,
+		DeploymentId:       m.deployments[i].ID,
+		Hostname:           ptr(hostname),
+		VerificationMethod: ptr(method),
+		VerificationToken:  ptr(token),
+		CreatedAt:          now(),
+		UpdatedAt:          now(),
+	}
+
+	m.domains = append(m.domains, domain)
+
+	return copyDomain(domain), nil
+}
+
+func (m *MemoryDatabase) SetDomainVerified(ctx context.Context, uuid string) (types.Domain, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, domain := range m.domains {
+		if *domain.UUID != uuid {
+			continue
+		}
+
+		for _, other := range m.domains {
+			if *other.UUID != uuid && *other.Hostname == *domain.Hostname && other.Verified() {
+				return types.Domain{}, uniqueViolation("deployment_domains_verified_hostname_key")
+			}
+		}
+
+		m.domains[i].VerifiedAt = now()
+		m.domains[i].UpdatedAt = now()
+
+		return copyDomain(m.domains[i]), nil
+	}
+
+	return types.Domain{}, sql.ErrNoRows
+}
+
+func (m *MemoryDatabase) DeleteDeploymentDomain(ctx context.Context, deploymentUUID string, uuid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, err := m.findDomain(deploymentUUID, uuid)
+	if err != nil {
+		return err
+	}
+
+	m.domains = append(m.domains[:j], m.domains[j+1:]...)
+
+	return nil
+}
+
+// findDomain returns the index of a domain of the deployment. Must be called with mu held.
+func (m *MemoryDatabase) findDomain(deploymentUUID string, uuid string) (int, error) {
+	i := m.findDeployment(deploymentUUID)
+	if i == -1 {
+		return -1, sql.ErrNoRows
+	}
+
+	for j, domain := range m.domains {
+		if *domain.DeploymentId == *m.deployments[i].ID && *domain.UUID == uuid {
+			return j, nil
+		}
+	}
+
+	return -1, sql.ErrNoRows
+}
+
+// removeDomainsOf mirrors the ON DELETE CASCADE of deployment_domains. Must be called with mu held.
+func (m *MemoryDatabase) removeDomainsOf(deploymentId int) {
+	domains := m.domains[:0]
+	for _, domain := range m.domains {
+		if *domain.DeploymentId != deploymentId {
+			domains = append(domains, domain)
+		}
+	}
+	m.domains = domains
+}
+
+func copyDomain(domain types.Domain) types.Domain {
+	copied := domain
+	copied.VerifiedAt = copyPtr(domain.VerifiedAt)
+	return copied
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func (m *MemoryDatabase) GetDeploymentEvents(ctx context.Context, deploymentUUID string) ([]types.DeploymentEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := []types.DeploymentEvent{}
+	for i := len(m.events) - 1; i >= 0; i-- {
+		if *m.events[i].DeploymentUUID == deploymentUUID {
+			events = append(events, m.events[i])
+		}
+	}
+
+	return events, nil
+}
+
+func (m *MemoryDatabase) CreateDeploymentEvent(ctx context.Context, deploymentUUID string, eventType string, message string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, types.DeploymentEvent{
+		ID:             m.newID(),
+		DeploymentUUID: ptr(deploymentUUID),
+		Type:           ptr(eventType),
+		Message:        ptr(message),
+		CreatedAt:      now(),
+	})
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func (m *MemoryDatabase) GetDeploymentInstances(ctx context.Context, deploymentUUID string) ([]types.DeploymentInstance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.findDeployment(deploymentUUID)
+	if index == -1 {
+		return []types.DeploymentInstance{}, nil
+	}
+
+	return m.instancesOf(*m.deployments[index].ID), nil
+}
+
+func (m *MemoryDatabase) GetAllDeploymentInstances(ctx context.Context) ([]types.DeploymentInstance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]types.DeploymentInstance{}, m.instances...), nil
+}
+
+func (m *MemoryDatabase) SetDeploymentInstances(ctx context.Context, deploymentUUID string, containerIds map[int]string) ([]types.DeploymentInstance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.findDeployment(deploymentUUID)
+	if index == -1 {
+		return []types.DeploymentInstance{}, sql.ErrNoRows
+	}
+
+	deploymentId := *m.deployments[index].ID
+
+	for _, instance := range m.instances {
+		if *instance.DeploymentId == deploymentId {
+			continue
+		}
+		for _, containerId := range containerIds {
+			if *instance.ContainerId == containerId {
+				return []types.DeploymentInstance{}, uniqueViolation("deployment_instances_container_id_key")
+			}
+		}
+	}
+
+	m.removeInstancesOf(deploymentId)
+
+	for replica, containerId := range containerIds {
+		m.instances = append(m.instances, types.DeploymentInstance{
+			ID:           m.newID(),
+			DeploymentId: ptr(deploymentId),
+			Replica:      ptr(replica),
+			ContainerId:  ptr(containerId),
+			CreatedAt:    now(),
+			UpdatedAt:    now(),
+		})
+	}
+
+	return m.instancesOf(deploymentId), nil
+}
+
+// instancesOf returns the instances of a deployment ordered by replica. Must be called with mu held.
+func (m *MemoryDatabase) instancesOf(deploymentId int) []types.DeploymentInstance {
+	instances := []types.DeploymentInstance{}
+	for _, instance := range m.instances {
+		if *instance.DeploymentId == deploymentId {
+			instances = append(instances, instance)
+		}
+	}
+
+	sort.Slice(instances, func(i, j int) bool { return *instances[i].Replica < *instances[j].Replica })
+
+	return instances
+}
+
+// removeInstancesOf mirrors the ON DELETE CASCADE of deployment_instances. Must be called with mu held.
+func (m *MemoryDatabase) removeInstancesOf(deploymentId int) {
+	remaining := m.instances[:0]
+	for _, instance := range m.instances {
+		if *instance.DeploymentId != deploymentId {
+			remaining = append(remaining, instance)
+		}
+	}
+
+	m.instances = remaining
+}
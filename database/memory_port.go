@@ -0,0 +1,187 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+)
+
+func (m *MemoryDatabase) GetDeploymentPorts(ctx context.Context, deploymentUUID string) ([]types.DeploymentPort, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ports := []types.DeploymentPort{}
+
+	i := m.findDeployment(deploymentUUID)
+	if i == -1 {
+		return ports, nil
+	}
+
+	for _, port := range m.ports {
+		if *port.DeploymentId == *m.deployments[i].ID {
+			ports = append(ports, copyPort(port))
+		}
+	}
+
+	sort.Slice(ports, func(i, j int) bool { return *ports[i].Name < *ports[j].Name })
+
+	return ports, nil
+}
+
+func (m *MemoryDatabase) CountPublicPortsForUser(ctx context.Context, userUUID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, err := m.findUser(func(user types.User) bool { return *user.UUID == userUUID })
+	if err != nil {
+		return 0, nil
+	}
+
+	return m.countPublicPorts(user), nil
+}
+
+// countPublicPorts counts the user's ports that have a public port, the caller holds mu.
+func (m *MemoryDatabase) countPublicPorts(user types.User) int {
+	count := 0
+	for _, port := range m.ports {
+		if port.PublicPort == nil {
+			continue
+		}
+		for _, deployment := range m.deployments {
+			if *deployment.ID == *port.DeploymentId && *deployment.UserId == *user.ID {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+func (m *MemoryDatabase) GetDeploymentPort(ctx context.Context, deploymentUUID string, uuid string) (types.DeploymentPort, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, err := m.findPort(deploymentUUID, uuid)
+	if err != nil {
+		return types.DeploymentPort{}, err
+	}
+
+	return copyPort(m.ports[j]), nil
+}
+
+func (m *MemoryDatabase) CreateDeploymentPort(ctx context.Context, deploymentUUID string, name string, protocol string, containerPort int, tls *string) (types.DeploymentPort, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.findDeployment(deploymentUUID)
+	if i == -1 {
+		return types.DeploymentPort{}, sql.ErrNoRows
+	}
+
+	used := map[int]bool{}
+	for _, port := range m.ports {
+		if *port.DeploymentId == *m.deployments[i].ID && *port.Name == name {
+			return types.DeploymentPort{}, uniqueViolation("deployment_ports_deployment_id_name_key")
+		}
+		if *port.Protocol == protocol && port.PublicPort != nil {
+			used[*port.PublicPort] = true
+		}
+	}
+
+	var publicPort *int
+	if tls == nil {
+		user, err := m.findUser(func(user types.User) bool { return *user.ID == *m.deployments[i].UserId })
+		if err != nil {
+			return types.DeploymentPort{}, err
+		}
+
+		if err := user.Quota().Check(types.Usage{PublicPorts: m.countPublicPorts(user) + 1}); err != nil {
+			return types.DeploymentPort{}, err
+		}
+
+		for candidate := config.PORT_POOL_START; candidate <= config.PORT_POOL_END; candidate++ {
+			if !used[candidate] {
+				publicPort = ptr(candidate)
+				break
+			}
+		}
+
+		if publicPort == nil {
+			return types.DeploymentPort{}, types.ErrPortPoolExhausted
+		}
+	}
+
+	uuid, err := utils.GenerateUUID()
+	if err != nil {
+		return types.DeploymentPort{}, err
+	}
+
+	port := types.DeploymentPort{
+		ID:            m.newID(),
+		UUID:          &uuid,
+		DeploymentId:  m.deployments[i].ID,
+		Name:          ptr(name),
+		Protocol:      ptr(protocol),
+		ContainerPort: ptr(containerPort),
+		TLS:           copyPtr(tls),
+		PublicPort:    publicPort,
+		CreatedAt:     now(),
+		UpdatedAt:     now(),
+	}
+
+	m.ports = append(m.ports, port)
+
+	return copyPort(port), nil
+}
+
+func (m *MemoryDatabase) DeleteDeploymentPort(ctx context.Context, deploymentUUID string, uuid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, err := m.findPort(deploymentUUID, uuid)
+	if err != nil {
+		return err
+	}
+
+	m.ports = append(m.ports[:j], m.ports[j+1:]...)
+
+	return nil
+}
+
+// findPort returns the index of a port of the deployment. Must be called with mu held.
+func (m *MemoryDatabase) findPort(deploymentUUID string, uuid string) (int, error) {
+	i := m.findDeployment(deploymentUUID)
+	if i == -1 {
+		return -1, sql.ErrNoRows
+	}
+
+	for j, port := range m.ports {
+		if *port.DeploymentId == *m.deployments[i].ID && *port.UUID == uuid {
+			return j, nil
+		}
+	}
+
+	return -1, sql.ErrNoRows
+}
+
+// removePortsOf mirrors the ON DELETE CASCADE of deployment_ports, which releases their public ports. Must be called with mu held.
+func (m *MemoryDatabase) removePortsOf(deploymentId int) {
+	ports := m.ports[:0]
+	for _, port := range m.ports {
+		if *port.DeploymentId != deploymentId {
+			ports = append(ports, port)
+		}
+	}
+	m.ports = ports
+}
+
+func copyPort(port types.DeploymentPort) types.DeploymentPort {
+	copied := port
+	copied.TLS = copyPtr(port.TLS)
+	copied.PublicPort = copyPtr(port.PublicPort)
+	return copied
+}
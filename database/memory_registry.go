@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+)
+
+func (m *MemoryDatabase) GetRegistryCredentialsForUser(ctx context.Context, userUUID string) ([]types.RegistryCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credentials := []types.RegistryCredential{}
+
+	user, err := m.findUser(func(user types.User) bool { return *user.UUID == userUUID })
+	if err != nil {
+		return credentials, nil
+	}
+
+	for _, credential := range m.registryCredentials {
+		if *credential.UserId == *user.ID {
+			credentials = append(credentials, copyRegistryCredential(credential))
+		}
+	}
+
+	sort.Slice(credentials, func(i, j int) bool { return *credentials[i].Host < *credentials[j].Host })
+
+	return credentials, nil
+}
+
+func (m *MemoryDatabase) GetRegistryCredential(ctx context.Context, userUUID string, uuid string) (types.RegistryCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, err := m.findRegistryCredential(userUUID, func(credential types.RegistryCredential) bool { return *credential.UUID == uuid })
+	if err != nil {
+		return types.RegistryCredential{}, err
+	}
+
+	return copyRegistryCredential(m.registryCredentials[i]), nil
+}
+
+func (m *MemoryDatabase) GetRegistryCredentialForDeployment(ctx context.Context, deploymentUUID string, host string) (types.RegistryCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.findDeployment(deploymentUUID)
+	if i == -1 {
+		return types.RegistryCredential{}, sql.ErrNoRows
+	}
+
+	for _, credential := range m.registryCredentials {
+		if *credential.UserId == *m.deployments[i].UserId && *credential.Host == host {
+			return copyRegistryCredential(credential), nil
+		}
+	}
+
+	return types.RegistryCredential{}, sql.ErrNoRows
+}
+
+func (m *MemoryDatabase) CreateRegistryCredential(ctx context.Context, userUUID string, host string, username string, ciphertext []byte, encryptedKey []byte) (types.RegistryCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, err := m.findUser(func(user types.User) bool { return *user.UUID == userUUID })
+	if err != nil {
+		return types.RegistryCredential{}, err
+	}
+
+	if _, err := m.findRegistryCredential(userUUID, func(credential types.RegistryCredential) bool { return *credential.Host == host }); err == nil {
+		return types.RegistryCredential{}, uniqueViolation("registry_credentials_user_id_host_key")
+	}
+
+	uuid, err := utils.GenerateUUID()
+	if err != nil {
+		return types.RegistryCredential{}, err
+	}
+
+	credential := types.RegistryCredential{
+		ID:           m.newID(),
+		UUID:         &uuid,
+		UserId:       user.ID,
+		Host:         ptr(host),
+		Username:     ptr(username),
+		Ciphertext:   append([]byte(nil), ciphertext...),
+		EncryptedKey: append([]byte(nil), encryptedKey...),
+		CreatedAt:    now(),
+		UpdatedAt:    now(),
+	}
+
+	m.registryCredentials = append(m.registryCredentials, credential)
+
+	return copyRegistryCredential(credential), nil
+}
+
+func (m *MemoryDatabase) UpdateRegistryCredential(ctx context.Context, userUUID string, uuid string, username string, ciphertext []byte, encryptedKey []byte) (types.RegistryCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, err := m.findRegistryCredential(userUUID, func(credential types.RegistryCredential) bool { return *credential.UUID == uuid })
+	if err != nil {
+		return types.RegistryCredential{}, err
+	}
+
+	m.registryCredentials[i].Username = ptr(username)
+	m.registryCredentials[i].Ciphertext = append([]byte(nil), ciphertext...)
+	m.registryCredentials[i].EncryptedKey = append([]byte(nil), encryptedKey...)
+	m.registryCredentials[i].UpdatedAt = now()
+
+	return copyRegistryCredential(m.registryCredentials[i]), nil
+}
+
+func (m *MemoryDatabase) DeleteRegistryCredential(ctx context.Context, userUUID string, uuid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, err := m.findRegistryCredential(userUUID, func(credential types.RegistryCredential) bool { return *credential.UUID == uuid })
+	if err != nil {
+		return err
+	}
+
+	m.registryCredentials = append(m.registryCredentials[:i], m.registryCredentials[i+1:]...)
+
+	return nil
+}
+
+// findRegistryCredential returns the index of the first of the user's credentials matching match. Must be called with mu held.
+func (m *MemoryDatabase) findRegistryCredential(userUUID string, match func(types.RegistryCredential) bool) (int, error) {
+	user, err := m.findUser(func(user types.User) bool { return *user.UUID == userUUID })
+	if err != nil {
+		return -1, err
+	}
+
+	for i, credential := range m.registryCredentials {
+		if *credential.UserId == *user.ID && match(credential) {
+			return i, nil
+		}
+	}
+
+	return -1, sql.ErrNoRows
+}
+
+func copyRegistryCredential(credential types.RegistryCredential) types.RegistryCredential {
+	copied := credential
+	copied.Ciphertext = append([]byte(nil), credential.Ciphertext...)
+	copied.EncryptedKey = append([]byte(nil), credential.EncryptedKey...)
+	return copied
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func (m *MemoryDatabase) GetDeploymentRevisions(ctx context.Context, deploymentUUID string) ([]types.DeploymentRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	revisions := []types.DeploymentRevision{}
+
+	i := m.findDeployment(deploymentUUID)
+	if i == -1 {
+		return revisions, nil
+	}
+
+	for j := len(m.revisions) - 1; j >= 0; j-- {
+		if *m.revisions[j].DeploymentId == *m.deployments[i].ID {
+			revisions = append(revisions, copyRevision(m.revisions[j]))
+		}
+	}
+
+	return revisions, nil
+}
+
+func (m *MemoryDatabase) GetDeploymentRevision(ctx context.Context, deploymentUUID string, revision int) (types.DeploymentRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.findDeployment(deploymentUUID)
+	if i == -1 {
+		return types.DeploymentRevision{}, sql.ErrNoRows
+	}
+
+	for _, deploymentRevision := range m.revisions {
+		if *deploymentRevision.DeploymentId == *m.deployments[i].ID && *deploymentRevision.Revision == revision {
+			return copyRevision(deploymentRevision), nil
+		}
+	}
+
+	return types.DeploymentRevision{}, sql.ErrNoRows
+}
+
+func (m *MemoryDatabase) CreateDeploymentRevision(ctx context.Context, revisionAttributes types.DeploymentRevisionAttributes) (types.DeploymentRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.findDeployment(revisionAttributes.DeploymentUUID)
+	if i == -1 {
+		return types.DeploymentRevision{}, sql.ErrNoRows
+	}
+	deploymentId := *m.deployments[i].ID
+
+	// Revisions are appended in order, so the last one of the deployment has the highest number
+	number := 1
+	for _, deploymentRevision := range m.revisions {
+		if *deploymentRevision.DeploymentId == deploymentId {
+			number = *deploymentRevision.Revision + 1
+		}
+	}
+
+	envConfig := copyEnvConfig(revisionAttributes.EnvConfig)
+	if envConfig == nil {
+		envConfig = types.EnvConfig{}
+	}
+
+	revision := types.DeploymentRevision{
+		ID:           m.newID(),
+		DeploymentId: ptr(deploymentId),
+		Revision:     ptr(number),
+		ImageTag:     ptr(revisionAttributes.ImageTag),
+		Subdomain:    ptr(revisionAttributes.Subdomain),
+		Port:         ptr(revisionAttributes.Port),
+		EnvConfig:    envConfig,
+
+		ResourceSpec: copyResourceSpec(revisionAttributes.ResourceSpec),
+		HealthCheck:  copyHealthCheck(revisionAttributes.HealthCheck),
+		Routing:      copyRouting(revisionAttributes.Routing),
+		Middlewares:  copyMiddlewares(revisionAttributes.Middlewares),
+
+		RolledBackFrom: copyPtr(revisionAttributes.RolledBackFrom),
+		CreatedBy:      copyPtr(revisionAttributes.CreatedBy),
+		CreatedAt:      now(),
+	}
+
+	m.revisions = append(m.revisions, revision)
+
+	return copyRevision(revision), nil
+}
+
+// removeRevisionsOf mirrors the ON DELETE CASCADE of deployment_revisions. Must be called with mu held.
+func (m *MemoryDatabase) removeRevisionsOf(deploymentId int) {
+	revisions := m.revisions[:0]
+	for _, revision := range m.revisions {
+		if *revision.DeploymentId != deploymentId {
+			revisions = append(revisions, revision)
+		}
+	}
+	m.revisions = revisions
+}
+
+func copyRevision(revision types.DeploymentRevision) types.DeploymentRevision {
+	copied := revision
+	copied.EnvConfig = copyEnvConfig(revision.EnvConfig)
+	copied.ResourceSpec = copyResourceSpec(revision.ResourceSpec)
+	copied.HealthCheck = copyHealthCheck(revision.HealthCheck)
+	copied.Routing = copyRouting(revision.Routing)
+	copied.Middlewares = copyMiddlewares(revision.Middlewares)
+
+	return copied
+}
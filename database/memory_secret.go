@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func (m *MemoryDatabase) GetSecretsForUser(ctx context.Context, userUUID string) ([]types.Secret, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, err := m.findUser(func(user types.User) bool { return *user.UUID == userUUID })
+	if err != nil {
+		return []types.Secret{}, nil
+	}
+
+	return m.secretsOf(*user.ID), nil
+}
+
+func (m *MemoryDatabase) GetSecretsForDeployment(ctx context.Context, deploymentUUID string) ([]types.Secret, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.findDeployment(deploymentUUID)
+	if i == -1 {
+		return []types.Secret{}, nil
+	}
+
+	return m.secretsOf(*m.deployments[i].UserId), nil
+}
+
+func (m *MemoryDatabase) GetSecret(ctx context.Context, userUUID string, name string) (types.Secret, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, err := m.findSecret(userUUID, name)
+	if err != nil {
+		return types.Secret{}, err
+	}
+
+	return copySecret(m.secrets[i]), nil
+}
+
+func (m *MemoryDatabase) CreateSecret(ctx context.Context, userUUID string, name string, ciphertext []byte, encryptedKey []byte) (types.Secret, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, err := m.findUser(func(user types.User) bool { return *user.UUID == userUUID })
+	if err != nil {
+		return types.Secret{}, err
+	}
+
+	if _, err := m.findSecret(userUUID, name); err == nil {
+		return types.Secret{}, uniqueViolation("secrets_user_id_name_key")
+	}
+
+	secret := types.Secret{
+		ID:           m.newID(),
+		UserId:       user.ID,
+		Name:         ptr(name),
+		Ciphertext:   append([]byte(nil), ciphertext...),
+		EncryptedKey: append([]byte(nil), encryptedKey...),
+		CreatedAt:    now(),
+		UpdatedAt:    now(),
+	}
+
+	m.secrets = append(m.secrets, secret)
+
+	return copySecret(secret), nil
+}
+
+func (m *MemoryDatabase) UpdateSecret(ctx context.Context, userUUID string, name string, ciphertext []byte, encryptedKey []byte) (types.Secret, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, err := m.findSecret(userUUID, name)
+	if err != nil {
+		return types.Secret{}, err
+	}
+
+	m.secrets[i].Ciphertext = append([]byte(nil), ciphertext...)
+	m.secrets[i].EncryptedKey = append([]byte(nil), encryptedKey...)
+	m.secrets[i].UpdatedAt = now()
+
+	return copySecret(m.secrets[i]), nil
+}
+
+func (m *MemoryDatabase) DeleteSecret(ctx context.Context, userUUID string, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i, err := m.findSecret(userUUID, name)
+	if err != nil {
+		return err
+	}
+
+	m.secrets = append(m.secrets[:i], m.secrets[i+1:]...)
+
+	return nil
+}
+
+// findSecret returns the index of the user's secret. Must be called with mu held.
+func (m *MemoryDatabase) findSecret(userUUID string, name string) (int, error) {
+	user, err := m.findUser(func(user types.User) bool { return *user.UUID == userUUID })
+	if err != nil {
+		return -1, err
+	}
+
+	for i, secret := range m.secrets {
+		if *secret.UserId == *user.ID && *secret.Name == name {
+			return i, nil
+		}
+	}
+
+	return -1, sql.ErrNoRows
+}
+
+// secretsOf returns the secrets of a user sorted by name. Must be called with mu held.
+func (m *MemoryDatabase) secretsOf(userId int) []types.Secret {
+	secrets := []types.Secret{}
+	for _, secret := range m.secrets {
+		if *secret.UserId == userId {
+			secrets = append(secrets, copySecret(secret))
+		}
+	}
+
+	sort.Slice(secrets, func(i, j int) bool { return *secrets[i].Name < *secrets[j].Name })
+
+	return secrets
+}
+
+func copySecret(secret types.Secret) types.Secret {
+	copied := secret
+	copied.Ciphertext = append([]byte(nil), secret.Ciphertext...)
+	copied.EncryptedKey = append([]byte(nil), secret.EncryptedKey...)
+	return copied
+}
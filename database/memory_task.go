@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+)
+
+func (m *MemoryDatabase) GetTask(ctx context.Context, uuid string) (types.TaskRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.findTask(uuid)
+	if index == -1 {
+		return types.TaskRecord{}, sql.ErrNoRows
+	}
+
+	return m.tasks[index], nil
+}
+
+func (m *MemoryDatabase) GetTasksForDeployment(ctx context.Context, deploymentUUID string) ([]types.TaskRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tasks := []types.TaskRecord{}
+	for i := len(m.tasks) - 1; i >= 0; i-- {
+		if m.tasks[i].DeploymentUUID != nil && *m.tasks[i].DeploymentUUID == deploymentUUID {
+			tasks = append(tasks, m.tasks[i])
+		}
+	}
+
+	return tasks, nil
+}
+
+func (m *MemoryDatabase) GetDeadLetterTasks(ctx context.Context) ([]types.TaskRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tasks := []types.TaskRecord{}
+	for i := len(m.tasks) - 1; i >= 0; i-- {
+		if *m.tasks[i].Status == "FAILED" {
+			tasks = append(tasks, m.tasks[i])
+		}
+	}
+
+	return tasks, nil
+}
+
+func (m *MemoryDatabase) HasPendingTasks(ctx context.Context, deploymentUUID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, task := range m.tasks {
+		if task.DeploymentUUID != nil && *task.DeploymentUUID == deploymentUUID && isPendingTask(task) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (m *MemoryDatabase) CreateTask(ctx context.Context, taskType string, payload []byte, maxAttempts int, deploymentUUID *string) (types.TaskRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	uuid, err := utils.GenerateUUID()
+	if err != nil {
+		return types.TaskRecord{}, err
+	}
+
+	task := types.TaskRecord{
+		ID:             m.newID(),
+		UUID:           &uuid,
+		DeploymentUUID: copyPtr(deploymentUUID),
+		Type:           ptr(taskType),
+		Payload:        append([]byte{}, payload...),
+		Status:         ptr("QUEUED"),
+		Attempts:       ptr(0),
+		MaxAttempts:    ptr(maxAttempts),
+		RunAt:          now(),
+		CreatedAt:      now(),
+		UpdatedAt:      now(),
+	}
+
+	if deploymentUUID != nil {
+		if index := m.findDeployment(*deploymentUUID); index != -1 {
+			task.UserId = m.deployments[index].UserId
+		}
+	}
+
+	m.tasks = append(m.tasks, task)
+
+	return task, nil
+}
+
+func (m *MemoryDatabase) CoalesceTask(ctx context.Context, taskType string, payload []byte, deploymentUUID string) (types.TaskRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.tasks) - 1; i >= 0; i-- {
+		task := m.tasks[i]
+		if task.DeploymentUUID == nil || *task.DeploymentUUID != deploymentUUID || !isPendingTask(task) {
+			continue
+		}
+
+		if *task.Type != taskType || *task.Status != "QUEUED" || *task.Attempts != 0 {
+			break
+		}
+
+		task.Payload = append([]byte{}, payload...)
+		task.UpdatedAt = now()
+		m.tasks[i] = task
+
+		return task, nil
+	}
+
+	return types.TaskRecord{}, sql.ErrNoRows
+}
+
+// ClaimNextTask follows the same ordering rules as Database.ClaimNextTask.
+func (m *MemoryDatabase) ClaimNextTask(ctx context.Context, lease time.Duration) (types.TaskRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	blocked := make(map[string]bool)
+
+	for i, task := range m.tasks {
+		if !isPendingTask(task) {
+			continue
+		}
+
+		if task.DeploymentUUID != nil {
+			if blocked[*task.DeploymentUUID] {
+				continue
+			}
+			blocked[*task.DeploymentUUID] = true
+		}
+
+		due := *task.Status == "QUEUED" && !task.RunAt.After(time.Now())
+		lapsed := *task.Status == "RUNNING" && task.LeaseExpiresAt != nil && task.LeaseExpiresAt.Before(time.Now())
+		if !due && !lapsed {
+			continue
+		}
+
+		task.Status = ptr("RUNNING")
+		task.Attempts = ptr(*task.Attempts + 1)
+		task.LeaseExpiresAt = ptr(time.Now().Add(lease))
+		if task.StartedAt == nil {
+			task.StartedAt = now()
+		}
+		task.UpdatedAt = now()
+		m.tasks[i] = task
+
+		return task, nil
+	}
+
+	return types.TaskRecord{}, sql.ErrNoRows
+}
+
+func (m *MemoryDatabase) RenewTaskLease(ctx context.Context, uuid string, lease time.Duration) error {
+	m.updateTask(uuid, func(task *types.TaskRecord) {
+		if *task.Status == "RUNNING" {
+			task.LeaseExpiresAt = ptr(time.Now().Add(lease))
+		}
+	})
+
+	return nil
+}
+
+func (m *MemoryDatabase) CompleteTask(ctx context.Context, uuid string) error {
+	m.updateTask(uuid, func(task *types.TaskRecord) {
+		task.Status = ptr("SUCCEEDED")
+		task.FinishedAt = now()
+		task.Payload = withoutEnv(task.Payload)
+	})
+
+	return nil
+}
+
+func (m *MemoryDatabase) RetryTask(ctx context.Context, uuid string, delay time.Duration, lastError string) error {
+	m.updateTask(uuid, func(task *types.TaskRecord) {
+		task.Status = ptr("QUEUED")
+		task.RunAt = ptr(time.Now().Add(delay))
+		task.LastError = ptr(lastError)
+	})
+
+	return nil
+}
+
+func (m *MemoryDatabase) DeadLetterTask(ctx context.Context, uuid string, lastError string) error {
+	m.updateTask(uuid, func(task *types.TaskRecord) {
+		task.Status = ptr("FAILED")
+		task.LastError = ptr(lastError)
+		task.FinishedAt = now()
+	})
+
+	return nil
+}
+
+func (m *MemoryDatabase) RedriveTask(ctx context.Context, uuid string) (types.TaskRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.findTask(uuid)
+	if index == -1 || *m.tasks[index].Status != "FAILED" {
+		return types.TaskRecord{}, sql.ErrNoRows
+	}
+
+	task := m.tasks[index]
+	task.Status = ptr("QUEUED")
+	task.Attempts = ptr(0)
+	task.RunAt = now()
+	task.FinishedAt = nil
+	task.UpdatedAt = now()
+	m.tasks[index] = task
+
+	return task, nil
+}
+
+// findTask returns the index of the task or -1. Must be called with mu held.
+func (m *MemoryDatabase) findTask(uuid string) int {
+	for i, task := range m.tasks {
+		if *task.UUID == uuid {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (m *MemoryDatabase) updateTask(uuid string, update func(task *types.TaskRecord)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.findTask(uuid)
+	if index == -1 {
+		return
+	}
+
+	task := m.tasks[index]
+	update(&task)
+	task.UpdatedAt = now()
+	m.tasks[index] = task
+}
+
+func isPendingTask(task types.TaskRecord) bool {
+	return *task.Status == "QUEUED" || *task.Status == "RUNNING"
+}
+
+// withoutEnv drops the env vars from a task payload like the jsonb - operator of Database.CompleteTask.
+func withoutEnv(payload []byte) []byte {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return payload
+	}
+	delete(fields, "envArray")
+
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return payload
+	}
+	return stripped
+}
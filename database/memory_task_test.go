@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClaimNextTaskLeases(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryDatabase()
+
+	if _, err := db.CreateTask(ctx, "TEST", []byte(`{}`), 3, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err := db.ClaimNextTask(ctx, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.ClaimNextTask(ctx, time.Hour); err != sql.ErrNoRows {
+		t.Fatalf("expected a leased task not to be claimed again, got %v", err)
+	}
+
+	// The worker stops renewing the lease, e.g. because its process died
+	if err := db.RenewTaskLease(ctx, *claimed.UUID, -time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	reclaimed, err := db.ClaimNextTask(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("expected the task to be claimed once its lease lapsed, got %s", err)
+	}
+	if *reclaimed.UUID != *claimed.UUID || *reclaimed.Attempts != 2 {
+		t.Errorf("expected task %s to be claimed for a second attempt, got %s attempt %d", *claimed.UUID, *reclaimed.UUID, *reclaimed.Attempts)
+	}
+}
+
+func TestCompleteTaskDropsEnv(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryDatabase()
+
+	task, err := db.CreateTask(ctx, "TEST", []byte(`{"deploymentUUID":"d","envArray":["TOKEN=hunter2"]}`), 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CompleteTask(ctx, *task.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	task, err = db.GetTask(ctx, *task.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(task.Payload), "hunter2") || !strings.Contains(string(task.Payload), `"deploymentUUID":"d"`) {
+		t.Errorf("expected only the env to be dropped from the payload, got %s", task.Payload)
+	}
+}
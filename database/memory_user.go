@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+)
+
+func (m *MemoryDatabase) GetUserByUUID(ctx context.Context, uuid string) (types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.findUser(func(user types.User) bool { return *user.UUID == uuid })
+}
+
+func (m *MemoryDatabase) GetUserByUsername(ctx context.Context, username string) (types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.findUser(func(user types.User) bool { return *user.Username == username })
+}
+
+func (m *MemoryDatabase) GetUserByAPIKeyHash(ctx context.Context, apiKeyHash string) (types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.findUser(func(user types.User) bool { return *user.ApiKey == apiKeyHash })
+}
+
+func (m *MemoryDatabase) CreateUser(ctx context.Context, userAttributes types.CreateUserRequest) (types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	uuid, err := utils.GenerateUUID()
+	if err != nil {
+		return types.User{}, err
+	}
+
+	user := types.User{
+		ID:        m.newID(),
+		UUID:      &uuid,
+		Username:  ptr(userAttributes.Username),
+		ApiKey:    ptr(userAttributes.ApiKey),
+		CreatedAt: now(),
+		UpdatedAt: now(),
+	}
+
+	m.users = append(m.users, user)
+
+	return user, nil
+}
+
+func (m *MemoryDatabase) UpdateUserQuota(ctx context.Context, uuid string, quota types.Quota) (types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, user := range m.users {
+		if *user.UUID == uuid {
+			user.MaxDeployments = ptr(quota.MaxDeployments)
+			user.MaxMemory = ptr(quota.MaxMemory)
+			user.MaxCPUs = ptr(quota.MaxCPUs)
+			user.MaxPublicPorts = ptr(quota.MaxPublicPorts)
+			user.UpdatedAt = now()
+			m.users[i] = user
+
+			return user, nil
+		}
+	}
+
+	return types.User{}, sql.ErrNoRows
+}
+
+// findUser must be called with mu held.
+func (m *MemoryDatabase) findUser(match func(user types.User) bool) (types.User, error) {
+	for _, user := range m.users {
+		if match(user) {
+			return user, nil
+		}
+	}
+
+	return types.User{}, sql.ErrNoRows
+}
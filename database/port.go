@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func (d *Database) GetDeploymentPorts(ctx context.Context, deploymentUUID string) ([]types.DeploymentPort, error) {
+	ports := []types.DeploymentPort{}
+	query := `SELECT * FROM deployment_ports WHERE deployment_id = (SELECT id FROM deployments WHERE uuid = $1) ORDER BY name`
+
+	if err := d.Client.SelectContext(ctx, &ports, query, deploymentUUID); err != nil {
+		return []types.DeploymentPort{}, err
+	}
+
+	return ports, nil
+}
+
+const countPublicPortsQuery = `SELECT count(*) FROM deployment_ports WHERE public_port IS NOT NULL AND deployment_id IN (SELECT id FROM deployments WHERE user_id = (SELECT id FROM users WHERE uuid = $1))`
+
+func (d *Database) CountPublicPortsForUser(ctx context.Context, userUUID string) (int, error) {
+	var count int
+
+	if err := d.Client.GetContext(ctx, &count, countPublicPortsQuery, userUUID); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (d *Database) GetDeploymentPort(ctx context.Context, deploymentUUID string, uuid string) (types.DeploymentPort, error) {
+	var port types.DeploymentPort
+	query := `SELECT * FROM deployment_ports WHERE deployment_id = (SELECT id FROM deployments WHERE uuid = $1) AND uuid = $2`
+
+	if err := d.Client.GetContext(ctx, &port, query, deploymentUUID, uuid); err != nil {
+		return types.DeploymentPort{}, err
+	}
+
+	return port, nil
+}
+
+// CreateDeploymentPort adds a port to the deployment, allocating the lowest free public port of the pool
+// unless it's a TLS port. types.ErrPortPoolExhausted is returned when none is free, and a types.QuotaExceededError
+// when the user has no public port left in their quota.
+func (d *Database) CreateDeploymentPort(ctx context.Context, deploymentUUID string, name string, protocol string, containerPort int, tls *string) (types.DeploymentPort, error) {
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return types.DeploymentPort{}, err
+	}
+
+	defer tx.Rollback()
+
+	// Concurrent allocations would otherwise pick the same free port, or both take the last port of the user's quota
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE deployment_ports IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return types.DeploymentPort{}, err
+	}
+
+	var publicPort *int
+	if tls == nil {
+		var user types.User
+		if err := tx.GetContext(ctx, &user, `SELECT users.* FROM users JOIN deployments ON deployments.user_id = users.id WHERE deployments.uuid = $1`, deploymentUUID); err != nil {
+			return types.DeploymentPort{}, err
+		}
+
+		var publicPorts int
+		if err := tx.GetContext(ctx, &publicPorts, countPublicPortsQuery, *user.UUID); err != nil {
+			return types.DeploymentPort{}, err
+		}
+
+		if err := user.Quota().Check(types.Usage{PublicPorts: publicPorts + 1}); err != nil {
+			return types.DeploymentPort{}, err
+		}
+
+		var free int
+		query := `SELECT port FROM generate_series($2::integer, $3::integer) AS port
+			WHERE port NOT IN (SELECT public_port FROM deployment_ports WHERE protocol = $1 AND public_port IS NOT NULL)
+			ORDER BY port LIMIT 1`
+		if err := tx.GetContext(ctx, &free, query, protocol, config.PORT_POOL_START, config.PORT_POOL_END); err != nil {
+			if err == sql.ErrNoRows {
+				return types.DeploymentPort{}, types.ErrPortPoolExhausted
+			}
+			return types.DeploymentPort{}, err
+		}
+		publicPort = &free
+	}
+
+	var port types.DeploymentPort
+	query := `INSERT INTO deployment_ports (deployment_id, name, protocol, container_port, tls, public_port) VALUES ((SELECT id FROM deployments WHERE uuid = $1), $2, $3, $4, $5, $6) RETURNING *`
+
+	if err := tx.GetContext(ctx, &port, query, deploymentUUID, name, protocol, containerPort, tls, publicPort); err != nil {
+		return types.DeploymentPort{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return types.DeploymentPort{}, err
+	}
+
+	return port, nil
+}
+
+func (d *Database) DeleteDeploymentPort(ctx context.Context, deploymentUUID string, uuid string) error {
+	result, err := d.Client.ExecContext(ctx, `DELETE FROM deployment_ports WHERE deployment_id = (SELECT id FROM deployments WHERE uuid = $1) AND uuid = $2`, deploymentUUID, uuid)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
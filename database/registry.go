@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func (d *Database) GetRegistryCredentialsForUser(ctx context.Context, userUUID string) ([]types.RegistryCredential, error) {
+	credentials := []types.RegistryCredential{}
+	query := `SELECT * FROM registry_credentials WHERE user_id = (SELECT id FROM users WHERE uuid = $1) ORDER BY host`
+
+	if err := d.Client.SelectContext(ctx, &credentials, query, userUUID); err != nil {
+		return []types.RegistryCredential{}, err
+	}
+
+	return credentials, nil
+}
+
+func (d *Database) GetRegistryCredential(ctx context.Context, userUUID string, uuid string) (types.RegistryCredential, error) {
+	var credential types.RegistryCredential
+	query := `SELECT * FROM registry_credentials WHERE user_id = (SELECT id FROM users WHERE uuid = $1) AND uuid = $2`
+
+	if err := d.Client.GetContext(ctx, &credential, query, userUUID, uuid); err != nil {
+		return types.RegistryCredential{}, err
+	}
+
+	return credential, nil
+}
+
+// GetRegistryCredentialForDeployment returns the credential the user owning the deployment has for host.
+func (d *Database) GetRegistryCredentialForDeployment(ctx context.Context, deploymentUUID string, host string) (types.RegistryCredential, error) {
+	var credential types.RegistryCredential
+	query := `SELECT * FROM registry_credentials WHERE user_id = (SELECT user_id FROM deployments WHERE uuid = $1) AND host = $2`
+
+	if err := d.Client.GetContext(ctx, &credential, query, deploymentUUID, host); err != nil {
+		return types.RegistryCredential{}, err
+	}
+
+	return credential, nil
+}
+
+func (d *Database) CreateRegistryCredential(ctx context.Context, userUUID string, host string, username string, ciphertext []byte, encryptedKey []byte) (types.RegistryCredential, error) {
+	var credential types.RegistryCredential
+	query := `INSERT INTO registry_credentials (user_id, host, username, ciphertext, encrypted_key) VALUES ((SELECT id FROM users WHERE uuid = $1), $2, $3, $4, $5) RETURNING *`
+
+	if err := d.Client.GetContext(ctx, &credential, query, userUUID, host, username, ciphertext, encryptedKey); err != nil {
+		return types.RegistryCredential{}, err
+	}
+
+	return credential, nil
+}
+
+func (d *Database) UpdateRegistryCredential(ctx context.Context, userUUID string, uuid string, username string, ciphertext []byte, encryptedKey []byte) (types.RegistryCredential, error) {
+	var credential types.RegistryCredential
+	query := `UPDATE registry_credentials SET username = $3, ciphertext = $4, encrypted_key = $5 WHERE user_id = (SELECT id FROM users WHERE uuid = $1) AND uuid = $2 RETURNING *`
+
+	if err := d.Client.GetContext(ctx, &credential, query, userUUID, uuid, username, ciphertext, encryptedKey); err != nil {
+		return types.RegistryCredential{}, err
+	}
+
+	return credential, nil
+}
+
+func (d *Database) DeleteRegistryCredential(ctx context.Context, userUUID string, uuid string) error {
+	result, err := d.Client.ExecContext(ctx, `DELETE FROM registry_credentials WHERE user_id = (SELECT id FROM users WHERE uuid = $1) AND uuid = $2`, userUUID, uuid)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// Repository is the storage used by the API, the task dispatcher and the background jobs.
+// Database is the Postgres implementation, MemoryDatabase keeps everything in memory.
+// Lookups that find nothing return sql.ErrNoRows in both implementations.
+type Repository interface {
+	Ping(ctx context.Context) error
+
+	UserRepository
+	DeploymentRepository
+	TaskRepository
+	EventRepository
+	SecretRepository
+	RegistryRepository
+}
+
+type UserRepository interface {
+	GetUserByUUID(ctx context.Context, uuid string) (types.User, error)
+	GetUserByUsername(ctx context.Context, username string) (types.User, error)
+	GetUserByAPIKeyHash(ctx context.Context, apiKeyHash string) (types.User, error)
+	CreateUser(ctx context.Context, userAttributes types.CreateUserRequest) (types.User, error)
+	UpdateUserQuota(ctx context.Context, uuid string, quota types.Quota) (types.User, error)
+}
+
+type DeploymentRepository interface {
+	GetDeployment(ctx context.Context, uuidOrSubdomain string) (types.Deployment, error)
+	GetAllDeploymentsForUser(ctx context.Context, userUUID string) ([]types.Deployment, error)
+	GetAllDeployments(ctx context.Context) ([]types.Deployment, error)
+	GetDeploymentsByStatus(ctx context.Context, status string) ([]types.Deployment, error)
+	// CreateDeployment returns a types.QuotaExceededError when the deployment doesn't fit in its user's quota
+	CreateDeployment(ctx context.Context, deploymentAttributes types.DeploymentAttributes) (types.Deployment, error)
+	// UpdateDeployment and UpdateDeploymentStatus return a types.InvalidTransitionError when the status change isn't allowed
+	UpdateDeployment(ctx context.Context, deploymentAttributes types.DeploymentAttributes) (types.Deployment, error)
+	UpdateDeploymentStatus(ctx context.Context, uuid string, status string, statusReason *string) (types.Deployment, error)
+	// ReserveDeploymentUpdate moves the deployment to UPDATING with update as its pending update, reserving usage, when
+	// set, against its user's quota until it leaves UPDATING. It returns a types.QuotaExceededError when the usage
+	// doesn't fit in the quota.
+	ReserveDeploymentUpdate(ctx context.Context, uuid string, update types.DeploymentConfig, usage *types.Usage) (types.Deployment, error)
+	// SettleDeploymentUpdate moves the deployment to status once the update queued with token is done, unless a newer
+	// update is pending. It returns a types.InvalidTransitionError when the status change isn't allowed.
+	SettleDeploymentUpdate(ctx context.Context, uuid string, token string, status string, statusReason *string) (types.Deployment, error)
+	DeleteDeployment(ctx context.Context, uuid string) error
+
+	GetDeploymentInstances(ctx context.Context, deploymentUUID string) ([]types.DeploymentInstance, error)
+	GetAllDeploymentInstances(ctx context.Context) ([]types.DeploymentInstance, error)
+	SetDeploymentInstances(ctx context.Context, deploymentUUID string, containerIds map[int]string) ([]types.DeploymentInstance, error)
+
+	GetDeploymentRevisions(ctx context.Context, deploymentUUID string) ([]types.DeploymentRevision, error)
+	GetDeploymentRevision(ctx context.Context, deploymentUUID string, revision int) (types.DeploymentRevision, error)
+	CreateDeploymentRevision(ctx context.Context, revisionAttributes types.DeploymentRevisionAttributes) (types.DeploymentRevision, error)
+
+	GetDeploymentDomains(ctx context.Context, deploymentUUID string) ([]types.Domain, error)
+	GetDeploymentDomain(ctx context.Context, deploymentUUID string, uuid string) (types.Domain, error)
+	GetVerifiedDomain(ctx context.Context, hostname string) (types.Domain, error)
+	CreateDeploymentDomain(ctx context.Context, deploymentUUID string, hostname string, method string, token string) (types.Domain, error)
+	// SetDomainVerified fails with a unique violation when another deployment already has the hostname verified
+	SetDomainVerified(ctx context.Context, uuid string) (types.Domain, error)
+	DeleteDeploymentDomain(ctx context.Context, deploymentUUID string, uuid string) error
+
+	GetDeploymentPorts(ctx context.Context, deploymentUUID string) ([]types.DeploymentPort, error)
+	GetDeploymentPort(ctx context.Context, deploymentUUID string, uuid string) (types.DeploymentPort, error)
+	// CreateDeploymentPort allocates a public port from the pool for ports without tls, failing with
+	// types.ErrPortPoolExhausted when none is left and with a types.QuotaExceededError when the user's quota has none left
+	CreateDeploymentPort(ctx context.Context, deploymentUUID string, name string, protocol string, containerPort int, tls *string) (types.DeploymentPort, error)
+	DeleteDeploymentPort(ctx context.Context, deploymentUUID string, uuid string) error
+	// CountPublicPortsForUser counts the ports of the user's deployments that have a public port from the pool
+	CountPublicPortsForUser(ctx context.Context, userUUID string) (int, error)
+}
+
+type TaskRepository interface {
+	GetTask(ctx context.Context, uuid string) (types.TaskRecord, error)
+	GetTasksForDeployment(ctx context.Context, deploymentUUID string) ([]types.TaskRecord, error)
+	GetDeadLetterTasks(ctx context.Context) ([]types.TaskRecord, error)
+	HasPendingTasks(ctx context.Context, deploymentUUID string) (bool, error)
+	CreateTask(ctx context.Context, taskType string, payload []byte, maxAttempts int, deploymentUUID *string) (types.TaskRecord, error)
+	CoalesceTask(ctx context.Context, taskType string, payload []byte, deploymentUUID string) (types.TaskRecord, error)
+	ClaimNextTask(ctx context.Context, lease time.Duration) (types.TaskRecord, error)
+	RenewTaskLease(ctx context.Context, uuid string, lease time.Duration) error
+	CompleteTask(ctx context.Context, uuid string) error
+	RetryTask(ctx context.Context, uuid string, delay time.Duration, lastError string) error
+	DeadLetterTask(ctx context.Context, uuid string, lastError string) error
+	RedriveTask(ctx context.Context, uuid string) (types.TaskRecord, error)
+}
+
+type EventRepository interface {
+	GetDeploymentEvents(ctx context.Context, deploymentUUID string) ([]types.DeploymentEvent, error)
+	CreateDeploymentEvent(ctx context.Context, deploymentUUID string, eventType string, message string) error
+}
+
+type SecretRepository interface {
+	GetSecretsForUser(ctx context.Context, userUUID string) ([]types.Secret, error)
+	GetSecretsForDeployment(ctx context.Context, deploymentUUID string) ([]types.Secret, error)
+	GetSecret(ctx context.Context, userUUID string, name string) (types.Secret, error)
+	CreateSecret(ctx context.Context, userUUID string, name string, ciphertext []byte, encryptedKey []byte) (types.Secret, error)
+	// UpdateSecret and DeleteSecret return sql.ErrNoRows when the user has no secret with that name
+	UpdateSecret(ctx context.Context, userUUID string, name string, ciphertext []byte, encryptedKey []byte) (types.Secret, error)
+	DeleteSecret(ctx context.Context, userUUID string, name string) error
+}
+
+type RegistryRepository interface {
+	GetRegistryCredentialsForUser(ctx context.Context, userUUID string) ([]types.RegistryCredential, error)
+	GetRegistryCredential(ctx context.Context, userUUID string, uuid string) (types.RegistryCredential, error)
+	GetRegistryCredentialForDeployment(ctx context.Context, deploymentUUID string, host string) (types.RegistryCredential, error)
+	CreateRegistryCredential(ctx context.Context, userUUID string, host string, username string, ciphertext []byte, encryptedKey []byte) (types.RegistryCredential, error)
+	// UpdateRegistryCredential and DeleteRegistryCredential return sql.ErrNoRows when the user has no credential with that uuid
+	UpdateRegistryCredential(ctx context.Context, userUUID string, uuid string, username string, ciphertext []byte, encryptedKey []byte) (types.RegistryCredential, error)
+	DeleteRegistryCredential(ctx context.Context, userUUID string, uuid string) error
+}
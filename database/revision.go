@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// @TODO: Paginate this
+func (d *Database) GetDeploymentRevisions(ctx context.Context, deploymentUUID string) ([]types.DeploymentRevision, error) {
+	revisions := []types.DeploymentRevision{}
+	query := `SELECT * FROM deployment_revisions WHERE deployment_id = (SELECT id FROM deployments WHERE uuid = $1) ORDER BY revision DESC`
+
+	if err := d.Client.SelectContext(ctx, &revisions, query, deploymentUUID); err != nil {
+		return []types.DeploymentRevision{}, err
+	}
+
+	return revisions, nil
+}
+
+func (d *Database) GetDeploymentRevision(ctx context.Context, deploymentUUID string, revision int) (types.DeploymentRevision, error) {
+	var deploymentRevision types.DeploymentRevision
+	query := `SELECT * FROM deployment_revisions WHERE deployment_id = (SELECT id FROM deployments WHERE uuid = $1) AND revision = $2`
+
+	if err := d.Client.GetContext(ctx, &deploymentRevision, query, deploymentUUID, revision); err != nil {
+		return types.DeploymentRevision{}, err
+	}
+
+	return deploymentRevision, nil
+}
+
+// CreateDeploymentRevision records a configuration under the next revision number of the deployment.
+func (d *Database) CreateDeploymentRevision(ctx context.Context, revisionAttributes types.DeploymentRevisionAttributes) (types.DeploymentRevision, error) {
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return types.DeploymentRevision{}, err
+	}
+
+	defer tx.Rollback()
+
+	// Locking the deployment row serializes revision numbers
+	var deploymentId int
+	if err := tx.GetContext(ctx, &deploymentId, `SELECT id FROM deployments WHERE uuid = $1 FOR UPDATE`, revisionAttributes.DeploymentUUID); err != nil {
+		return types.DeploymentRevision{}, err
+	}
+
+	envConfig := revisionAttributes.EnvConfig
+	if envConfig == nil {
+		envConfig = types.EnvConfig{}
+	}
+
+	var revision types.DeploymentRevision
+	query := `INSERT INTO deployment_revisions (deployment_id, revision, image_tag, sub_domain, port, env_config, cpu_shares, cpu_quota, cpu_period, memory_limit, memory_reservation, pids_limit, ulimits, health_check, routing, middlewares, rolled_back_from, created_by)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17 FROM deployment_revisions WHERE deployment_id = $1
+		RETURNING *`
+
+	resources := revisionAttributes.ResourceSpec
+	if err := tx.GetContext(ctx, &revision, query, deploymentId, revisionAttributes.ImageTag, revisionAttributes.Subdomain, revisionAttributes.Port, envConfig, resources.CPUShares, resources.CPUQuota, resources.CPUPeriod, resources.MemoryLimit, resources.MemoryReservation, resources.PidsLimit, resources.Ulimits, revisionAttributes.HealthCheck, revisionAttributes.Routing, revisionAttributes.Middlewares, revisionAttributes.RolledBackFrom, revisionAttributes.CreatedBy); err != nil {
+		return types.DeploymentRevision{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return types.DeploymentRevision{}, err
+	}
+
+	return revision, nil
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func (d *Database) GetSecretsForUser(ctx context.Context, userUUID string) ([]types.Secret, error) {
+	secrets := []types.Secret{}
+	query := `SELECT * FROM secrets WHERE user_id = (SELECT id FROM users WHERE uuid = $1) ORDER BY name`
+
+	if err := d.Client.SelectContext(ctx, &secrets, query, userUUID); err != nil {
+		return []types.Secret{}, err
+	}
+
+	return secrets, nil
+}
+
+// GetSecretsForDeployment returns the secrets of the user owning the deployment.
+func (d *Database) GetSecretsForDeployment(ctx context.Context, deploymentUUID string) ([]types.Secret, error) {
+	secrets := []types.Secret{}
+	query := `SELECT * FROM secrets WHERE user_id = (SELECT user_id FROM deployments WHERE uuid = $1) ORDER BY name`
+
+	if err := d.Client.SelectContext(ctx, &secrets, query, deploymentUUID); err != nil {
+		return []types.Secret{}, err
+	}
+
+	return secrets, nil
+}
+
+func (d *Database) GetSecret(ctx context.Context, userUUID string, name string) (types.Secret, error) {
+	var secret types.Secret
+	query := `SELECT * FROM secrets WHERE user_id = (SELECT id FROM users WHERE uuid = $1) AND name = $2`
+
+	if err := d.Client.GetContext(ctx, &secret, query, userUUID, name); err != nil {
+		return types.Secret{}, err
+	}
+
+	return secret, nil
+}
+
+func (d *Database) CreateSecret(ctx context.Context, userUUID string, name string, ciphertext []byte, encryptedKey []byte) (types.Secret, error) {
+	var secret types.Secret
+	query := `INSERT INTO secrets (user_id, name, ciphertext, encrypted_key) VALUES ((SELECT id FROM users WHERE uuid = $1), $2, $3, $4) RETURNING *`
+
+	if err := d.Client.GetContext(ctx, &secret, query, userUUID, name, ciphertext, encryptedKey); err != nil {
+		return types.Secret{}, err
+	}
+
+	return secret, nil
+}
+
+func (d *Database) UpdateSecret(ctx context.Context, userUUID string, name string, ciphertext []byte, encryptedKey []byte) (types.Secret, error) {
+	var secret types.Secret
+	query := `UPDATE secrets SET ciphertext = $3, encrypted_key = $4 WHERE user_id = (SELECT id FROM users WHERE uuid = $1) AND name = $2 RETURNING *`
+
+	if err := d.Client.GetContext(ctx, &secret, query, userUUID, name, ciphertext, encryptedKey); err != nil {
+		return types.Secret{}, err
+	}
+
+	return secret, nil
+}
+
+func (d *Database) DeleteSecret(ctx context.Context, userUUID string, name string) error {
+	result, err := d.Client.ExecContext(ctx, `DELETE FROM secrets WHERE user_id = (SELECT id FROM users WHERE uuid = $1) AND name = $2`, userUUID, name)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
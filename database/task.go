@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func (d *Database) GetTask(ctx context.Context, uuid string) (types.TaskRecord, error) {
+	var task types.TaskRecord
+	query := `SELECT * FROM tasks WHERE uuid = $1`
+
+	if err := d.Client.GetContext(ctx, &task, query, uuid); err != nil {
+		return types.TaskRecord{}, err
+	}
+
+	return task, nil
+}
+
+// @TODO: Paginate this
+func (d *Database) GetTasksForDeployment(ctx context.Context, deploymentUUID string) ([]types.TaskRecord, error) {
+	tasks := []types.TaskRecord{}
+	query := `SELECT * FROM tasks WHERE deployment_uuid = $1 ORDER BY id DESC`
+
+	if err := d.Client.SelectContext(ctx, &tasks, query, deploymentUUID); err != nil {
+		return []types.TaskRecord{}, err
+	}
+
+	return tasks, nil
+}
+
+// HasPendingTasks reports whether a deployment has tasks that are queued or running.
+func (d *Database) HasPendingTasks(ctx context.Context, deploymentUUID string) (bool, error) {
+	var pending bool
+	query := `SELECT EXISTS (SELECT 1 FROM tasks WHERE deployment_uuid = $1 AND status IN ('QUEUED', 'RUNNING'))`
+
+	if err := d.Client.GetContext(ctx, &pending, query, deploymentUUID); err != nil {
+		return false, err
+	}
+
+	return pending, nil
+}
+
+// CreateTask queues a task. When deploymentUUID is set the task is attributed to the deployment's owner,
+// so its history stays visible to them after the deployment row is gone.
+func (d *Database) CreateTask(ctx context.Context, taskType string, payload []byte, maxAttempts int, deploymentUUID *string) (types.TaskRecord, error) {
+	var task types.TaskRecord
+	query := `INSERT INTO tasks (type, payload, max_attempts, deployment_uuid, user_id)
+		VALUES ($1, $2, $3, $4, (SELECT user_id FROM deployments WHERE uuid = $4)) RETURNING *`
+
+	if err := d.Client.GetContext(ctx, &task, query, taskType, payload, maxAttempts, deploymentUUID); err != nil {
+		return types.TaskRecord{}, err
+	}
+
+	return task, nil
+}
+
+// @TODO: Paginate this
+func (d *Database) GetDeadLetterTasks(ctx context.Context) ([]types.TaskRecord, error) {
+	tasks := []types.TaskRecord{}
+	query := `SELECT * FROM tasks WHERE status = 'FAILED' ORDER BY updated_at DESC`
+
+	if err := d.Client.SelectContext(ctx, &tasks, query); err != nil {
+		return []types.TaskRecord{}, err
+	}
+
+	return tasks, nil
+}
+
+// CoalesceTask replaces the payload of the last pending task of a deployment when it is of the same type
+// and has not been attempted yet, so back-to-back tasks that carry the full target state collapse into one.
+// Returns sql.ErrNoRows when the deployment has no such task at the tail of its queue.
+func (d *Database) CoalesceTask(ctx context.Context, taskType string, payload []byte, deploymentUUID string) (types.TaskRecord, error) {
+	var task types.TaskRecord
+	query := `UPDATE tasks SET payload = $2 WHERE id = (
+		SELECT id FROM tasks WHERE deployment_uuid = $3 AND status IN ('QUEUED', 'RUNNING') ORDER BY id DESC LIMIT 1 FOR UPDATE
+	) AND type = $1 AND status = 'QUEUED' AND attempts = 0 RETURNING *`
+
+	if err := d.Client.GetContext(ctx, &task, query, taskType, payload, deploymentUUID); err != nil {
+		return types.TaskRecord{}, err
+	}
+
+	return task, nil
+}
+
+// ClaimNextTask atomically moves the oldest due task to RUNNING under a lease, counts the attempt and returns it.
+// Rows locked by other workers are skipped, so concurrent callers never claim the same task. RUNNING tasks whose
+// lease lapsed, because the process running them died, are claimed again.
+// A task is only eligible once every earlier task of the same deployment has finished, which keeps
+// tasks of one deployment strictly ordered while different deployments are processed in parallel.
+// Returns sql.ErrNoRows when there is nothing to claim.
+func (d *Database) ClaimNextTask(ctx context.Context, lease time.Duration) (types.TaskRecord, error) {
+	var task types.TaskRecord
+	query := `UPDATE tasks SET status = 'RUNNING', attempts = attempts + 1, started_at = COALESCE(started_at, now()), lease_expires_at = now() + $1 * interval '1 millisecond' WHERE id = (
+		SELECT t.id FROM tasks t
+		WHERE ((t.status = 'QUEUED' AND t.run_at <= now()) OR (t.status = 'RUNNING' AND t.lease_expires_at < now())) AND (t.deployment_uuid IS NULL OR NOT EXISTS (
+			SELECT 1 FROM tasks p WHERE p.deployment_uuid = t.deployment_uuid AND p.id < t.id AND p.status IN ('QUEUED', 'RUNNING')
+		))
+		ORDER BY t.id LIMIT 1 FOR UPDATE SKIP LOCKED
+	) RETURNING *`
+
+	if err := d.Client.GetContext(ctx, &task, query, lease.Milliseconds()); err != nil {
+		return types.TaskRecord{}, err
+	}
+
+	return task, nil
+}
+
+// RenewTaskLease extends the lease of a task that is still RUNNING.
+func (d *Database) RenewTaskLease(ctx context.Context, uuid string, lease time.Duration) error {
+	query := `UPDATE tasks SET lease_expires_at = now() + $2 * interval '1 millisecond' WHERE uuid = $1 AND status = 'RUNNING'`
+
+	if _, err := d.Client.ExecContext(ctx, query, uuid, lease.Milliseconds()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CompleteTask marks a task as SUCCEEDED. The env vars it carried are dropped from its payload, the deployment
+// and its revisions keep them from now on.
+func (d *Database) CompleteTask(ctx context.Context, uuid string) error {
+	if _, err := d.Client.ExecContext(ctx, `UPDATE tasks SET status = 'SUCCEEDED', finished_at = now(), payload = payload - 'envArray' WHERE uuid = $1`, uuid); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RetryTask puts a failed task back in the queue to be claimed again once the delay has passed.
+func (d *Database) RetryTask(ctx context.Context, uuid string, delay time.Duration, lastError string) error {
+	query := `UPDATE tasks SET status = 'QUEUED', run_at = now() + $2 * interval '1 millisecond', last_error = $3 WHERE uuid = $1`
+
+	if _, err := d.Client.ExecContext(ctx, query, uuid, delay.Milliseconds(), lastError); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeadLetterTask marks a task that has exhausted its attempts as FAILED.
+func (d *Database) DeadLetterTask(ctx context.Context, uuid string, lastError string) error {
+	if _, err := d.Client.ExecContext(ctx, `UPDATE tasks SET status = 'FAILED', last_error = $2, finished_at = now() WHERE uuid = $1`, uuid, lastError); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RedriveTask resets the attempts of a dead-lettered task and queues it to run immediately.
+// Returns sql.ErrNoRows if no FAILED task with the given uuid exists.
+func (d *Database) RedriveTask(ctx context.Context, uuid string) (types.TaskRecord, error) {
+	var task types.TaskRecord
+	query := `UPDATE tasks SET status = 'QUEUED', attempts = 0, run_at = now(), finished_at = NULL WHERE uuid = $1 AND status = 'FAILED' RETURNING *`
+
+	if err := d.Client.GetContext(ctx, &task, query, uuid); err != nil {
+		return types.TaskRecord{}, err
+	}
+
+	return task, nil
+}
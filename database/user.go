@@ -53,3 +53,11 @@ func (d *Database) CreateUser(ctx context.Context, userAttributes types.CreateUs
 	return user, nil
 
 }
+
+func (d *Database) UpdateUserQuota(ctx context.Context, uuid string, quota types.Quota) (types.User, error) {
+	if _, err := d.Client.ExecContext(ctx, `UPDATE users SET max_deployments = $2, max_memory = $3, max_cpus = $4, max_public_ports = $5 WHERE uuid = $1`, uuid, quota.MaxDeployments, quota.MaxMemory, quota.MaxCPUs, quota.MaxPublicPorts); err != nil {
+		return types.User{}, err
+	}
+
+	return d.GetUserByUUID(ctx, uuid)
+}
@@ -0,0 +1,183 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// GarbageCollector removes containers created by the engine that no deployment points to anymore,
+// e.g. when provisioning succeeded but the deployment row could not be updated, along with the dangling images
+// pulled for deployments. Containers that started or stopped within the grace period are left alone so in-flight
+// tasks are not raced and the logs of a container that just crashed can still be read.
+type GarbageCollector struct {
+	Db          database.Repository
+	Docker      services.ContainerRuntime
+	Interval    time.Duration
+	GracePeriod time.Duration
+}
+
+type CollectedContainer struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	DeploymentUUID string    `json:"deploymentUUID"`
+	State          string    `json:"state"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Error          string    `json:"error,omitempty"`
+}
+
+type CollectedImage struct {
+	ID        string    `json:"id"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type GarbageCollectionReport struct {
+	DryRun     bool                 `json:"dryRun"`
+	Containers []CollectedContainer `json:"containers"`
+	Images     []CollectedImage     `json:"images"`
+}
+
+func NewGarbageCollector(db database.Repository, docker services.ContainerRuntime, interval time.Duration, gracePeriod time.Duration) *GarbageCollector {
+	return &GarbageCollector{
+		Db:          db,
+		Docker:      docker,
+		Interval:    interval,
+		GracePeriod: gracePeriod,
+	}
+}
+
+func (g *GarbageCollector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(g.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			report, err := g.Collect(ctx, false)
+			if err != nil {
+				log.Printf("error collecting garbage: %s\n", err.Error())
+				continue
+			}
+			if len(report.Containers) > 0 || len(report.Images) > 0 {
+				log.Printf("garbage collector removed %d containers and %d images\n", len(report.Containers), len(report.Images))
+			}
+		}
+	}
+}
+
+// Collect finds orphaned containers and dangling images of deployed repositories and, unless dryRun is set, removes them.
+// Images the engine didn't pull, e.g. ones built on the host, are never removed.
+func (g *GarbageCollector) Collect(ctx context.Context, dryRun bool) (GarbageCollectionReport, error) {
+	report := GarbageCollectionReport{DryRun: dryRun, Containers: []CollectedContainer{}, Images: []CollectedImage{}}
+	cutoff := time.Now().Add(-g.GracePeriod)
+
+	instances, err := g.Db.GetAllDeploymentInstances(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	referenced := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		referenced[*instance.ContainerId] = true
+	}
+
+	containers, err := g.Docker.ListContainers(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	for _, cont := range containers {
+		if cont.Labels[services.LABEL_MANAGED] != "true" || referenced[cont.ID] || cont.CreatedAt.After(cutoff) {
+			continue
+		}
+
+		// Only inspecting a container tells when it last started or stopped
+		inspected, err := g.Docker.InspectContainer(ctx, cont.ID)
+		if errors.Is(err, services.ErrContainerNotFound) {
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		if inspected.StartedAt.After(cutoff) || inspected.FinishedAt.After(cutoff) {
+			continue
+		}
+
+		collected := CollectedContainer{ID: cont.ID, Name: cont.Name, DeploymentUUID: cont.Labels[services.LABEL_DEPLOYMENT_UUID], State: cont.State, CreatedAt: cont.CreatedAt}
+		if !dryRun {
+			if err := g.Docker.RemoveContainer(ctx, cont.ID); err != nil {
+				collected.Error = err.Error()
+			}
+		}
+
+		report.Containers = append(report.Containers, collected)
+	}
+
+	repositories, err := g.deployedRepositories(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	images, err := g.Docker.ListDanglingImages(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	for _, image := range images {
+		if image.CreatedAt.After(cutoff) || !pulledFrom(image, repositories) {
+			continue
+		}
+
+		collected := CollectedImage{ID: image.ID, Size: image.Size, CreatedAt: image.CreatedAt}
+		if !dryRun {
+			if err := g.Docker.RemoveImage(ctx, image.ID); err != nil {
+				collected.Error = err.Error()
+			}
+		}
+
+		report.Images = append(report.Images, collected)
+	}
+
+	return report, nil
+}
+
+// deployedRepositories returns the repositories of every image a deployment runs or one of its revisions ran.
+func (g *GarbageCollector) deployedRepositories(ctx context.Context) (map[string]bool, error) {
+	deployments, err := g.Db.GetAllDeployments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	repositories := map[string]bool{}
+	for _, deployment := range deployments {
+		repositories[types.ImageRepository(*deployment.ImageTag)] = true
+
+		revisions, err := g.Db.GetDeploymentRevisions(ctx, *deployment.UUID)
+		if err != nil {
+			return nil, err
+		}
+		for _, revision := range revisions {
+			repositories[types.ImageRepository(*revision.ImageTag)] = true
+		}
+	}
+
+	return repositories, nil
+}
+
+func pulledFrom(image services.ImageSummary, repositories map[string]bool) bool {
+	for _, repository := range image.Repositories {
+		if repositories[types.ImageRepository(repository)] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+)
+
+func TestCollectGracePeriodRunsFromStop(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+	docker.Uptime = 2 * time.Hour
+
+	running, err := docker.ProvisionContainer(ctx, services.ContainerSpec{DeploymentUUID: "gone", ServiceName: "running", Image: "nginx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped, err := docker.ProvisionContainer(ctx, services.ContainerSpec{DeploymentUUID: "gone", ServiceName: "stopped", Image: "nginx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Created two hours ago, but it only just crashed
+	if err := docker.SetContainerState(stopped, "exited"); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := NewGarbageCollector(db, docker, time.Minute, time.Hour).Collect(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Containers) != 1 || report.Containers[0].ID != running {
+		t.Fatalf("expected only the container running for two hours to be collected, got %+v", report.Containers)
+	}
+	if _, exists := docker.Container(stopped); !exists {
+		t.Error("expected the container that stopped within the grace period to be kept")
+	}
+}
+
+func TestCollectOnlyDeployedImages(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+
+	createDeployment(t, db, docker, "web", 1, nil)
+
+	old := time.Now().Add(-2 * time.Hour)
+	docker.AddDanglingImage(services.ImageSummary{ID: "deployed", Repositories: []string{"nginx"}, CreatedAt: old})
+	docker.AddDanglingImage(services.ImageSummary{ID: "other", Repositories: []string{"ghcr.io/someone/else"}, CreatedAt: old})
+	docker.AddDanglingImage(services.ImageSummary{ID: "built", CreatedAt: old})
+
+	report, err := NewGarbageCollector(db, docker, time.Minute, time.Hour).Collect(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Images) != 1 || report.Images[0].ID != "deployed" {
+		t.Fatalf("expected only the image of the deployed repository to be collected, got %+v", report.Images)
+	}
+}
@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/queue"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+)
+
+// IngressSyncer keeps a file based ingress provider in line with the deployments table and the containers serving
+// them, since the proxy can't discover the containers itself. Tasks sync on their own before removing containers,
+// the periodic sync picks up containers becoming healthy or failing.
+type IngressSyncer struct {
+	Db       database.Repository
+	Docker   services.ContainerRuntime
+	Interval time.Duration
+}
+
+func NewIngressSyncer(db database.Repository, docker services.ContainerRuntime, interval time.Duration) *IngressSyncer {
+	return &IngressSyncer{
+		Db:       db,
+		Docker:   docker,
+		Interval: interval,
+	}
+}
+
+func (s *IngressSyncer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sync(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("error syncing ingress: %s\n", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *IngressSyncer) Sync(ctx context.Context) error {
+	return queue.SyncIngress(ctx, s.Db, s.Docker)
+}
@@ -0,0 +1,200 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/queue"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// Reconciler periodically compares READY and CRASHED deployments against the containers running on the host,
+// restarting containers that exited and queueing recreation of containers that are gone so that
+// every deployment keeps running its number of replicas. A container that keeps exiting after MaxRestarts restarts
+// within RestartWindow is left exited and its deployment marked CRASHED.
+type Reconciler struct {
+	Db             database.Repository
+	Docker         services.ContainerRuntime
+	TaskDispatcher queue.Dispatcher
+	Interval       time.Duration
+	MaxRestarts    int
+	RestartWindow  time.Duration
+
+	// restarts holds when each container was restarted within the window
+	restarts map[string][]time.Time
+}
+
+func NewReconciler(db database.Repository, docker services.ContainerRuntime, taskDispatcher queue.Dispatcher, interval time.Duration, maxRestarts int, restartWindow time.Duration) *Reconciler {
+	return &Reconciler{
+		Db:             db,
+		Docker:         docker,
+		TaskDispatcher: taskDispatcher,
+		Interval:       interval,
+		MaxRestarts:    maxRestarts,
+		RestartWindow:  restartWindow,
+		restarts:       make(map[string][]time.Time),
+	}
+}
+
+func (r *Reconciler) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := r.Reconcile(ctx); err != nil {
+				log.Printf("error reconciling deployments: %s\n", err.Error())
+			}
+		}
+	}
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context) error {
+	// Containers are listed before the deployments are fetched. Tasks that stop containers move the deployment
+	// out of READY first, so a container found stopped here belongs to a deployment that is no longer fetched.
+	containers, err := r.Docker.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+
+	containersByID := make(map[string]services.ContainerSummary, len(containers))
+	for _, c := range containers {
+		containersByID[c.ID] = c
+	}
+
+	now := time.Now()
+	r.forgetRestarts(now)
+
+	deployments, err := r.Db.GetDeploymentsByStatus(ctx, types.DEPLOYMENT_STATUS_READY)
+	if err != nil {
+		return err
+	}
+
+	// Crashed deployments are retried until their containers are back up
+	crashedDeployments, err := r.Db.GetDeploymentsByStatus(ctx, types.DEPLOYMENT_STATUS_CRASHED)
+	if err != nil {
+		return err
+	}
+	deployments = append(deployments, crashedDeployments...)
+
+	for _, deployment := range deployments {
+		// Deployments with tasks in flight are expected to be out of sync until the tasks finish
+		pending, err := r.Db.HasPendingTasks(ctx, *deployment.UUID)
+		if err != nil {
+			return err
+		}
+		if pending {
+			continue
+		}
+
+		instances, err := r.Db.GetDeploymentInstances(ctx, *deployment.UUID)
+		if err != nil {
+			return err
+		}
+
+		missing := 0
+		var restartErr error
+		for _, instance := range instances {
+			cont, exists := containersByID[*instance.ContainerId]
+
+			switch {
+			case !exists:
+				missing++
+			case cont.State != "running" && cont.State != "restarting":
+				if err := r.restart(ctx, deployment, cont, now); err != nil {
+					restartErr = err
+				}
+			}
+		}
+
+		switch {
+		case missing > 0 || len(instances) != deployment.ReplicaCount():
+			r.rescale(ctx, deployment, len(instances)-missing)
+		case restartErr != nil:
+			r.setStatus(ctx, deployment, types.DEPLOYMENT_STATUS_CRASHED, restartErr)
+		case *deployment.Status == types.DEPLOYMENT_STATUS_CRASHED:
+			r.setStatus(ctx, deployment, types.DEPLOYMENT_STATUS_READY, nil)
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) rescale(ctx context.Context, deployment types.Deployment, found int) {
+	taskUUID, err := r.TaskDispatcher.Enqueue(queue.ScaleDeploymentTask{Db: r.Db, Docker: r.Docker, DeploymentUUID: *deployment.UUID, Replicas: deployment.ReplicaCount()})
+	if err != nil {
+		log.Printf("error enqueueing container recreation: %s\n", err.Error())
+		return
+	}
+
+	r.recordEvent(ctx, *deployment.UUID, types.EVENT_CONTAINER_MISSING, fmt.Sprintf("Found %d of %d containers, queued recreation as task %s", found, deployment.ReplicaCount(), taskUUID))
+}
+
+func (r *Reconciler) restart(ctx context.Context, deployment types.Deployment, cont services.ContainerSummary, now time.Time) error {
+	if len(r.restarts[cont.ID]) >= r.MaxRestarts {
+		err := fmt.Errorf("container %s keeps exiting, it was restarted %d times within %s", cont.ID, len(r.restarts[cont.ID]), r.RestartWindow)
+		if *deployment.Status != types.DEPLOYMENT_STATUS_CRASHED {
+			r.recordEvent(ctx, *deployment.UUID, types.EVENT_CONTAINER_CRASH_LOOP, fmt.Sprintf("Container %s keeps exiting and is no longer restarted until %s have passed", cont.ID, r.RestartWindow))
+		}
+		return err
+	}
+
+	r.restarts[cont.ID] = append(r.restarts[cont.ID], now)
+
+	if err := r.Docker.StartContainer(ctx, cont.ID); err != nil {
+		r.recordEvent(ctx, *deployment.UUID, types.EVENT_CONTAINER_RESTART_FAILED, fmt.Sprintf("Container %s is %s and could not be restarted: %s", cont.ID, cont.State, err.Error()))
+		return err
+	}
+
+	r.recordEvent(ctx, *deployment.UUID, types.EVENT_CONTAINER_RESTARTED, fmt.Sprintf("Container %s was %s and has been restarted", cont.ID, cont.State))
+	return nil
+}
+
+// forgetRestarts drops the restarts that happened before the window.
+func (r *Reconciler) forgetRestarts(now time.Time) {
+	for containerID, restarts := range r.restarts {
+		recent := []time.Time{}
+		for _, restartedAt := range restarts {
+			if now.Sub(restartedAt) < r.RestartWindow {
+				recent = append(recent, restartedAt)
+			}
+		}
+
+		if len(recent) == 0 {
+			delete(r.restarts, containerID)
+		} else {
+			r.restarts[containerID] = recent
+		}
+	}
+}
+
+func (r *Reconciler) setStatus(ctx context.Context, deployment types.Deployment, status string, cause error) {
+	if *deployment.Status == status {
+		return
+	}
+
+	var statusReason *string
+	if cause != nil {
+		reason := cause.Error()
+		statusReason = &reason
+	}
+
+	if _, err := r.Db.UpdateDeploymentStatus(ctx, *deployment.UUID, status, statusReason); err != nil {
+		log.Printf("error updating deployment status: %s\n", err.Error())
+	}
+}
+
+func (r *Reconciler) recordEvent(ctx context.Context, deploymentUUID string, eventType string, message string) {
+	log.Printf("reconciler: deployment %s: %s\n", deploymentUUID, message)
+
+	if err := r.Db.CreateDeploymentEvent(ctx, deploymentUUID, eventType, message); err != nil {
+		log.Printf("error recording deployment event: %s\n", err.Error())
+	}
+}
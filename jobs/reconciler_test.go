@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/queue"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// recordingDispatcher keeps the enqueued tasks so that tests can process them one by one.
+type recordingDispatcher struct {
+	tasks []queue.Task
+}
+
+func (d *recordingDispatcher) Enqueue(task queue.Task) (string, error) {
+	d.tasks = append(d.tasks, task)
+	return "task", nil
+}
+
+func (d *recordingDispatcher) Start(ctx context.Context) error {
+	return nil
+}
+
+// newFakeRuntime returns a runtime whose containers are past the startup grace period as soon as they start.
+func newFakeRuntime() *services.FakeRuntime {
+	docker := services.NewFakeRuntime(services.TraefikLabelProvider{})
+	docker.Uptime = time.Hour
+	return docker
+}
+
+// createDeployment creates a READY deployment of the given replicas the way the create handler and task do.
+func createDeployment(t *testing.T, db database.Repository, docker services.ContainerRuntime, subdomain string, replicas int, envConfig types.EnvConfig) types.Deployment {
+	t.Helper()
+	ctx := context.Background()
+
+	user, err := db.CreateUser(ctx, types.CreateUserRequest{Username: subdomain, ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := 8080
+	deployment, err := db.CreateDeployment(ctx, types.DeploymentAttributes{UserUUID: *user.UUID, Subdomain: subdomain, ImageTag: "nginx", Status: types.DEPLOYMENT_STATUS_PENDING, Port: &port, Replicas: replicas, EnvConfig: envConfig})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := queue.CreateDeploymentTask{Db: db, Docker: docker, DeploymentUUID: *deployment.UUID, ImageTag: "nginx", Subdomain: subdomain, EnvArray: envConfig.Array(), ContainerPort: port, Replicas: replicas, CreatedBy: user.UUID}
+	if err := task.Process(); err != nil {
+		t.Fatal(err)
+	}
+
+	deployment, err = db.GetDeployment(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deployment
+}
+
+func TestReconcileRecreatesMissingReplicaWithStoredEnv(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+	dispatcher := &recordingDispatcher{}
+
+	deployment := createDeployment(t, db, docker, "web", 2, types.EnvConfig{"PORT": "8080", "GREETING": "hello"})
+
+	instances, err := db.GetDeploymentInstances(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := docker.RemoveContainer(ctx, *instances[1].ContainerId); err != nil {
+		t.Fatal(err)
+	}
+
+	reconciler := NewReconciler(db, docker, dispatcher, 0, 3, time.Minute)
+	if err := reconciler.Reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dispatcher.tasks) != 1 {
+		t.Fatalf("expected a scale task to be queued, got %d tasks", len(dispatcher.tasks))
+	}
+	if err := dispatcher.tasks[0].Process(); err != nil {
+		t.Fatal(err)
+	}
+
+	instances, err = db.GetDeploymentInstances(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("expected 2 instances, got %d", len(instances))
+	}
+
+	for _, instance := range instances {
+		cont, exists := docker.Container(*instance.ContainerId)
+		if !exists {
+			t.Fatalf("replica %d has no container", *instance.Replica)
+		}
+		if cont.Env["GREETING"] != "hello" || cont.Env["PORT"] != "8080" {
+			t.Errorf("replica %d was started with env %v", *instance.Replica, cont.Env)
+		}
+	}
+}
+
+func TestReconcileMarksCrashLoopingDeploymentCrashed(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+	dispatcher := &recordingDispatcher{}
+
+	deployment := createDeployment(t, db, docker, "web", 1, types.EnvConfig{"PORT": "8080"})
+
+	instances, err := db.GetDeploymentInstances(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	containerID := *instances[0].ContainerId
+
+	reconciler := NewReconciler(db, docker, dispatcher, 0, 2, time.Minute)
+
+	// The container exits again after every restart
+	for pass := 0; pass < 3; pass++ {
+		if err := docker.StopContainer(ctx, containerID); err != nil {
+			t.Fatal(err)
+		}
+		if err := reconciler.Reconcile(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		deployment, err = db.GetDeployment(ctx, *deployment.UUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := types.DEPLOYMENT_STATUS_READY
+		if pass == 2 {
+			expected = types.DEPLOYMENT_STATUS_CRASHED
+		}
+		if *deployment.Status != expected {
+			t.Fatalf("expected the deployment to be %s after pass %d, got %s", expected, pass, *deployment.Status)
+		}
+	}
+
+	if cont, _ := docker.Container(containerID); cont.State != "exited" {
+		t.Errorf("expected the crash looping container to be left exited, got %s", cont.State)
+	}
+
+	if err := reconciler.Reconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	deployment, err = db.GetDeployment(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Status != types.DEPLOYMENT_STATUS_CRASHED {
+		t.Errorf("expected the deployment to stay CRASHED within the restart window, got %s", *deployment.Status)
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"time"
 
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/api"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/jobs"
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/queue"
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
 	_ "github.com/joho/godotenv/autoload"
@@ -20,11 +22,22 @@ import (
 
 func main() {
 
-	log.Println("Connecting to database...")
+	var db database.Repository
 
-	db, err := database.NewDatabase()
-	if err != nil {
-		log.Println("Error connecting to the database", err)
+	switch os.Getenv("STORAGE_DRIVER") {
+	case "memory":
+		log.Println("Using in-memory storage, all data will be lost on shutdown")
+
+		db = database.NewMemoryDatabase()
+	default:
+		log.Println("Connecting to database...")
+
+		postgres, err := database.NewDatabase()
+		if err != nil {
+			log.Fatal("Error connecting to the database", err)
+		}
+
+		db = postgres
 	}
 
 	if err := db.Ping(context.Background()); err != nil {
@@ -33,21 +46,69 @@ func main() {
 
 	log.Println("successfully connected to database")
 
-	docker, err := services.NewDockerService()
-	if err != nil {
-		log.Println("Error connecting to Docker", err)
+	var ingress services.IngressProvider
+
+	switch os.Getenv("INGRESS_PROVIDER") {
+	case "file":
+		log.Printf("Writing ingress configuration to %s\n", os.Getenv("INGRESS_FILE_PATH"))
+
+		fileIngress, err := services.NewFileIngressProvider(os.Getenv("INGRESS_FILE_FORMAT"), os.Getenv("INGRESS_FILE_PATH"))
+		if err != nil {
+			log.Fatal("Error configuring the ingress provider", err)
+		}
+
+		ingress = fileIngress
+	default:
+		ingress = services.TraefikLabelProvider{}
+	}
+
+	var docker services.ContainerRuntime
+
+	switch os.Getenv("CONTAINER_RUNTIME") {
+	case "fake":
+		log.Println("Using the fake container runtime, no containers will actually be started")
+
+		docker = services.NewFakeRuntime(ingress)
+	default:
+		dockerService, err := services.NewDockerService(ingress, os.Getenv("INGRESS_NETWORK"))
+		if err != nil {
+			log.Fatal("Error connecting to Docker", err)
+		}
+
+		docker = dockerService
 	}
 
 	if err := docker.Ping(context.Background()); err != nil {
 		log.Fatal("Error pinging Docker", err)
 	}
 
-	taskDispatcher := queue.NewTaskDispatcher(queue.Options{MaxWorkers: 10, MaxQueueSize: 100})
+	taskDispatcher := queue.NewPersistentDispatcher(db, docker, queue.Options{MaxWorkers: 10, PollInterval: time.Second})
 	taskDispatcherCTX, taskDispatcherCTXCancel := context.WithCancel(context.Background())
 
-	go taskDispatcher.Start(taskDispatcherCTX)
+	go func() {
+		if err := taskDispatcher.Start(taskDispatcherCTX); err != nil && err != context.Canceled {
+			log.Fatal("Task dispatcher error: ", err)
+		}
+	}()
+
+	reconciler := jobs.NewReconciler(db, docker, taskDispatcher, config.RECONCILE_INTERVAL, config.CRASH_LOOP_RESTARTS, config.CRASH_LOOP_WINDOW)
+	jobsCTX, jobsCTXCancel := context.WithCancel(context.Background())
+
+	go reconciler.Start(jobsCTX)
+
+	gc := jobs.NewGarbageCollector(db, docker, config.GC_INTERVAL, config.GC_GRACE_PERIOD)
+
+	go gc.Start(jobsCTX)
+
+	if os.Getenv("INGRESS_PROVIDER") == "file" {
+		ingressSyncer := jobs.NewIngressSyncer(db, docker, config.INGRESS_SYNC_INTERVAL)
+
+		go ingressSyncer.Start(jobsCTX)
+	}
+
+	resolver := services.NewNetResolver(os.Getenv("DNS_RESOLVER"), os.Getenv("HTTP_CHALLENGE_ADDRESS"))
 
-	server := api.NewServer(fmt.Sprintf(":%s", os.Getenv("PORT")), db, docker, taskDispatcher)
+	server := api.NewServer(fmt.Sprintf(":%s", os.Getenv("PORT")), db, docker, taskDispatcher, gc, resolver)
 
 	log.Println("Starting server...")
 
@@ -68,6 +129,7 @@ func main() {
 
 	log.Println("==============================")
 
+	jobsCTXCancel()
 	taskDispatcherCTXCancel()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRequired(c *gin.Context) {
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	if adminToken == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin API is disabled"})
+		return
+	}
+
+	providedToken := c.GetHeader("X-Admin-Token")
+	if subtle.ConstantTimeCompare([]byte(providedToken), []byte(adminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin token"})
+		return
+	}
+
+	c.Next()
+}
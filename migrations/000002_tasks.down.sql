@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS public.tasks CASCADE;
+
+DROP TYPE IF EXISTS task_status;
@@ -0,0 +1,22 @@
+CREATE TYPE task_status AS ENUM ('QUEUED', 'RUNNING', 'SUCCEEDED', 'FAILED');
+
+CREATE TABLE IF NOT EXISTS public.tasks (
+  id bigserial NOT NULL PRIMARY KEY,
+  uuid text NOT NULL DEFAULT replace(gen_random_uuid ()::text, '-', ''),
+
+  type text NOT NULL,
+  payload jsonb NOT NULL,
+
+  status task_status DEFAULT 'QUEUED' NOT NULL,
+
+  created_at timestamptz DEFAULT now() NOT NULL,
+  updated_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS tasks_status_id_idx ON public.tasks (status, id);
+
+CREATE TRIGGER tasks_updated_at_update_trigger
+  BEFORE UPDATE
+  ON public.tasks
+  FOR EACH ROW
+EXECUTE PROCEDURE update_updated_at();
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS tasks_status_run_at_idx;
+CREATE INDEX IF NOT EXISTS tasks_status_id_idx ON public.tasks (status, id);
+
+ALTER TABLE public.tasks
+  DROP COLUMN IF EXISTS attempts,
+  DROP COLUMN IF EXISTS max_attempts,
+  DROP COLUMN IF EXISTS run_at,
+  DROP COLUMN IF EXISTS last_error;
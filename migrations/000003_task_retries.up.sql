@@ -0,0 +1,8 @@
+ALTER TABLE public.tasks
+  ADD COLUMN IF NOT EXISTS attempts INTEGER DEFAULT 0 NOT NULL,
+  ADD COLUMN IF NOT EXISTS max_attempts INTEGER DEFAULT 1 NOT NULL,
+  ADD COLUMN IF NOT EXISTS run_at timestamptz DEFAULT now() NOT NULL,
+  ADD COLUMN IF NOT EXISTS last_error text DEFAULT NULL;
+
+DROP INDEX IF EXISTS tasks_status_id_idx;
+CREATE INDEX IF NOT EXISTS tasks_status_run_at_idx ON public.tasks (status, run_at);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS tasks_deployment_uuid_idx;
+
+ALTER TABLE public.tasks
+  DROP COLUMN IF EXISTS deployment_uuid,
+  DROP COLUMN IF EXISTS user_id,
+  DROP COLUMN IF EXISTS started_at,
+  DROP COLUMN IF EXISTS finished_at;
@@ -0,0 +1,7 @@
+ALTER TABLE public.tasks
+  ADD COLUMN IF NOT EXISTS deployment_uuid text DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS user_id bigint DEFAULT NULL CONSTRAINT tasks_user_id_fkey REFERENCES public.users (id) ON UPDATE CASCADE ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS started_at timestamptz DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS finished_at timestamptz DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS tasks_deployment_uuid_idx ON public.tasks (deployment_uuid, id);
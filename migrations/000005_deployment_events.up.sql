@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS public.deployment_events (
+  id bigserial NOT NULL PRIMARY KEY,
+  deployment_uuid text NOT NULL,
+
+  type text NOT NULL,
+  message text NOT NULL,
+
+  created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS deployment_events_deployment_uuid_idx ON public.deployment_events (deployment_uuid, id);
@@ -0,0 +1,8 @@
+ALTER TABLE public.deployments
+  DROP COLUMN IF EXISTS cpu_shares,
+  DROP COLUMN IF EXISTS cpu_quota,
+  DROP COLUMN IF EXISTS cpu_period,
+  DROP COLUMN IF EXISTS memory_limit,
+  DROP COLUMN IF EXISTS memory_reservation,
+  DROP COLUMN IF EXISTS pids_limit,
+  DROP COLUMN IF EXISTS ulimits;
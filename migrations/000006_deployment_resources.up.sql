@@ -0,0 +1,8 @@
+ALTER TABLE public.deployments
+  ADD COLUMN IF NOT EXISTS cpu_shares BIGINT DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS cpu_quota BIGINT DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS cpu_period BIGINT DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS memory_limit BIGINT DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS memory_reservation BIGINT DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS pids_limit BIGINT DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS ulimits jsonb DEFAULT NULL;
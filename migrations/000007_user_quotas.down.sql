@@ -0,0 +1,4 @@
+ALTER TABLE public.users
+  DROP COLUMN IF EXISTS max_deployments,
+  DROP COLUMN IF EXISTS max_memory,
+  DROP COLUMN IF EXISTS max_cpus;
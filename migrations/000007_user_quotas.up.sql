@@ -0,0 +1,4 @@
+ALTER TABLE public.users
+  ADD COLUMN IF NOT EXISTS max_deployments INTEGER DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS max_memory BIGINT DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS max_cpus DOUBLE PRECISION DEFAULT NULL;
@@ -0,0 +1,10 @@
+ALTER TABLE public.deployments
+  ADD COLUMN IF NOT EXISTS container_id TEXT UNIQUE DEFAULT NULL;
+
+UPDATE public.deployments d SET container_id = i.container_id
+  FROM public.deployment_instances i WHERE i.deployment_id = d.id AND i.replica = 0;
+
+DROP TABLE IF EXISTS public.deployment_instances;
+
+ALTER TABLE public.deployments
+  DROP COLUMN IF EXISTS replicas;
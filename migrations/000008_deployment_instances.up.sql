@@ -0,0 +1,27 @@
+ALTER TABLE public.deployments
+  ADD COLUMN IF NOT EXISTS replicas INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS public.deployment_instances (
+  id bigserial NOT NULL PRIMARY KEY,
+  deployment_id bigint NOT NULL CONSTRAINT deployment_instances_deployment_id_fkey REFERENCES public.deployments (id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  replica INTEGER NOT NULL,
+  container_id TEXT UNIQUE NOT NULL,
+
+  created_at timestamptz DEFAULT now() NOT NULL,
+  updated_at timestamptz DEFAULT now() NOT NULL,
+
+  CONSTRAINT deployment_instances_deployment_id_replica_key UNIQUE (deployment_id, replica)
+);
+
+CREATE TRIGGER deployment_instances_updated_at_update_trigger
+  BEFORE UPDATE
+  ON public.deployment_instances
+  FOR EACH ROW
+EXECUTE PROCEDURE update_updated_at();
+
+INSERT INTO public.deployment_instances (deployment_id, replica, container_id)
+  SELECT id, 0, container_id FROM public.deployments WHERE container_id IS NOT NULL;
+
+ALTER TABLE public.deployments
+  DROP COLUMN IF EXISTS container_id;
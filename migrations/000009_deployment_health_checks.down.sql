@@ -0,0 +1,16 @@
+ALTER TABLE public.deployments
+  DROP COLUMN IF EXISTS health_check,
+  DROP COLUMN IF EXISTS status_reason;
+
+UPDATE public.deployments SET status = 'PENDING' WHERE status = 'FAILED';
+
+ALTER TYPE deployment_status RENAME TO deployment_status_old;
+
+CREATE TYPE deployment_status AS ENUM ('PENDING', 'READY', 'DELETING');
+
+ALTER TABLE public.deployments
+  ALTER COLUMN status DROP DEFAULT,
+  ALTER COLUMN status TYPE deployment_status USING status::text::deployment_status,
+  ALTER COLUMN status SET DEFAULT 'PENDING';
+
+DROP TYPE deployment_status_old;
@@ -0,0 +1,5 @@
+ALTER TYPE deployment_status ADD VALUE IF NOT EXISTS 'FAILED';
+
+ALTER TABLE public.deployments
+  ADD COLUMN IF NOT EXISTS health_check jsonb DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS status_reason TEXT DEFAULT NULL;
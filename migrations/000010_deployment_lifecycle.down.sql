@@ -0,0 +1,13 @@
+UPDATE public.deployments SET status = 'PENDING' WHERE status = 'PROVISIONING';
+UPDATE public.deployments SET status = 'READY' WHERE status IN ('UPDATING', 'STOPPED', 'CRASHED');
+
+ALTER TYPE deployment_status RENAME TO deployment_status_old;
+
+CREATE TYPE deployment_status AS ENUM ('PENDING', 'READY', 'DELETING', 'FAILED');
+
+ALTER TABLE public.deployments
+  ALTER COLUMN status DROP DEFAULT,
+  ALTER COLUMN status TYPE deployment_status USING status::text::deployment_status,
+  ALTER COLUMN status SET DEFAULT 'PENDING';
+
+DROP TYPE deployment_status_old;
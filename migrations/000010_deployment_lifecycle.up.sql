@@ -0,0 +1,4 @@
+ALTER TYPE deployment_status ADD VALUE IF NOT EXISTS 'PROVISIONING';
+ALTER TYPE deployment_status ADD VALUE IF NOT EXISTS 'UPDATING';
+ALTER TYPE deployment_status ADD VALUE IF NOT EXISTS 'STOPPED';
+ALTER TYPE deployment_status ADD VALUE IF NOT EXISTS 'CRASHED';
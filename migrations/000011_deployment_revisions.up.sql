@@ -0,0 +1,27 @@
+-- Existing deployments get their first revision on their next update, their env vars can't be recovered here
+CREATE TABLE IF NOT EXISTS public.deployment_revisions (
+  id bigserial NOT NULL PRIMARY KEY,
+  deployment_id bigint NOT NULL CONSTRAINT deployment_revisions_deployment_id_fkey REFERENCES public.deployments (id) ON UPDATE CASCADE ON DELETE CASCADE,
+  revision INTEGER NOT NULL,
+
+  image_tag TEXT NOT NULL,
+  sub_domain TEXT NOT NULL,
+  port INTEGER NOT NULL,
+  env_config jsonb NOT NULL DEFAULT '{}',
+
+  cpu_shares BIGINT DEFAULT NULL,
+  cpu_quota BIGINT DEFAULT NULL,
+  cpu_period BIGINT DEFAULT NULL,
+  memory_limit BIGINT DEFAULT NULL,
+  memory_reservation BIGINT DEFAULT NULL,
+  pids_limit BIGINT DEFAULT NULL,
+  ulimits jsonb DEFAULT NULL,
+  health_check jsonb DEFAULT NULL,
+
+  rolled_back_from INTEGER DEFAULT NULL,
+  created_by TEXT DEFAULT NULL,
+
+  created_at timestamptz DEFAULT now() NOT NULL,
+
+  CONSTRAINT deployment_revisions_deployment_id_revision_key UNIQUE (deployment_id, revision)
+);
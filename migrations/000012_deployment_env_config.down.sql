@@ -0,0 +1,2 @@
+ALTER TABLE public.deployments
+  DROP COLUMN IF EXISTS env_config;
@@ -0,0 +1,7 @@
+ALTER TABLE public.deployments
+  ADD COLUMN IF NOT EXISTS env_config jsonb DEFAULT NULL;
+
+-- Deployments without a revision keep a NULL env config, it's read back from their containers instead
+UPDATE public.deployments d SET env_config = r.env_config
+  FROM public.deployment_revisions r
+  WHERE r.deployment_id = d.id AND r.revision = (SELECT MAX(revision) FROM public.deployment_revisions WHERE deployment_id = d.id);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS public.secrets (
+  id bigserial NOT NULL PRIMARY KEY,
+  user_id bigint NOT NULL CONSTRAINT secrets_user_id_fkey REFERENCES public.users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  name TEXT NOT NULL,
+  ciphertext bytea NOT NULL,
+  encrypted_key bytea NOT NULL,
+
+  created_at timestamptz DEFAULT now() NOT NULL,
+  updated_at timestamptz DEFAULT now() NOT NULL,
+
+  CONSTRAINT secrets_user_id_name_key UNIQUE (user_id, name)
+);
+
+CREATE TRIGGER secrets_updated_at_update_trigger
+  BEFORE UPDATE
+  ON public.secrets
+  FOR EACH ROW
+EXECUTE PROCEDURE update_updated_at();
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS public.registry_credentials (
+  id bigserial NOT NULL PRIMARY KEY,
+  uuid text NOT NULL DEFAULT replace(gen_random_uuid ()::text, '-', ''),
+  user_id bigint NOT NULL CONSTRAINT registry_credentials_user_id_fkey REFERENCES public.users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  host TEXT NOT NULL,
+  username TEXT NOT NULL,
+  ciphertext bytea NOT NULL,
+  encrypted_key bytea NOT NULL,
+
+  created_at timestamptz DEFAULT now() NOT NULL,
+  updated_at timestamptz DEFAULT now() NOT NULL,
+
+  CONSTRAINT registry_credentials_uuid_key UNIQUE (uuid),
+  CONSTRAINT registry_credentials_user_id_host_key UNIQUE (user_id, host)
+);
+
+CREATE TRIGGER registry_credentials_updated_at_update_trigger
+  BEFORE UPDATE
+  ON public.registry_credentials
+  FOR EACH ROW
+EXECUTE PROCEDURE update_updated_at();
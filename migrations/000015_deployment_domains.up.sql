@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS public.deployment_domains (
+  id bigserial NOT NULL PRIMARY KEY,
+  uuid text NOT NULL DEFAULT replace(gen_random_uuid ()::text, '-', ''),
+  deployment_id bigint NOT NULL CONSTRAINT deployment_domains_deployment_id_fkey REFERENCES public.deployments (id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  hostname TEXT NOT NULL,
+  verification_method TEXT NOT NULL,
+  verification_token TEXT NOT NULL,
+  verified_at timestamptz DEFAULT NULL,
+
+  created_at timestamptz DEFAULT now() NOT NULL,
+  updated_at timestamptz DEFAULT now() NOT NULL,
+
+  CONSTRAINT deployment_domains_uuid_key UNIQUE (uuid),
+  CONSTRAINT deployment_domains_deployment_id_hostname_key UNIQUE (deployment_id, hostname)
+);
+
+-- Any number of deployments can claim a hostname, but only one can have it verified
+CREATE UNIQUE INDEX IF NOT EXISTS deployment_domains_verified_hostname_key ON public.deployment_domains (hostname) WHERE verified_at IS NOT NULL;
+
+CREATE TRIGGER deployment_domains_updated_at_update_trigger
+  BEFORE UPDATE
+  ON public.deployment_domains
+  FOR EACH ROW
+EXECUTE PROCEDURE update_updated_at();
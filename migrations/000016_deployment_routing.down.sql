@@ -0,0 +1,5 @@
+ALTER TABLE public.deployment_revisions
+  DROP COLUMN IF EXISTS routing;
+
+ALTER TABLE public.deployments
+  DROP COLUMN IF EXISTS routing;
@@ -0,0 +1,5 @@
+ALTER TABLE public.deployments
+  ADD COLUMN IF NOT EXISTS middlewares jsonb DEFAULT NULL;
+
+ALTER TABLE public.deployment_revisions
+  ADD COLUMN IF NOT EXISTS middlewares jsonb DEFAULT NULL;
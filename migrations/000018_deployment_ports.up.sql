@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS public.deployment_ports (
+  id bigserial NOT NULL PRIMARY KEY,
+  uuid text NOT NULL DEFAULT replace(gen_random_uuid ()::text, '-', ''),
+  deployment_id bigint NOT NULL CONSTRAINT deployment_ports_deployment_id_fkey REFERENCES public.deployments (id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+  name TEXT NOT NULL,
+  protocol TEXT NOT NULL CONSTRAINT deployment_ports_protocol_check CHECK (protocol IN ('TCP', 'UDP')),
+  container_port integer NOT NULL,
+  tls TEXT DEFAULT NULL,
+  -- NULL for TLS ports, which are routed by SNI instead
+  public_port integer DEFAULT NULL,
+
+  created_at timestamptz DEFAULT now() NOT NULL,
+  updated_at timestamptz DEFAULT now() NOT NULL,
+
+  CONSTRAINT deployment_ports_uuid_key UNIQUE (uuid),
+  CONSTRAINT deployment_ports_deployment_id_name_key UNIQUE (deployment_id, name),
+  CONSTRAINT deployment_ports_protocol_public_port_key UNIQUE (protocol, public_port)
+);
+
+CREATE TRIGGER deployment_ports_updated_at_update_trigger
+  BEFORE UPDATE
+  ON public.deployment_ports
+  FOR EACH ROW
+EXECUTE PROCEDURE update_updated_at();
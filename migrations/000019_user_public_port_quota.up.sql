@@ -0,0 +1,2 @@
+ALTER TABLE public.users
+  ADD COLUMN IF NOT EXISTS max_public_ports INTEGER DEFAULT NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE public.tasks
+  ADD COLUMN IF NOT EXISTS lease_expires_at timestamptz DEFAULT NULL;
+
+-- Tasks left RUNNING by a process that predates leases are taken over right away
+UPDATE public.tasks SET lease_expires_at = now() WHERE status = 'RUNNING';
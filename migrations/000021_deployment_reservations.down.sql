@@ -0,0 +1,3 @@
+ALTER TABLE public.deployments
+  DROP COLUMN IF EXISTS reserved_memory,
+  DROP COLUMN IF EXISTS reserved_cpus;
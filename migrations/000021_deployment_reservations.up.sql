@@ -0,0 +1,4 @@
+-- What an update in progress will use, counted against the user's quota until the deployment leaves UPDATING
+ALTER TABLE public.deployments
+  ADD COLUMN IF NOT EXISTS reserved_memory BIGINT DEFAULT NULL,
+  ADD COLUMN IF NOT EXISTS reserved_cpus DOUBLE PRECISION DEFAULT NULL;
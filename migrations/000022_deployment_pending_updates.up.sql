@@ -0,0 +1,3 @@
+-- The configuration queued updates move the deployment to, later updates build on it until the last of them is done
+ALTER TABLE public.deployments
+  ADD COLUMN IF NOT EXISTS pending_update JSONB DEFAULT NULL;
@@ -10,30 +10,110 @@ import (
 )
 
 type CreateDeploymentTask struct {
-	Db                   *database.Database
-	Docker               *services.DockerService
-	DeploymentAttributes *types.Deployment
-	ImageTag             string
-	Subdomain            string
-	EnvArray             []string
-	ContainerPort        int
-	AuthString           string
+	Db             database.Repository       `json:"-"`
+	Docker         services.ContainerRuntime `json:"-"`
+	DeploymentUUID string                    `json:"deploymentUUID"`
+	ImageTag       string                    `json:"imageTag"`
+	Subdomain      string                    `json:"subdomain"`
+	EnvArray       []string                  `json:"envArray"`
+	ContainerPort  int                       `json:"containerPort"`
+	// RegistryCredential is the uuid of the stored credential the dockerAuth of the request was saved as
+	RegistryCredential string                `json:"registryCredential,omitempty"`
+	Resources          types.ResourceSpec    `json:"resources"`
+	Replicas           int                   `json:"replicas"`
+	HealthCheck        *types.HealthCheck    `json:"healthCheck"`
+	Routing            *types.RoutingSpec    `json:"routing"`
+	Middlewares        *types.MiddlewareSpec `json:"middlewares"`
+	CreatedBy          *string               `json:"createdBy"`
+}
+
+func (task CreateDeploymentTask) Type() string {
+	return CREATE_DEPLOYMENT_TASK
+}
+
+func (task CreateDeploymentTask) DeploymentID() string {
+	return task.DeploymentUUID
 }
 
 func (task CreateDeploymentTask) Process() error {
 	log.Println("ADDED DEPLOYMENT CREATE TASK TO QUEUE")
-	log.Printf("%+v\n", task)
 
-	containerId, err := task.Docker.ProvisionContainer(context.Background(), task.ImageTag, task.Subdomain, task.EnvArray, task.ContainerPort, task.AuthString)
+	ctx := context.Background()
+
+	if skip, err := beginTransition(ctx, task.Db, task.DeploymentUUID, types.DEPLOYMENT_STATUS_PROVISIONING); skip || err != nil {
+		return err
+	}
+
+	if err := task.provision(ctx); err != nil {
+		settleStatus(ctx, task.Db, task.Docker, task.DeploymentUUID, err)
+		return err
+	}
+
+	return nil
+}
+
+func (task CreateDeploymentTask) provision(ctx context.Context) error {
+	replicas := task.Replicas
+	if replicas < 1 {
+		replicas = 1
+	}
+
+	env, secrets, err := resolveSecrets(ctx, task.Db, task.DeploymentUUID, task.EnvArray)
+	if err != nil {
+		log.Printf("error resolving secrets: %s\n", err.Error())
+		return err
+	}
+
+	auth, err := registryAuth(ctx, task.Db, task.DeploymentUUID, task.ImageTag, task.CreatedBy, task.RegistryCredential)
+	if err != nil {
+		log.Printf("error fetching registry credentials: %s\n", err.Error())
+		return err
+	}
+
+	domains, err := verifiedDomains(ctx, task.Db, task.DeploymentUUID)
+	if err != nil {
+		log.Printf("error fetching deployment domains: %s\n", err.Error())
+		return err
+	}
+
+	ports, err := task.Db.GetDeploymentPorts(ctx, task.DeploymentUUID)
+	if err != nil {
+		log.Printf("error fetching deployment ports: %s\n", err.Error())
+		return err
+	}
+
+	spec := services.ContainerSpec{DeploymentUUID: task.DeploymentUUID, ServiceName: task.Subdomain, Image: task.ImageTag, Env: env, Secrets: secrets, Port: task.ContainerPort, RegistryAuth: auth, Resources: task.Resources, HealthCheck: task.HealthCheck, Routing: task.Routing, Middlewares: task.Middlewares, Domains: domains, Ports: ports}
+
+	containerIds, err := provisionReplicas(ctx, task.Docker, spec, replicaRange(0, replicas), "", nil)
 	if err != nil {
 		log.Printf("error provisioning container: %s\n", err.Error())
 		return err
 	}
 
-	if _, err := task.Db.UpdateDeployment(context.Background(), types.DeploymentAttributes{UUID: *task.DeploymentAttributes.UUID, ImageTag: *task.DeploymentAttributes.ImageTag, Subdomain: *task.DeploymentAttributes.Subdomain, Port: task.DeploymentAttributes.Port, ContainerId: &containerId, Status: "READY"}); err != nil {
+	// The containers are kept when they fail their health check so that their logs can be inspected
+	if _, err := task.Db.SetDeploymentInstances(ctx, task.DeploymentUUID, containerIds); err != nil {
+		log.Printf("error updating deployment instances: %s\n", err.Error())
+		return err
+	}
+
+	if err := waitForReplicas(ctx, task.Docker, spec, containerIds); err != nil {
+		log.Printf("error waiting for container: %s\n", err.Error())
+
+		if err := task.Db.CreateDeploymentEvent(ctx, task.DeploymentUUID, types.EVENT_HEALTH_CHECK_FAILED, err.Error()); err != nil {
+			log.Printf("error recording deployment event: %s\n", err.Error())
+		}
+		return err
+	}
+
+	if _, err := task.Db.UpdateDeployment(ctx, types.DeploymentAttributes{UUID: task.DeploymentUUID, ImageTag: task.ImageTag, Subdomain: task.Subdomain, Port: &task.ContainerPort, Replicas: replicas, Status: types.DEPLOYMENT_STATUS_READY, ResourceSpec: task.Resources, HealthCheck: task.HealthCheck, Routing: task.Routing, Middlewares: task.Middlewares, EnvConfig: types.ParseEnv(task.EnvArray)}); err != nil {
 		log.Printf("error updating deployment row: %s\n", err.Error())
 		return err
 	}
 
+	// The configuration is already applied, a missing revision isn't worth failing the task over
+	if _, err := task.Db.CreateDeploymentRevision(ctx, types.DeploymentRevisionAttributes{DeploymentUUID: task.DeploymentUUID, ImageTag: task.ImageTag, Subdomain: task.Subdomain, Port: task.ContainerPort, EnvConfig: types.ParseEnv(task.EnvArray), ResourceSpec: task.Resources, HealthCheck: task.HealthCheck, Routing: task.Routing, Middlewares: task.Middlewares, RolledBackFrom: nil, CreatedBy: task.CreatedBy}); err != nil {
+		log.Printf("error recording deployment revision: %s\n", err.Error())
+	}
+
 	return nil
 }
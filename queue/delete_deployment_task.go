@@ -6,25 +6,46 @@ import (
 
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
-	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
 )
 
 type DeleteDeploymentTask struct {
-	Db                   *database.Database
-	Docker               *services.DockerService
-	DeploymentAttributes *types.Deployment
+	Db             database.Repository       `json:"-"`
+	Docker         services.ContainerRuntime `json:"-"`
+	DeploymentUUID string                    `json:"deploymentUUID"`
+}
+
+func (task DeleteDeploymentTask) Type() string {
+	return DELETE_DEPLOYMENT_TASK
+}
+
+// Deletions keep retrying for longer since giving up leaves the deployment stuck in DELETING.
+func (task DeleteDeploymentTask) RetryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy
+	policy.MaxAttempts = 10
+	return policy
+}
+
+func (task DeleteDeploymentTask) DeploymentID() string {
+	return task.DeploymentUUID
 }
 
 func (task DeleteDeploymentTask) Process() error {
 	log.Println("ADDED DEPLOYMENT DELETE TASK TO QUEUE")
-	log.Printf("%+v\n", task)
 
-	if err := task.Docker.RemoveContainer(context.Background(), *task.DeploymentAttributes.ContainerId); err != nil {
-		log.Printf("error removing container: %s\n", err.Error())
+	instances, err := task.Db.GetDeploymentInstances(context.Background(), task.DeploymentUUID)
+	if err != nil {
+		log.Printf("error fetching deployment instances: %s\n", err.Error())
 		return err
 	}
 
-	if err := task.Db.DeleteDeployment(context.Background(), *task.DeploymentAttributes.UUID); err != nil {
+	for _, instance := range instances {
+		if err := removeContainer(context.Background(), task.Docker, *instance.ContainerId); err != nil {
+			log.Printf("error removing container: %s\n", err.Error())
+			return err
+		}
+	}
+
+	if err := task.Db.DeleteDeployment(context.Background(), task.DeploymentUUID); err != nil {
 		log.Printf("error deleting deployment row: %s\n", err.Error())
 		return err
 	}
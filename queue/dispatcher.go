@@ -3,43 +3,99 @@ package queue
 import (
 	"context"
 	"errors"
+	"hash/fnv"
+	"log"
 	"sync"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
 )
 
+// TaskDispatcher is an in-memory dispatcher. Tasks of the same deployment are always routed to the
+// same worker, so they run strictly in order while different deployments are processed in parallel.
 type TaskDispatcher struct {
 	Opts     Options
 	Queue    chan Task
 	Finished bool
+
+	deadLettersMu sync.Mutex
+	deadLetters   []Task
 }
 
-func (d *TaskDispatcher) Enqueue(task Task) error {
+func (d *TaskDispatcher) Enqueue(task Task) (string, error) {
 	if d.Finished {
-		return errors.New(`queue is closed`)
+		return "", errors.New(`queue is closed`)
+	}
+
+	// In-memory tasks have no record to look up, the UUID only identifies the task in logs
+	uuid, err := utils.GenerateUUID()
+	if err != nil {
+		return "", err
 	}
+
 	d.Queue <- task
-	return nil
+	return uuid, nil
+}
+
+// DeadLetters returns the tasks that exhausted their retry policy.
+func (d *TaskDispatcher) DeadLetters() []Task {
+	d.deadLettersMu.Lock()
+	defer d.deadLettersMu.Unlock()
+
+	return append([]Task{}, d.deadLetters...)
 }
 
 func (d *TaskDispatcher) Start(ctx context.Context) error {
 	wg := sync.WaitGroup{}
 	errChan := make(chan error, 1)
 
-	for i := 0; i < d.Opts.MaxWorkers; i++ {
+	workerQueues := make([]chan Task, d.Opts.MaxWorkers)
+	for i := range workerQueues {
+		workerQueues[i] = make(chan Task, d.Opts.MaxQueueSize)
+
 		wg.Add(1)
-		go func() {
+		go func(workerQueue chan Task) {
 			defer wg.Done()
 			for {
 				select {
 				case <-ctx.Done():
-					d.Finished = true
-					errChan <- ctx.Err()
 					return
-				case task := <-d.Queue:
-					task.Process()
+				case task := <-workerQueue:
+					d.process(ctx, task)
 				}
 			}
-		}()
+		}(workerQueues[i])
 	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		next := 0
+		for {
+			select {
+			case <-ctx.Done():
+				d.Finished = true
+				errChan <- ctx.Err()
+				return
+			case task := <-d.Queue:
+				var workerQueue chan Task
+				if deploymentTask, ok := task.(DeploymentTask); ok {
+					hash := fnv.New32a()
+					hash.Write([]byte(deploymentTask.DeploymentID()))
+					workerQueue = workerQueues[hash.Sum32()%uint32(len(workerQueues))]
+				} else {
+					workerQueue = workerQueues[next%len(workerQueues)]
+					next++
+				}
+
+				select {
+				case <-ctx.Done():
+				case workerQueue <- task:
+				}
+			}
+		}
+	}()
+
 	go func() {
 		wg.Wait()
 		close(errChan)
@@ -49,7 +105,41 @@ func (d *TaskDispatcher) Start(ctx context.Context) error {
 	return err
 }
 
+// process runs a task until it succeeds or exhausts its retry policy. Retries block the worker
+// so that later tasks of the same deployment cannot overtake a failing one.
+func (d *TaskDispatcher) process(ctx context.Context, task Task) {
+	policy := retryPolicyFor(task, d.Opts.RetryPolicy)
+
+	for attempts := 1; ; attempts++ {
+		err := runTask(task)
+		if err == nil {
+			return
+		}
+
+		if attempts >= policy.MaxAttempts || isPermanent(err) {
+			log.Printf("task %s failed after %d attempts, moving to dead letters: %s\n", task.Type(), attempts, err.Error())
+			d.deadLettersMu.Lock()
+			d.deadLetters = append(d.deadLetters, task)
+			d.deadLettersMu.Unlock()
+			return
+		}
+
+		backoff := policy.Backoff(attempts)
+		log.Printf("task %s failed on attempt %d, retrying in %s: %s\n", task.Type(), attempts, backoff, err.Error())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
 func NewTaskDispatcher(opts Options) *TaskDispatcher {
+	if opts.RetryPolicy.MaxAttempts == 0 {
+		opts.RetryPolicy = DefaultRetryPolicy
+	}
+
 	return &TaskDispatcher{
 		Opts:     opts,
 		Queue:    make(chan Task, opts.MaxQueueSize),
@@ -0,0 +1,25 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+)
+
+// verifiedDomains returns the custom hostnames the deployment's containers are routed on, they are read when
+// the containers are provisioned so that tasks pick up domains verified after they were queued.
+func verifiedDomains(ctx context.Context, db database.Repository, deploymentUUID string) ([]string, error) {
+	domains, err := db.GetDeploymentDomains(ctx, deploymentUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	hostnames := []string{}
+	for _, domain := range domains {
+		if domain.Verified() {
+			hostnames = append(hostnames, *domain.Hostname)
+		}
+	}
+
+	return hostnames, nil
+}
@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+)
+
+// ingressMu serializes syncs, so that a sync working from containers listed before a task replaced the
+// instances can't overwrite the routes written after
+var ingressMu sync.Mutex
+
+// SyncIngress publishes the routes of every deployment to the runtime's ingress provider. Only the recorded
+// instances of a deployment that are running and healthy, or have no health check, receive requests, so tasks
+// replacing containers sync after updating the instances and before removing the old containers.
+func SyncIngress(ctx context.Context, db database.Repository, docker services.ContainerRuntime) error {
+	ingressMu.Lock()
+	defer ingressMu.Unlock()
+
+	instances, err := db.GetAllDeploymentInstances(ctx)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		current[*instance.ContainerId] = true
+	}
+
+	containers, err := docker.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Containers are reached through their short ID, which Docker resolves on the ingress network
+	upstreams := map[string][]string{}
+	for _, cont := range containers {
+		if cont.Labels[services.LABEL_MANAGED] != "true" || !current[cont.ID] || cont.State != "running" || (cont.Health != "" && cont.Health != "healthy") {
+			continue
+		}
+
+		host := cont.ID
+		if len(host) > 12 {
+			host = host[:12]
+		}
+
+		deploymentUUID := cont.Labels[services.LABEL_DEPLOYMENT_UUID]
+		upstreams[deploymentUUID] = append(upstreams[deploymentUUID], host)
+	}
+
+	deployments, err := db.GetAllDeployments(ctx)
+	if err != nil {
+		return err
+	}
+
+	routes := []services.IngressRoute{}
+	for _, deployment := range deployments {
+		hosts := upstreams[*deployment.UUID]
+		if len(hosts) == 0 || deployment.Port == nil {
+			continue
+		}
+		sort.Strings(hosts)
+
+		domains, err := verifiedDomains(ctx, db, *deployment.UUID)
+		if err != nil {
+			return err
+		}
+
+		ports, err := db.GetDeploymentPorts(ctx, *deployment.UUID)
+		if err != nil {
+			return err
+		}
+
+		spec := services.ContainerSpec{DeploymentUUID: *deployment.UUID, ServiceName: *deployment.Subdomain, Port: *deployment.Port, Routing: deployment.Routing, Middlewares: deployment.Middlewares, Domains: domains, Ports: ports}
+		routes = append(routes, services.IngressRoute{Spec: spec, Upstreams: hosts})
+	}
+
+	return docker.Ingress().Sync(ctx, routes)
+}
+
+// syncIngress syncs before containers that were serving are removed, a failure is logged as the periodic
+// sync catches up with the removal anyway.
+func syncIngress(ctx context.Context, db database.Repository, docker services.ContainerRuntime) {
+	if err := SyncIngress(ctx, db, docker); err != nil {
+		log.Printf("error syncing ingress: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,133 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// ErrNoInstances is returned when starting or restarting a deployment that has no containers, only an update
+// provisions them.
+var ErrNoInstances = errors.New("the deployment has no containers, update it to provision them")
+
+// provisionReplicas starts a container for each of the given replicas of spec, with suffix appended to
+// their names. Containers of the deployment an earlier attempt left under the same names are removed first,
+// unless they are in current. If any replica fails the containers started so far are removed again.
+func provisionReplicas(ctx context.Context, docker services.ContainerRuntime, spec services.ContainerSpec, replicas []int, suffix string, current map[string]bool) (map[int]string, error) {
+	containers, err := docker.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	containersByName := make(map[string]services.ContainerSummary, len(containers))
+	for _, cont := range containers {
+		containersByName[cont.Name] = cont
+	}
+
+	containerIds := make(map[int]string, len(replicas))
+	for _, replica := range replicas {
+		replicaSpec := spec
+		replicaSpec.Replica = replica
+		replicaSpec.ContainerName = services.InstanceName(spec.ServiceName, replica) + suffix
+
+		// A container of the same name that isn't labelled with this deployment is never removed, provisioning
+		// fails on the name conflict instead
+		if leftover, exists := containersByName[replicaSpec.Name()]; exists && !current[leftover.ID] && ownedBy(leftover, spec.DeploymentUUID) {
+			if err := removeContainer(ctx, docker, leftover.ID); err != nil {
+				removeContainers(ctx, docker, containerIds)
+				return nil, err
+			}
+		}
+
+		containerId, err := docker.ProvisionContainer(ctx, replicaSpec)
+		if err != nil {
+			removeContainers(ctx, docker, containerIds)
+			return nil, err
+		}
+
+		containerIds[replica] = containerId
+	}
+
+	return containerIds, nil
+}
+
+// ownedBy reports whether the engine created the container for the deployment.
+func ownedBy(cont services.ContainerSummary, deploymentUUID string) bool {
+	return cont.Labels[services.LABEL_MANAGED] == "true" && cont.Labels[services.LABEL_DEPLOYMENT_UUID] == deploymentUUID
+}
+
+// waitForReplicas waits for every container to become healthy, returning the first failure. A replica failing
+// its health check is a PermanentError, provisioning it again would fail the same way.
+func waitForReplicas(ctx context.Context, docker services.ContainerRuntime, spec services.ContainerSpec, containerIds map[int]string) error {
+	for replica, containerId := range containerIds {
+		if err := services.WaitForContainer(ctx, docker, containerId, spec.HealthyTimeout(), spec.StartupGrace()); err != nil {
+			var unhealthy services.UnhealthyError
+			if errors.As(err, &unhealthy) {
+				return PermanentError{fmt.Errorf("replica %d: %w", replica, err)}
+			}
+			return fmt.Errorf("replica %d: %w", replica, err)
+		}
+	}
+
+	return nil
+}
+
+// applyToInstances runs action on every container of a deployment and, with wait set,
+// waits for them to become healthy afterwards. Waiting on a deployment without containers, e.g. one whose create
+// failed, fails permanently rather than reporting it healthy.
+func applyToInstances(ctx context.Context, db database.Repository, docker services.ContainerRuntime, deployment types.Deployment, action func(ctx context.Context, containerID string) error, wait bool) error {
+	instances, err := db.GetDeploymentInstances(ctx, *deployment.UUID)
+	if err != nil {
+		return err
+	}
+
+	if wait && len(instances) == 0 {
+		return PermanentError{ErrNoInstances}
+	}
+
+	containerIds := make(map[int]string, len(instances))
+	for _, instance := range instances {
+		if err := action(ctx, *instance.ContainerId); err != nil {
+			return fmt.Errorf("replica %d: %w", *instance.Replica, err)
+		}
+		containerIds[*instance.Replica] = *instance.ContainerId
+	}
+
+	if !wait {
+		return nil
+	}
+
+	return waitForReplicas(ctx, docker, services.ContainerSpec{HealthCheck: deployment.HealthCheck}, containerIds)
+}
+
+// removeContainer removes a container, treating one that is already gone as removed so that retries get past it.
+func removeContainer(ctx context.Context, docker services.ContainerRuntime, containerId string) error {
+	if err := docker.RemoveContainer(ctx, containerId); err != nil && !errors.Is(err, services.ErrContainerNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// removeContainers removes containers on a best effort basis, the garbage collector picks up whatever is left.
+func removeContainers(ctx context.Context, docker services.ContainerRuntime, containerIds map[int]string) {
+	for _, containerId := range containerIds {
+		if err := removeContainer(ctx, docker, containerId); err != nil {
+			log.Printf("error removing container %s: %s\n", containerId, err.Error())
+		}
+	}
+}
+
+func replicaRange(from int, to int) []int {
+	replicas := []int{}
+	for replica := from; replica < to; replica++ {
+		replicas = append(replicas, replica)
+	}
+
+	return replicas
+}
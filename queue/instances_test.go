@@ -0,0 +1,109 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// newFakeRuntime returns a runtime whose containers are past the startup grace period as soon as they start.
+func newFakeRuntime() *services.FakeRuntime {
+	docker := services.NewFakeRuntime(services.TraefikLabelProvider{})
+	docker.Uptime = time.Hour
+	return docker
+}
+
+// createDeployment creates a READY deployment of the given replicas the way the create handler and task do.
+func createDeployment(t *testing.T, db database.Repository, docker services.ContainerRuntime, subdomain string, replicas int) types.Deployment {
+	t.Helper()
+	ctx := context.Background()
+
+	user, err := db.CreateUser(ctx, types.CreateUserRequest{Username: subdomain, ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := 8080
+	envConfig := types.EnvConfig{"PORT": "8080"}
+	deployment, err := db.CreateDeployment(ctx, types.DeploymentAttributes{UserUUID: *user.UUID, Subdomain: subdomain, ImageTag: "nginx", Status: types.DEPLOYMENT_STATUS_PENDING, Port: &port, Replicas: replicas, EnvConfig: envConfig})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := CreateDeploymentTask{Db: db, Docker: docker, DeploymentUUID: *deployment.UUID, ImageTag: "nginx", Subdomain: subdomain, EnvArray: envConfig.Array(), ContainerPort: port, Replicas: replicas, CreatedBy: user.UUID}
+	if err := task.Process(); err != nil {
+		t.Fatal(err)
+	}
+
+	deployment, err = db.GetDeployment(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deployment
+}
+
+func instanceContainers(t *testing.T, db database.Repository, deploymentUUID string) map[int]string {
+	t.Helper()
+
+	instances, err := db.GetDeploymentInstances(context.Background(), deploymentUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	containerIds := make(map[int]string, len(instances))
+	for _, instance := range instances {
+		containerIds[*instance.Replica] = *instance.ContainerId
+	}
+	return containerIds
+}
+
+func TestDeleteSkipsRemovedContainers(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+
+	deployment := createDeployment(t, db, docker, "web", 2)
+	if err := docker.RemoveContainer(ctx, instanceContainers(t, db, *deployment.UUID)[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (DeleteDeploymentTask{Db: db, Docker: docker, DeploymentUUID: *deployment.UUID}).Process(); err != nil {
+		t.Fatalf("expected the delete to get past the removed container, got %s", err)
+	}
+
+	containers, _ := docker.ListContainers(ctx)
+	if len(containers) != 0 {
+		t.Errorf("expected every container to be removed, %d are left", len(containers))
+	}
+}
+
+func TestUpdateRetryAfterInstancesWereReplaced(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+
+	deployment := createDeployment(t, db, docker, "web", 1)
+	task := UpdateDeploymentTask{Db: db, Docker: docker, DeploymentUUID: *deployment.UUID, ImageTag: "nginx:2", Subdomain: "web", EnvArray: []string{"PORT=8080"}, ContainerPort: 8080, Replicas: 1}
+
+	// An earlier attempt made its container current, and failed before the old one was removed and it was renamed
+	nextId, err := docker.ProvisionContainer(ctx, services.ContainerSpec{DeploymentUUID: *deployment.UUID, ServiceName: "web", ContainerName: "web" + NEXT_CONTAINER_SUFFIX, Image: "nginx:2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SetDeploymentInstances(ctx, *deployment.UUID, map[int]string{0: nextId}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := task.Process(); err != nil {
+		t.Fatalf("expected the retry to succeed, got %s", err)
+	}
+
+	containers, _ := docker.ListContainers(ctx)
+	if len(containers) != 1 || containers[0].Name != "web" || containers[0].Image != "nginx:2" {
+		t.Errorf("expected a single web container running nginx:2, got %+v", containers)
+	}
+}
@@ -0,0 +1,255 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+const DEFAULT_POLL_INTERVAL time.Duration = time.Second
+
+// PersistentDispatcher stores tasks in the tasks table so that pending work survives restarts.
+// Workers claim tasks with SELECT ... FOR UPDATE SKIP LOCKED under a lease they renew while the task runs,
+// so that tasks of a process that died are taken over once their lease lapses, while tasks other running
+// processes are working on are left alone.
+// Tasks of the same deployment run strictly in order, see Database.ClaimNextTask.
+type PersistentDispatcher struct {
+	Opts     Options
+	Db       database.Repository
+	Docker   services.ContainerRuntime
+	Finished bool
+
+	wake chan struct{}
+}
+
+func (d *PersistentDispatcher) Enqueue(task Task) (string, error) {
+	if d.Finished {
+		return "", errors.New(`queue is closed`)
+	}
+
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return "", err
+	}
+
+	policy := retryPolicyFor(task, d.Opts.RetryPolicy)
+
+	var deploymentUUID *string
+	if deploymentTask, ok := task.(DeploymentTask); ok {
+		id := deploymentTask.DeploymentID()
+		deploymentUUID = &id
+	}
+
+	if coalescable, ok := task.(CoalescableTask); ok && coalescable.Coalescable() {
+		record, err := d.Db.CoalesceTask(context.Background(), task.Type(), payload, coalescable.DeploymentID())
+		if err == nil {
+			log.Printf("coalesced %s task into pending task %s\n", task.Type(), *record.UUID)
+			return *record.UUID, nil
+		}
+		if err != sql.ErrNoRows {
+			return "", err
+		}
+	}
+
+	record, err := d.Db.CreateTask(context.Background(), task.Type(), payload, policy.MaxAttempts, deploymentUUID)
+	if err != nil {
+		return "", err
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+
+	return *record.UUID, nil
+}
+
+func (d *PersistentDispatcher) Start(ctx context.Context) error {
+	wg := sync.WaitGroup{}
+	errChan := make(chan error, 1)
+
+	for i := 0; i < d.Opts.MaxWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					d.Finished = true
+					select {
+					case errChan <- ctx.Err():
+					default:
+					}
+					return
+				default:
+				}
+
+				if d.processNext(ctx) {
+					continue
+				}
+
+				select {
+				case <-ctx.Done():
+				case <-d.wake:
+				case <-time.After(d.Opts.PollInterval):
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(errChan)
+	}()
+
+	err := <-errChan
+	return err
+}
+
+// processNext claims and runs a single task. It reports whether a task was claimed.
+func (d *PersistentDispatcher) processNext(ctx context.Context) bool {
+	record, err := d.Db.ClaimNextTask(ctx, d.Opts.LeaseDuration)
+	if err != nil {
+		if err != sql.ErrNoRows && ctx.Err() == nil {
+			log.Printf("error claiming task: %s\n", err.Error())
+		}
+		return false
+	}
+
+	policy := d.Opts.RetryPolicy
+
+	done := make(chan struct{})
+	go d.renewLease(*record.UUID, done)
+
+	task, err := d.decode(record)
+	if err == nil {
+		policy = retryPolicyFor(task, policy)
+		err = runTask(task)
+	}
+	close(done)
+
+	if err == nil {
+		if err := d.Db.CompleteTask(context.Background(), *record.UUID); err != nil {
+			log.Printf("error updating task status: %s\n", err.Error())
+		}
+		return true
+	}
+
+	if *record.Attempts >= *record.MaxAttempts || isPermanent(err) {
+		log.Printf("task %s (%s) failed after %d attempts, moving to dead letters: %s\n", *record.UUID, *record.Type, *record.Attempts, err.Error())
+		if err := d.Db.DeadLetterTask(context.Background(), *record.UUID, err.Error()); err != nil {
+			log.Printf("error dead-lettering task: %s\n", err.Error())
+		}
+		return true
+	}
+
+	backoff := policy.Backoff(*record.Attempts)
+	log.Printf("task %s (%s) failed on attempt %d, retrying in %s: %s\n", *record.UUID, *record.Type, *record.Attempts, backoff, err.Error())
+	if err := d.Db.RetryTask(context.Background(), *record.UUID, backoff, err.Error()); err != nil {
+		log.Printf("error scheduling task retry: %s\n", err.Error())
+	}
+
+	return true
+}
+
+// renewLease keeps extending the lease of a running task until done is closed.
+func (d *PersistentDispatcher) renewLease(uuid string, done chan struct{}) {
+	ticker := time.NewTicker(d.Opts.LeaseDuration / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := d.Db.RenewTaskLease(context.Background(), uuid, d.Opts.LeaseDuration); err != nil {
+				log.Printf("error renewing task lease: %s\n", err.Error())
+			}
+		}
+	}
+}
+
+func (d *PersistentDispatcher) decode(record types.TaskRecord) (Task, error) {
+	switch *record.Type {
+	case CREATE_DEPLOYMENT_TASK:
+		task := CreateDeploymentTask{}
+		if err := json.Unmarshal(record.Payload, &task); err != nil {
+			return nil, err
+		}
+		task.Db, task.Docker = d.Db, d.Docker
+		return task, nil
+	case UPDATE_DEPLOYMENT_TASK:
+		task := UpdateDeploymentTask{}
+		if err := json.Unmarshal(record.Payload, &task); err != nil {
+			return nil, err
+		}
+		task.Db, task.Docker = d.Db, d.Docker
+		return task, nil
+	case DELETE_DEPLOYMENT_TASK:
+		task := DeleteDeploymentTask{}
+		if err := json.Unmarshal(record.Payload, &task); err != nil {
+			return nil, err
+		}
+		task.Db, task.Docker = d.Db, d.Docker
+		return task, nil
+	case SCALE_DEPLOYMENT_TASK:
+		task := ScaleDeploymentTask{}
+		if err := json.Unmarshal(record.Payload, &task); err != nil {
+			return nil, err
+		}
+		task.Db, task.Docker = d.Db, d.Docker
+		return task, nil
+	case STOP_DEPLOYMENT_TASK:
+		task := StopDeploymentTask{}
+		if err := json.Unmarshal(record.Payload, &task); err != nil {
+			return nil, err
+		}
+		task.Db, task.Docker = d.Db, d.Docker
+		return task, nil
+	case START_DEPLOYMENT_TASK:
+		task := StartDeploymentTask{}
+		if err := json.Unmarshal(record.Payload, &task); err != nil {
+			return nil, err
+		}
+		task.Db, task.Docker = d.Db, d.Docker
+		return task, nil
+	case RESTART_DEPLOYMENT_TASK:
+		task := RestartDeploymentTask{}
+		if err := json.Unmarshal(record.Payload, &task); err != nil {
+			return nil, err
+		}
+		task.Db, task.Docker = d.Db, d.Docker
+		return task, nil
+	default:
+		return nil, fmt.Errorf("unknown task type: %s", *record.Type)
+	}
+}
+
+func NewPersistentDispatcher(db database.Repository, docker services.ContainerRuntime, opts Options) *PersistentDispatcher {
+	if opts.PollInterval == 0 {
+		opts.PollInterval = DEFAULT_POLL_INTERVAL
+	}
+	if opts.RetryPolicy.MaxAttempts == 0 {
+		opts.RetryPolicy = DefaultRetryPolicy
+	}
+	if opts.LeaseDuration == 0 {
+		opts.LeaseDuration = config.TASK_LEASE_DURATION
+	}
+
+	return &PersistentDispatcher{
+		Opts:     opts,
+		Db:       db,
+		Docker:   docker,
+		Finished: false,
+		wake:     make(chan struct{}, opts.MaxWorkers),
+	}
+}
@@ -0,0 +1,47 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+)
+
+// registryAuth returns the credentials image is pulled with. The stored credential credentialUUID refers to wins,
+// it holds the credentials sent with the request. Otherwise the credential the deployment's owner has for the
+// image's registry is used, if there is one.
+func registryAuth(ctx context.Context, db database.Repository, deploymentUUID string, image string, userUUID *string, credentialUUID string) (string, error) {
+	host := types.ImageRegistryHost(image)
+
+	var credential types.RegistryCredential
+	err := sql.ErrNoRows
+	if credentialUUID != "" && userUUID != nil {
+		credential, err = db.GetRegistryCredential(ctx, *userUUID, credentialUUID)
+	}
+	// The credential may have been deleted since the task was enqueued
+	if err == sql.ErrNoRows {
+		credential, err = db.GetRegistryCredentialForDeployment(ctx, deploymentUUID, host)
+	}
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	key, err := utils.SecretsKey()
+	if err != nil {
+		return "", err
+	}
+
+	token, err := utils.OpenEnvelope(key, credential.Ciphertext, credential.EncryptedKey, types.RegistryCredentialAdditionalData(*credential.UserId, *credential.Host))
+	if err != nil {
+		return "", fmt.Errorf("decrypting registry credential for %s: %w", host, err)
+	}
+
+	return services.EncodeRegistryAuth(*credential.Username, string(token), host), nil
+}
@@ -0,0 +1,49 @@
+package queue
+
+import (
+	"context"
+	"log"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// RestartDeploymentTask restarts every container of a deployment and waits for them to become healthy before marking it READY.
+type RestartDeploymentTask struct {
+	Db             database.Repository       `json:"-"`
+	Docker         services.ContainerRuntime `json:"-"`
+	DeploymentUUID string                    `json:"deploymentUUID"`
+}
+
+func (task RestartDeploymentTask) Type() string {
+	return RESTART_DEPLOYMENT_TASK
+}
+
+func (task RestartDeploymentTask) DeploymentID() string {
+	return task.DeploymentUUID
+}
+
+func (task RestartDeploymentTask) Process() error {
+	log.Println("ADDED DEPLOYMENT RESTART TASK TO QUEUE")
+
+	ctx := context.Background()
+
+	deployment, skip, err := canTransition(ctx, task.Db, task.DeploymentUUID, types.DEPLOYMENT_STATUS_READY)
+	if skip || err != nil {
+		return err
+	}
+
+	if err := applyToInstances(ctx, task.Db, task.Docker, deployment, task.Docker.RestartContainer, true); err != nil {
+		log.Printf("error restarting container: %s\n", err.Error())
+		settleStatus(ctx, task.Db, task.Docker, task.DeploymentUUID, err)
+		return err
+	}
+
+	if _, err := task.Db.UpdateDeploymentStatus(ctx, task.DeploymentUUID, types.DEPLOYMENT_STATUS_READY, nil); err != nil {
+		log.Printf("error updating deployment status: %s\n", err.Error())
+		return err
+	}
+
+	return nil
+}
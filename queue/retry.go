@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter is the fraction of the backoff that is randomised, e.g. 0.2 spreads retries over ±20%
+	Jitter float64
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 2 * time.Second,
+	MaxBackoff:     5 * time.Minute,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// RetryableTask is implemented by tasks that need a retry policy other than the dispatcher default.
+type RetryableTask interface {
+	RetryPolicy() RetryPolicy
+}
+
+// PermanentError marks a failure that retrying won't fix, the dispatchers dead-letter the task right away.
+type PermanentError struct {
+	Err error
+}
+
+func (e PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Backoff returns how long to wait before the next attempt, given how many attempts have been made.
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempts-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(backoff)
+}
+
+func isPermanent(err error) bool {
+	var permanent PermanentError
+	return errors.As(err, &permanent)
+}
+
+func retryPolicyFor(task Task, fallback RetryPolicy) RetryPolicy {
+	if retryable, ok := task.(RetryableTask); ok {
+		return retryable.RetryPolicy()
+	}
+	return fallback
+}
+
+// runTask calls Process and turns a panic into an error so that one bad task cannot take down a worker.
+func runTask(task Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+
+	return task.Process()
+}
@@ -0,0 +1,171 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// ScaleDeploymentTask brings the number of containers of a deployment to Replicas, starting
+// the missing replicas and removing the extra ones. Replicas whose container is gone are replaced.
+type ScaleDeploymentTask struct {
+	Db             database.Repository       `json:"-"`
+	Docker         services.ContainerRuntime `json:"-"`
+	DeploymentUUID string                    `json:"deploymentUUID"`
+	Replicas       int                       `json:"replicas"`
+	// Token is the token of the pending update the task was queued for
+	Token string `json:"token,omitempty"`
+}
+
+func (task ScaleDeploymentTask) Type() string {
+	return SCALE_DEPLOYMENT_TASK
+}
+
+func (task ScaleDeploymentTask) DeploymentID() string {
+	return task.DeploymentUUID
+}
+
+func (task ScaleDeploymentTask) Coalescable() bool {
+	return true
+}
+
+func (task ScaleDeploymentTask) Process() error {
+	log.Println("ADDED DEPLOYMENT SCALE TASK TO QUEUE")
+
+	ctx := context.Background()
+
+	if skip, err := beginTransition(ctx, task.Db, task.DeploymentUUID, types.DEPLOYMENT_STATUS_UPDATING); skip || err != nil {
+		return err
+	}
+
+	if err := task.scale(ctx); err != nil {
+		settleUpdate(ctx, task.Db, task.Docker, task.DeploymentUUID, task.Token, err)
+		return err
+	}
+
+	return nil
+}
+
+func (task ScaleDeploymentTask) scale(ctx context.Context) error {
+	deployment, err := task.Db.GetDeployment(ctx, task.DeploymentUUID)
+	if err != nil {
+		log.Printf("error fetching deployment row: %s\n", err.Error())
+		return err
+	}
+
+	instances, err := task.Db.GetDeploymentInstances(ctx, task.DeploymentUUID)
+	if err != nil {
+		log.Printf("error fetching deployment instances: %s\n", err.Error())
+		return err
+	}
+
+	containerIds := make(map[int]string, task.Replicas)
+	current := make(map[string]bool, len(instances))
+	extra := make(map[int]string)
+	for _, instance := range instances {
+		current[*instance.ContainerId] = true
+
+		if *instance.Replica >= task.Replicas {
+			extra[*instance.Replica] = *instance.ContainerId
+			continue
+		}
+
+		_, err := task.Docker.InspectContainer(ctx, *instance.ContainerId)
+		switch {
+		case err == nil:
+			containerIds[*instance.Replica] = *instance.ContainerId
+		case !errors.Is(err, services.ErrContainerNotFound):
+			log.Printf("error inspecting container: %s\n", err.Error())
+			return err
+		}
+	}
+
+	missing := []int{}
+	for _, replica := range replicaRange(0, task.Replicas) {
+		if _, exists := containerIds[replica]; !exists {
+			missing = append(missing, replica)
+		}
+	}
+
+	if len(missing) > 0 {
+		env, secrets, err := task.env(ctx, deployment)
+		if err != nil {
+			log.Printf("error fetching deployment env: %s\n", err.Error())
+			return err
+		}
+
+		// Credentials sent with the create or update request were stored as the credential of the image's registry
+		auth, err := registryAuth(ctx, task.Db, task.DeploymentUUID, *deployment.ImageTag, nil, "")
+		if err != nil {
+			log.Printf("error fetching registry credentials: %s\n", err.Error())
+			return err
+		}
+
+		domains, err := verifiedDomains(ctx, task.Db, task.DeploymentUUID)
+		if err != nil {
+			log.Printf("error fetching deployment domains: %s\n", err.Error())
+			return err
+		}
+
+		ports, err := task.Db.GetDeploymentPorts(ctx, task.DeploymentUUID)
+		if err != nil {
+			log.Printf("error fetching deployment ports: %s\n", err.Error())
+			return err
+		}
+
+		spec := services.ContainerSpec{DeploymentUUID: task.DeploymentUUID, ServiceName: *deployment.Subdomain, Image: *deployment.ImageTag, Env: env, Secrets: secrets, RegistryAuth: auth, Port: *deployment.Port, Resources: deployment.ResourceSpec, HealthCheck: deployment.HealthCheck, Routing: deployment.Routing, Middlewares: deployment.Middlewares, Domains: domains, Ports: ports}
+		provisioned, err := provisionReplicas(ctx, task.Docker, spec, missing, "", current)
+		if err != nil {
+			log.Printf("error provisioning container: %s\n", err.Error())
+			return err
+		}
+
+		if err := waitForReplicas(ctx, task.Docker, spec, provisioned); err != nil {
+			log.Printf("error waiting for container: %s\n", err.Error())
+			removeContainers(ctx, task.Docker, provisioned)
+			return err
+		}
+
+		for replica, containerId := range provisioned {
+			containerIds[replica] = containerId
+		}
+	}
+
+	if _, err := task.Db.SetDeploymentInstances(ctx, task.DeploymentUUID, containerIds); err != nil {
+		log.Printf("error updating deployment instances: %s\n", err.Error())
+		return err
+	}
+
+	// Extra replicas are removed after they are dropped from the instances and the ingress, whatever fails is left
+	// to the garbage collector
+	if len(extra) > 0 {
+		syncIngress(ctx, task.Db, task.Docker)
+		removeContainers(ctx, task.Docker, extra)
+	}
+
+	if _, err := task.Db.UpdateDeployment(ctx, types.DeploymentAttributes{UUID: task.DeploymentUUID, ImageTag: *deployment.ImageTag, Subdomain: *deployment.Subdomain, Port: deployment.Port, Replicas: task.Replicas, Status: types.DEPLOYMENT_STATUS_UPDATING, ResourceSpec: deployment.ResourceSpec, HealthCheck: deployment.HealthCheck, Routing: deployment.Routing, Middlewares: deployment.Middlewares, EnvConfig: deployment.EnvConfig}); err != nil {
+		log.Printf("error updating deployment row: %s\n", err.Error())
+		return err
+	}
+
+	if _, err := task.Db.SettleDeploymentUpdate(ctx, task.DeploymentUUID, task.Token, types.DEPLOYMENT_STATUS_READY, nil); err != nil {
+		log.Printf("error updating deployment status: %s\n", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// env returns the environment new replicas are started with.
+func (task ScaleDeploymentTask) env(ctx context.Context, deployment types.Deployment) ([]string, map[string][]byte, error) {
+	envConfig, err := DeploymentEnvConfig(ctx, task.Db, deployment)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resolveSecrets(ctx, task.Db, task.DeploymentUUID, envConfig.Array())
+}
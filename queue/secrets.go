@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+)
+
+// resolveSecrets decrypts the secrets of the deployment's owner that ${secret:NAME} values in env refer to. Their values
+// are kept out of the env, which docker inspect shows to anyone with access to the host: a variable VAR referring to
+// NAME is replaced by VAR_FILE, the path of the file NAME is written to in the container.
+// It runs right before containers are created, the resolved values are never stored.
+func resolveSecrets(ctx context.Context, db database.Repository, deploymentUUID string, envArray []string) ([]string, map[string][]byte, error) {
+	env := types.ParseEnv(envArray)
+	if len(env.SecretReferences()) == 0 {
+		return envArray, nil, nil
+	}
+
+	secrets, err := db.GetSecretsForDeployment(ctx, deploymentUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secretsByName := make(map[string]types.Secret, len(secrets))
+	for _, secret := range secrets {
+		secretsByName[*secret.Name] = secret
+	}
+
+	key, err := utils.SecretsKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resolved := types.EnvConfig{}
+	files := make(map[string][]byte)
+	for variable, value := range env {
+		name, ok := types.SecretReference(value)
+		if !ok {
+			resolved[variable] = value
+			continue
+		}
+
+		secret, exists := secretsByName[name]
+		if !exists {
+			return nil, nil, fmt.Errorf("secret %s referenced by %s does not exist", name, variable)
+		}
+
+		plaintext, err := utils.OpenEnvelope(key, secret.Ciphertext, secret.EncryptedKey, types.SecretAdditionalData(*secret.UserId, name))
+		if err != nil {
+			return nil, nil, fmt.Errorf("decrypting secret %s: %w", name, err)
+		}
+
+		resolved[variable+"_FILE"] = types.SecretFilePath(name)
+		files[name] = plaintext
+	}
+
+	return resolved.Array(), files, nil
+}
+
+// DeploymentEnvConfig returns the stored env config of a deployment. Deployments created before the env config was
+// stored fall back to their last revision, or to only their PORT without one. The env of their containers isn't used,
+// it also holds the variables of the image, e.g. PATH, which would then be pinned in the deployment's config.
+func DeploymentEnvConfig(ctx context.Context, db database.Repository, deployment types.Deployment) (types.EnvConfig, error) {
+	if deployment.EnvConfig != nil {
+		return deployment.EnvConfig, nil
+	}
+
+	revisions, err := db.GetDeploymentRevisions(ctx, *deployment.UUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(revisions) > 0 && revisions[0].EnvConfig != nil {
+		return revisions[0].EnvConfig, nil
+	}
+
+	return types.EnvConfig{"PORT": fmt.Sprintf("%d", *deployment.Port)}, nil
+}
+
+// DesiredConfig returns the configuration the deployment runs once its queued updates are done, with the env config
+// filled in like DeploymentEnvConfig does. A new update builds on it and gets a fresh token.
+func DesiredConfig(ctx context.Context, db database.Repository, deployment types.Deployment) (types.DeploymentConfig, error) {
+	config := deployment.Config()
+	if config.EnvConfig == nil {
+		envConfig, err := DeploymentEnvConfig(ctx, db, deployment)
+		if err != nil {
+			return types.DeploymentConfig{}, err
+		}
+		config.EnvConfig = envConfig
+	}
+
+	token, err := utils.GenerateUUID()
+	if err != nil {
+		return types.DeploymentConfig{}, err
+	}
+	config.Token = token
+
+	return config, nil
+}
@@ -0,0 +1,49 @@
+package queue
+
+import (
+	"context"
+	"log"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// StartDeploymentTask starts every container of a stopped deployment and waits for them to become healthy before marking it READY.
+type StartDeploymentTask struct {
+	Db             database.Repository       `json:"-"`
+	Docker         services.ContainerRuntime `json:"-"`
+	DeploymentUUID string                    `json:"deploymentUUID"`
+}
+
+func (task StartDeploymentTask) Type() string {
+	return START_DEPLOYMENT_TASK
+}
+
+func (task StartDeploymentTask) DeploymentID() string {
+	return task.DeploymentUUID
+}
+
+func (task StartDeploymentTask) Process() error {
+	log.Println("ADDED DEPLOYMENT START TASK TO QUEUE")
+
+	ctx := context.Background()
+
+	deployment, skip, err := canTransition(ctx, task.Db, task.DeploymentUUID, types.DEPLOYMENT_STATUS_READY)
+	if skip || err != nil {
+		return err
+	}
+
+	if err := applyToInstances(ctx, task.Db, task.Docker, deployment, task.Docker.StartContainer, true); err != nil {
+		log.Printf("error starting container: %s\n", err.Error())
+		settleStatus(ctx, task.Db, task.Docker, task.DeploymentUUID, err)
+		return err
+	}
+
+	if _, err := task.Db.UpdateDeploymentStatus(ctx, task.DeploymentUUID, types.DEPLOYMENT_STATUS_READY, nil); err != nil {
+		log.Printf("error updating deployment status: %s\n", err.Error())
+		return err
+	}
+
+	return nil
+}
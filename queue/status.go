@@ -0,0 +1,79 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// beginTransition moves a deployment to the status of the task about to run. When the deployment can't
+// make that transition, e.g. because it is being deleted, the task has nothing left to do and skip is set.
+func beginTransition(ctx context.Context, db database.Repository, deploymentUUID string, status string) (skip bool, err error) {
+	if _, err := db.UpdateDeploymentStatus(ctx, deploymentUUID, status, nil); err != nil {
+		var transitionErr types.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			log.Printf("skipping task of deployment %s: %s\n", deploymentUUID, err.Error())
+			return true, nil
+		}
+
+		log.Printf("error updating deployment status: %s\n", err.Error())
+		return false, err
+	}
+
+	return false, nil
+}
+
+// canTransition fetches the deployment and checks that it can move to status before a task starts working on it.
+// Like beginTransition, skip is set when the task has nothing left to do.
+func canTransition(ctx context.Context, db database.Repository, deploymentUUID string, status string) (deployment types.Deployment, skip bool, err error) {
+	deployment, err = db.GetDeployment(ctx, deploymentUUID)
+	if err != nil {
+		log.Printf("error fetching deployment row: %s\n", err.Error())
+		return deployment, false, err
+	}
+
+	if err := types.CheckTransition(*deployment.Status, status); err != nil {
+		log.Printf("skipping task of deployment %s: %s\n", deploymentUUID, err.Error())
+		return deployment, true, nil
+	}
+
+	return deployment, false, nil
+}
+
+// settleStatus records why a task failed. The deployment goes back to READY when all of its containers
+// are still running, e.g. after an update was rolled back, and to FAILED otherwise.
+func settleStatus(ctx context.Context, db database.Repository, docker services.ContainerRuntime, deploymentUUID string, cause error) {
+	reason := cause.Error()
+	if _, err := db.UpdateDeploymentStatus(ctx, deploymentUUID, settledStatus(ctx, db, docker, deploymentUUID), &reason); err != nil {
+		log.Printf("error updating deployment status: %s\n", err.Error())
+	}
+}
+
+// settleUpdate is settleStatus for the update queued with token. A deployment with a newer update queued stays
+// UPDATING for it.
+func settleUpdate(ctx context.Context, db database.Repository, docker services.ContainerRuntime, deploymentUUID string, token string, cause error) {
+	reason := cause.Error()
+	if _, err := db.SettleDeploymentUpdate(ctx, deploymentUUID, token, settledStatus(ctx, db, docker, deploymentUUID), &reason); err != nil {
+		log.Printf("error updating deployment status: %s\n", err.Error())
+	}
+}
+
+func settledStatus(ctx context.Context, db database.Repository, docker services.ContainerRuntime, deploymentUUID string) string {
+	instances, err := db.GetDeploymentInstances(ctx, deploymentUUID)
+	if err != nil || len(instances) == 0 {
+		return types.DEPLOYMENT_STATUS_FAILED
+	}
+
+	for _, instance := range instances {
+		cont, err := docker.InspectContainer(ctx, *instance.ContainerId)
+		if err != nil || cont.State != "running" || cont.Health == "unhealthy" {
+			return types.DEPLOYMENT_STATUS_FAILED
+		}
+	}
+
+	return types.DEPLOYMENT_STATUS_READY
+}
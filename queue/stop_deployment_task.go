@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"context"
+	"log"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/services"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// StopDeploymentTask stops every container of a deployment, keeping them and the deployment row around so it can be started again.
+type StopDeploymentTask struct {
+	Db             database.Repository       `json:"-"`
+	Docker         services.ContainerRuntime `json:"-"`
+	DeploymentUUID string                    `json:"deploymentUUID"`
+}
+
+func (task StopDeploymentTask) Type() string {
+	return STOP_DEPLOYMENT_TASK
+}
+
+func (task StopDeploymentTask) DeploymentID() string {
+	return task.DeploymentUUID
+}
+
+func (task StopDeploymentTask) Process() error {
+	log.Println("ADDED DEPLOYMENT STOP TASK TO QUEUE")
+
+	ctx := context.Background()
+
+	// The deployment is STOPPED before any container is, so that the reconciler never finds a stopped container
+	// of a READY deployment and starts it again. A retry finds it STOPPED and stops whatever is left.
+	if skip, err := beginTransition(ctx, task.Db, task.DeploymentUUID, types.DEPLOYMENT_STATUS_STOPPED); skip || err != nil {
+		return err
+	}
+
+	deployment, err := task.Db.GetDeployment(ctx, task.DeploymentUUID)
+	if err != nil {
+		log.Printf("error fetching deployment row: %s\n", err.Error())
+		return err
+	}
+
+	if err := applyToInstances(ctx, task.Db, task.Docker, deployment, task.Docker.StopContainer, false); err != nil {
+		log.Printf("error stopping container: %s\n", err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package queue
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+)
+
+func TestCreateFailureSettlesStatus(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+
+	user, err := db.CreateUser(ctx, types.CreateUserRequest{Username: "web", ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := 8080
+	deployment, err := db.CreateDeployment(ctx, types.DeploymentAttributes{UserUUID: *user.UUID, Subdomain: "web", ImageTag: "nginx", Status: types.DEPLOYMENT_STATUS_PENDING, Port: &port, Replicas: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := CreateDeploymentTask{Db: db, Docker: docker, DeploymentUUID: *deployment.UUID, ImageTag: "nginx", Subdomain: "web", EnvArray: []string{"PORT=8080"}, ContainerPort: port, Replicas: 2}
+
+	docker.FailProvisioning(errors.New("pull access denied"))
+	if err := task.Process(); err == nil {
+		t.Fatal("expected the create to fail")
+	}
+
+	deployment, err = db.GetDeployment(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Status != types.DEPLOYMENT_STATUS_FAILED || deployment.StatusReason == nil || *deployment.StatusReason != "pull access denied" {
+		t.Fatalf("expected the deployment to have FAILED with the provisioning error, got %s", *deployment.Status)
+	}
+
+	// The retry by the dispatcher succeeds once the image can be pulled
+	docker.FailProvisioning(nil)
+	if err := task.Process(); err != nil {
+		t.Fatalf("expected the retry to succeed, got %s", err)
+	}
+
+	deployment, err = db.GetDeployment(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Status != types.DEPLOYMENT_STATUS_READY || len(instanceContainers(t, db, *deployment.UUID)) != 2 {
+		t.Errorf("expected a READY deployment with 2 instances, got %s", *deployment.Status)
+	}
+}
+
+func TestUpdateReplacesContainers(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+
+	deployment := createDeployment(t, db, docker, "web", 2)
+	old := instanceContainers(t, db, *deployment.UUID)
+
+	update := deployment.Config()
+	update.Token = "update"
+	update.ImageTag = "nginx:2"
+	usage := types.ResourceUsage(deployment.ResourceSpec, 2)
+	if _, err := db.ReserveDeploymentUpdate(ctx, *deployment.UUID, update, &usage); err != nil {
+		t.Fatal(err)
+	}
+
+	task := UpdateDeploymentTask{Db: db, Docker: docker, DeploymentUUID: *deployment.UUID, ImageTag: "nginx:2", Subdomain: "web", EnvArray: []string{"PORT=8080"}, ContainerPort: 8080, Replicas: 2, Token: update.Token}
+	if err := task.Process(); err != nil {
+		t.Fatal(err)
+	}
+
+	deployment, err := db.GetDeployment(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Status != types.DEPLOYMENT_STATUS_READY || *deployment.ImageTag != "nginx:2" || deployment.ReservedMemory != nil {
+		t.Errorf("expected a READY deployment of nginx:2 without a reservation, got %s of %s", *deployment.Status, *deployment.ImageTag)
+	}
+
+	for replica, containerId := range instanceContainers(t, db, *deployment.UUID) {
+		if containerId == old[replica] {
+			t.Errorf("expected replica %d to be replaced", replica)
+		}
+	}
+
+	containers, _ := docker.ListContainers(ctx)
+	names := map[string]string{}
+	for _, cont := range containers {
+		names[cont.Name] = cont.Image
+	}
+	if len(names) != 2 || names["web"] != "nginx:2" || names["web_1"] != "nginx:2" {
+		t.Errorf("expected web and web_1 running nginx:2, got %v", names)
+	}
+}
+
+func TestUpdateFailingHealthCheckKeepsOldContainers(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+
+	deployment := createDeployment(t, db, docker, "web", 1)
+	old := instanceContainers(t, db, *deployment.UUID)
+
+	docker.HealthStatus = "unhealthy"
+	task := UpdateDeploymentTask{Db: db, Docker: docker, DeploymentUUID: *deployment.UUID, ImageTag: "nginx:2", Subdomain: "web", EnvArray: []string{"PORT=8080"}, ContainerPort: 8080, Replicas: 1, HealthCheck: &types.HealthCheck{Type: "CMD", Command: []string{"true"}}}
+
+	var permanent PermanentError
+	if err := task.Process(); !errors.As(err, &permanent) {
+		t.Fatalf("expected a failing health check to be permanent, got %v", err)
+	}
+
+	deployment, err := db.GetDeployment(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Status != types.DEPLOYMENT_STATUS_READY || *deployment.ImageTag != "nginx" {
+		t.Errorf("expected the deployment to stay READY on nginx, got %s on %s", *deployment.Status, *deployment.ImageTag)
+	}
+	if current := instanceContainers(t, db, *deployment.UUID); current[0] != old[0] {
+		t.Errorf("expected the old container to stay current, got %s", current[0])
+	}
+}
+
+func TestScaleDown(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+
+	deployment := createDeployment(t, db, docker, "web", 3)
+
+	if err := (ScaleDeploymentTask{Db: db, Docker: docker, DeploymentUUID: *deployment.UUID, Replicas: 1}).Process(); err != nil {
+		t.Fatal(err)
+	}
+
+	deployment, err := db.GetDeployment(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Status != types.DEPLOYMENT_STATUS_READY || deployment.ReplicaCount() != 1 {
+		t.Errorf("expected a READY deployment of 1 replica, got %s with %d", *deployment.Status, deployment.ReplicaCount())
+	}
+
+	containers, _ := docker.ListContainers(ctx)
+	if len(containers) != 1 || containers[0].Name != "web" {
+		t.Errorf("expected only the web container to be left, got %+v", containers)
+	}
+}
+
+func TestStartWithoutInstancesFails(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+
+	user, err := db.CreateUser(ctx, types.CreateUserRequest{Username: "web", ApiKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port := 8080
+	deployment, err := db.CreateDeployment(ctx, types.DeploymentAttributes{UserUUID: *user.UUID, Subdomain: "web", ImageTag: "nginx", Status: types.DEPLOYMENT_STATUS_FAILED, Port: &port, Replicas: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (StartDeploymentTask{Db: db, Docker: docker, DeploymentUUID: *deployment.UUID}).Process(); !errors.Is(err, ErrNoInstances) {
+		t.Fatalf("expected starting a deployment without containers to fail, got %v", err)
+	}
+
+	deployment, err = db.GetDeployment(ctx, *deployment.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Status != types.DEPLOYMENT_STATUS_FAILED {
+		t.Errorf("expected the deployment to stay FAILED, got %s", *deployment.Status)
+	}
+}
+
+func TestResolveSecretsMovesValuesToFiles(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryDatabase()
+	docker := newFakeRuntime()
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	t.Setenv("SECRETS_KEY", base64.StdEncoding.EncodeToString(key))
+
+	deployment := createDeployment(t, db, docker, "web", 1)
+	user, err := db.GetUserByUsername(ctx, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext, encryptedKey, err := utils.SealEnvelope(key, []byte("hunter2"), types.SecretAdditionalData(*user.ID, "db_password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.CreateSecret(ctx, *user.UUID, "db_password", ciphertext, encryptedKey); err != nil {
+		t.Fatal(err)
+	}
+
+	env, files, err := resolveSecrets(ctx, db, *deployment.UUID, []string{"PORT=8080", "DB_PASSWORD=${secret:db_password}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved := types.ParseEnv(env)
+	if _, exists := resolved["DB_PASSWORD"]; exists || resolved["DB_PASSWORD_FILE"] != "/run/secrets/db_password" || resolved["PORT"] != "8080" {
+		t.Errorf("expected DB_PASSWORD to be replaced by DB_PASSWORD_FILE, got %v", resolved)
+	}
+	if string(files["db_password"]) != "hunter2" {
+		t.Errorf("expected the secret file to hold the decrypted value, got %q", files["db_password"])
+	}
+
+	if _, _, err := resolveSecrets(ctx, db, *deployment.UUID, []string{"API_TOKEN=${secret:api_token}"}); err == nil {
+		t.Error("expected a reference to a missing secret to fail")
+	}
+}
@@ -1,17 +1,49 @@
 package queue
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+const (
+	CREATE_DEPLOYMENT_TASK  string = "CREATE_DEPLOYMENT"
+	UPDATE_DEPLOYMENT_TASK  string = "UPDATE_DEPLOYMENT"
+	DELETE_DEPLOYMENT_TASK  string = "DELETE_DEPLOYMENT"
+	SCALE_DEPLOYMENT_TASK   string = "SCALE_DEPLOYMENT"
+	STOP_DEPLOYMENT_TASK    string = "STOP_DEPLOYMENT"
+	START_DEPLOYMENT_TASK   string = "START_DEPLOYMENT"
+	RESTART_DEPLOYMENT_TASK string = "RESTART_DEPLOYMENT"
+)
 
 type Options struct {
 	MaxWorkers   int
 	MaxQueueSize int
+	PollInterval time.Duration
+	RetryPolicy  RetryPolicy
+	// LeaseDuration is how long a claimed task stays with its worker without a heartbeat, persistent dispatchers only
+	LeaseDuration time.Duration
 }
 
 type Task interface {
+	Type() string
 	Process() error
 }
 
+// DeploymentTask is implemented by tasks that operate on a single deployment.
+type DeploymentTask interface {
+	DeploymentID() string
+}
+
+// CoalescableTask is implemented by the tasks of updates. An update builds on the deployment's pending update, which
+// holds every update queued before it, so a pending task of the same type can be replaced by a newer one instead of
+// running both.
+type CoalescableTask interface {
+	DeploymentTask
+	Coalescable() bool
+}
+
 type Dispatcher interface {
-	Enqueue(task Task) error
+	// Enqueue queues a task and returns its UUID
+	Enqueue(task Task) (string, error)
 	Start(ctx context.Context) error
 }
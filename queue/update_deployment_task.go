@@ -2,6 +2,7 @@ package queue
 
 import (
 	"context"
+	"fmt"
 	"log"
 
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/database"
@@ -9,36 +10,207 @@ import (
 	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
 )
 
+// The new container runs under this suffix until the old one has been removed
+const NEXT_CONTAINER_SUFFIX string = "_next"
+
 type UpdateDeploymentTask struct {
-	Db                   *database.Database
-	Docker               *services.DockerService
-	DeploymentAttributes *types.Deployment
-	ImageTag             string
-	Subdomain            string
-	EnvArray             []string
-	ContainerPort        int
-	AuthString           string
+	Db             database.Repository       `json:"-"`
+	Docker         services.ContainerRuntime `json:"-"`
+	DeploymentUUID string                    `json:"deploymentUUID"`
+	ImageTag       string                    `json:"imageTag"`
+	Subdomain      string                    `json:"subdomain"`
+	EnvArray       []string                  `json:"envArray"`
+	ContainerPort  int                       `json:"containerPort"`
+	// RegistryCredential is the uuid of the stored credential the dockerAuth of the request was saved as
+	RegistryCredential string                `json:"registryCredential,omitempty"`
+	Resources          types.ResourceSpec    `json:"resources"`
+	Replicas           int                   `json:"replicas"`
+	HealthCheck        *types.HealthCheck    `json:"healthCheck"`
+	Routing            *types.RoutingSpec    `json:"routing"`
+	Middlewares        *types.MiddlewareSpec `json:"middlewares"`
+	RolledBackFrom     *int                  `json:"rolledBackFrom"`
+	CreatedBy          *string               `json:"createdBy"`
+	// Redeploy re-applies the current configuration, e.g. to route new domains or ports, without recording a revision
+	Redeploy bool `json:"redeploy"`
+	// Token is the token of the pending update the task was queued for
+	Token string `json:"token,omitempty"`
+}
+
+func (task UpdateDeploymentTask) Type() string {
+	return UPDATE_DEPLOYMENT_TASK
+}
+
+func (task UpdateDeploymentTask) DeploymentID() string {
+	return task.DeploymentUUID
+}
+
+func (task UpdateDeploymentTask) Coalescable() bool {
+	return true
 }
 
 func (task UpdateDeploymentTask) Process() error {
 	log.Println("ADDED DEPLOYMENT UPDATE TASK TO QUEUE")
-	log.Printf("%+v\n", task)
 
-	if err := task.Docker.RemoveContainer(context.Background(), *task.DeploymentAttributes.ContainerId); err != nil {
-		log.Printf("error removing container: %s\n", err.Error())
+	ctx := context.Background()
+
+	if skip, err := beginTransition(ctx, task.Db, task.DeploymentUUID, types.DEPLOYMENT_STATUS_UPDATING); skip || err != nil {
+		return err
+	}
+
+	if err := task.update(ctx); err != nil {
+		settleUpdate(ctx, task.Db, task.Docker, task.DeploymentUUID, task.Token, err)
+		return err
+	}
+
+	return nil
+}
+
+func (task UpdateDeploymentTask) update(ctx context.Context) error {
+	instances, err := task.Db.GetDeploymentInstances(ctx, task.DeploymentUUID)
+	if err != nil {
+		log.Printf("error fetching deployment instances: %s\n", err.Error())
+		return err
+	}
+
+	current := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		current[*instance.ContainerId] = true
+	}
+
+	if err := task.restoreNames(ctx, instances, current); err != nil {
+		log.Printf("error renaming container: %s\n", err.Error())
+		return err
+	}
+
+	replicas := task.Replicas
+	if replicas < 1 {
+		replicas = 1
+	}
+
+	env, secrets, err := resolveSecrets(ctx, task.Db, task.DeploymentUUID, task.EnvArray)
+	if err != nil {
+		log.Printf("error resolving secrets: %s\n", err.Error())
+		return err
+	}
+
+	auth, err := registryAuth(ctx, task.Db, task.DeploymentUUID, task.ImageTag, task.CreatedBy, task.RegistryCredential)
+	if err != nil {
+		log.Printf("error fetching registry credentials: %s\n", err.Error())
+		return err
+	}
+
+	domains, err := verifiedDomains(ctx, task.Db, task.DeploymentUUID)
+	if err != nil {
+		log.Printf("error fetching deployment domains: %s\n", err.Error())
+		return err
+	}
+
+	ports, err := task.Db.GetDeploymentPorts(ctx, task.DeploymentUUID)
+	if err != nil {
+		log.Printf("error fetching deployment ports: %s\n", err.Error())
 		return err
 	}
 
-	containerId, err := task.Docker.ProvisionContainer(context.Background(), task.ImageTag, task.Subdomain, task.EnvArray, task.ContainerPort, task.AuthString)
+	// The new containers carry the same router labels as the old ones, so Traefik
+	// load balances across both until the old ones are removed
+	spec := services.ContainerSpec{DeploymentUUID: task.DeploymentUUID, ServiceName: task.Subdomain, Image: task.ImageTag, Env: env, Secrets: secrets, Port: task.ContainerPort, RegistryAuth: auth, Resources: task.Resources, HealthCheck: task.HealthCheck, Routing: task.Routing, Middlewares: task.Middlewares, Domains: domains, Ports: ports}
+	containerIds, err := provisionReplicas(ctx, task.Docker, spec, replicaRange(0, replicas), NEXT_CONTAINER_SUFFIX, current)
 	if err != nil {
 		log.Printf("error provisioning container: %s\n", err.Error())
 		return err
 	}
 
-	if _, err := task.Db.UpdateDeployment(context.Background(), types.DeploymentAttributes{UUID: *task.DeploymentAttributes.UUID, ImageTag: task.ImageTag, Subdomain: task.Subdomain, Port: &task.ContainerPort, ContainerId: &containerId, Status: "READY"}); err != nil {
+	if err := waitForReplicas(ctx, task.Docker, spec, containerIds); err != nil {
+		log.Printf("error waiting for container: %s\n", err.Error())
+		task.rollback(ctx, containerIds, err)
+		return err
+	}
+
+	if _, err := task.Db.SetDeploymentInstances(ctx, task.DeploymentUUID, containerIds); err != nil {
+		log.Printf("error updating deployment instances: %s\n", err.Error())
+		return err
+	}
+
+	// File based proxies only route recorded instances, they move over to the new containers before the old ones go
+	syncIngress(ctx, task.Db, task.Docker)
+
+	// Old containers that fail to be removed are no longer referenced once the instances are replaced,
+	// so the garbage collector picks them up
+	for _, instance := range instances {
+		if err := removeContainer(ctx, task.Docker, *instance.ContainerId); err != nil {
+			log.Printf("error removing container: %s\n", err.Error())
+		}
+	}
+
+	// The names are cosmetic, the containers are tracked by id
+	for replica, containerId := range containerIds {
+		if err := task.Docker.RenameContainer(ctx, containerId, services.InstanceName(task.Subdomain, replica)); err != nil {
+			log.Printf("error renaming container: %s\n", err.Error())
+		}
+	}
+
+	if _, err := task.Db.UpdateDeployment(ctx, types.DeploymentAttributes{UUID: task.DeploymentUUID, ImageTag: task.ImageTag, Subdomain: task.Subdomain, Port: &task.ContainerPort, Replicas: replicas, Status: types.DEPLOYMENT_STATUS_UPDATING, ResourceSpec: task.Resources, HealthCheck: task.HealthCheck, Routing: task.Routing, Middlewares: task.Middlewares, EnvConfig: types.ParseEnv(task.EnvArray)}); err != nil {
 		log.Printf("error updating deployment row: %s\n", err.Error())
 		return err
 	}
 
+	if _, err := task.Db.SettleDeploymentUpdate(ctx, task.DeploymentUUID, task.Token, types.DEPLOYMENT_STATUS_READY, nil); err != nil {
+		log.Printf("error updating deployment status: %s\n", err.Error())
+		return err
+	}
+
+	if task.Redeploy {
+		return nil
+	}
+
+	// The configuration is already applied, a missing revision isn't worth failing the task over
+	if _, err := task.Db.CreateDeploymentRevision(ctx, types.DeploymentRevisionAttributes{DeploymentUUID: task.DeploymentUUID, ImageTag: task.ImageTag, Subdomain: task.Subdomain, Port: task.ContainerPort, EnvConfig: types.ParseEnv(task.EnvArray), ResourceSpec: task.Resources, HealthCheck: task.HealthCheck, Routing: task.Routing, Middlewares: task.Middlewares, RolledBackFrom: task.RolledBackFrom, CreatedBy: task.CreatedBy}); err != nil {
+		log.Printf("error recording deployment revision: %s\n", err.Error())
+	}
+
+	return nil
+}
+
+// restoreNames gives instances an earlier attempt of the task made current, but failed to rename, their plain names,
+// so that the new containers can take the suffixed names again. Old replicas left under the plain names are removed.
+func (task UpdateDeploymentTask) restoreNames(ctx context.Context, instances []types.DeploymentInstance, current map[string]bool) error {
+	containers, err := task.Docker.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+
+	containersByID := make(map[string]services.ContainerSummary, len(containers))
+	containersByName := make(map[string]services.ContainerSummary, len(containers))
+	for _, cont := range containers {
+		containersByID[cont.ID] = cont
+		containersByName[cont.Name] = cont
+	}
+
+	for _, instance := range instances {
+		name := services.InstanceName(task.Subdomain, *instance.Replica)
+		if cont, exists := containersByID[*instance.ContainerId]; !exists || cont.Name != name+NEXT_CONTAINER_SUFFIX {
+			continue
+		}
+
+		if leftover, exists := containersByName[name]; exists && !current[leftover.ID] && ownedBy(leftover, task.DeploymentUUID) {
+			if err := removeContainer(ctx, task.Docker, leftover.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := task.Docker.RenameContainer(ctx, *instance.ContainerId, name); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// rollback removes the new containers, leaving the old ones serving traffic.
+func (task UpdateDeploymentTask) rollback(ctx context.Context, containerIds map[int]string, cause error) {
+	removeContainers(ctx, task.Docker, containerIds)
+
+	if err := task.Db.CreateDeploymentEvent(ctx, task.DeploymentUUID, types.EVENT_UPDATE_ROLLED_BACK, fmt.Sprintf("Update rolled back: %s", cause.Error())); err != nil {
+		log.Printf("error recording deployment event: %s\n", err.Error())
+	}
+}
@@ -1,40 +1,68 @@
 package services
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"time"
 
-	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
+	engineTypes "github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-units"
 )
 
 const (
-	NETWORK_NAME              string = "traefik_default"
-	TRAEFIK_ENTRYPOINT_NAME   string = "websecure"
-	TRAEFIK_CERTRESOLVER_NAME string = "tlsresolver"
+	// Labels set on every container the engine creates, used to tell its containers apart from others on the host
+	LABEL_MANAGED         string = "container_provisioning_engine.managed"
+	LABEL_DEPLOYMENT_UUID string = "container_provisioning_engine.deployment_uuid"
+	LABEL_REPLICA         string = "container_provisioning_engine.replica"
+
+	CONTAINER_POLL_INTERVAL time.Duration = time.Millisecond * 500
 )
 
 type DockerService struct {
-	client *client.Client
+	client  *client.Client
+	ingress IngressProvider
+	// network the containers are attached to, the proxy has to be on it too
+	network string
 }
 
-func NewDockerService() (*DockerService, error) {
+var _ ContainerRuntime = (*DockerService)(nil)
+
+func NewDockerService(ingress IngressProvider, network string) (*DockerService, error) {
 	client, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
 		return &DockerService{}, err
 	}
 
+	if network == "" {
+		network = DEFAULT_INGRESS_NETWORK
+	}
+
 	return &DockerService{
-		client: client,
+		client:  client,
+		ingress: ingress,
+		network: network,
 	}, nil
 }
 
+func (d *DockerService) Ingress() IngressProvider {
+	return d.ingress
+}
+
 func (d *DockerService) Ping(ctx context.Context) error {
 	_, err := d.client.Ping(ctx)
 	if err != nil {
@@ -43,63 +71,145 @@ func (d *DockerService) Ping(ctx context.Context) error {
 	return nil
 }
 
-func (d *DockerService) ProvisionContainer(ctx context.Context, image string, serviceName string, envConfig []string, port int, authSting string) (string, error) {
-	reader, err := d.client.ImagePull(ctx, image, types.ImagePullOptions{RegistryAuth: authSting})
+func (d *DockerService) ProvisionContainer(ctx context.Context, spec ContainerSpec) (string, error) {
+	reader, err := d.client.ImagePull(ctx, spec.Image, types.ImagePullOptions{RegistryAuth: spec.RegistryAuth})
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
 	defer reader.Close()
 
 	io.Copy(os.Stdout, reader)
 
-	serviceHostname := fmt.Sprintf("%s.%s", serviceName, config.DEFAULT_HOSTNAME)
-
-	traefikLabels := map[string]string{
-		"traefik.enable": "true",
-		fmt.Sprintf("traefik.http.routers.%s.entrypoints", serviceName):               TRAEFIK_ENTRYPOINT_NAME,
-		fmt.Sprintf("traefik.http.routers.%s.tls.certresolver", serviceName):          TRAEFIK_CERTRESOLVER_NAME,
-		fmt.Sprintf("traefik.http.routers.%s.rule", serviceName):                      fmt.Sprintf("Host(`%s`)", serviceHostname),
-		fmt.Sprintf("traefik.http.services.%s.loadbalancer.server.port", serviceName): fmt.Sprintf("%d", port),
-	}
+	serviceHostname := spec.Hostname()
 
 	cont, err := d.client.ContainerCreate(
 		ctx,
 		&container.Config{
-			Image:    image,
-			Labels:   traefikLabels,
-			Hostname: serviceHostname,
-			Env:      envConfig,
+			Image:       spec.Image,
+			Labels:      utils.MergeMaps(spec.Labels(), d.ingress.Labels(spec)),
+			Hostname:    serviceHostname,
+			Env:         spec.Env,
+			Healthcheck: containerHealthcheck(spec),
 		},
-		&container.HostConfig{}, &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{NETWORK_NAME: {NetworkID: NETWORK_NAME}}}, nil, serviceName)
+		&container.HostConfig{Resources: containerResources(spec.Resources)}, &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{d.network: {NetworkID: d.network}}}, nil, spec.Name())
 	if err != nil {
-		panic(err)
+		return "", err
+	}
+
+	if len(spec.Secrets) > 0 {
+		archive, err := secretsArchive(spec.Secrets)
+		if err == nil {
+			err = d.client.CopyToContainer(ctx, cont.ID, "/", archive, types.CopyToContainerOptions{})
+		}
+		if err != nil {
+			d.client.ContainerRemove(ctx, cont.ID, types.ContainerRemoveOptions{RemoveVolumes: true, Force: true})
+			return "", fmt.Errorf("writing secrets: %w", err)
+		}
 	}
 
 	if err := d.client.ContainerStart(ctx, cont.ID, types.ContainerStartOptions{}); err != nil {
-		panic(err)
+		// Remove the created container so that a retry does not conflict on the container name
+		d.client.ContainerRemove(ctx, cont.ID, types.ContainerRemoveOptions{RemoveVolumes: true, Force: true})
+		return "", err
 	}
 	fmt.Printf("Container ID %s: started\n", cont.ID)
-	fmt.Printf("Service running on: https://%s\n", serviceHostname)
+	fmt.Printf("Service running on: https://%s\n", spec.RoutingHostname())
 
 	return cont.ID, nil
 }
 
+// secretsArchive returns a tar archive of the secrets laid out relative to /. The files are readable by every user,
+// images often don't run as root.
+func secretsArchive(secrets map[string][]byte) (io.Reader, error) {
+	buf := &bytes.Buffer{}
+	archive := tar.NewWriter(buf)
+
+	dir := strings.TrimPrefix(engineTypes.SECRETS_DIR, "/")
+	if err := archive.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0755}); err != nil {
+		return nil, err
+	}
+
+	for name, value := range secrets {
+		if err := archive.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: strings.TrimPrefix(engineTypes.SecretFilePath(name), "/"), Mode: 0444, Size: int64(len(value))}); err != nil {
+			return nil, err
+		}
+		if _, err := archive.Write(value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+func containerResources(resources engineTypes.ResourceSpec) container.Resources {
+	hostResources := container.Resources{PidsLimit: resources.PidsLimit}
+
+	if resources.CPUShares != nil {
+		hostResources.CPUShares = *resources.CPUShares
+	}
+	if resources.CPUQuota != nil {
+		hostResources.CPUQuota = *resources.CPUQuota
+		hostResources.CPUPeriod = engineTypes.DEFAULT_CPU_PERIOD
+	}
+	if resources.CPUPeriod != nil {
+		hostResources.CPUPeriod = *resources.CPUPeriod
+	}
+	if resources.MemoryLimit != nil {
+		hostResources.Memory = *resources.MemoryLimit
+	}
+	if resources.MemoryReservation != nil {
+		hostResources.MemoryReservation = *resources.MemoryReservation
+	}
+
+	for _, ulimit := range resources.Ulimits {
+		hostResources.Ulimits = append(hostResources.Ulimits, &units.Ulimit{Name: ulimit.Name, Soft: ulimit.Soft, Hard: ulimit.Hard})
+	}
+
+	return hostResources
+}
+
+func containerHealthcheck(spec ContainerSpec) *container.HealthConfig {
+	if spec.HealthCheck == nil {
+		return nil
+	}
+
+	return &container.HealthConfig{
+		Test:        spec.HealthCheck.Test(spec.Port),
+		Interval:    time.Duration(spec.HealthCheck.Interval) * time.Second,
+		Timeout:     time.Duration(spec.HealthCheck.Timeout) * time.Second,
+		StartPeriod: time.Duration(spec.HealthCheck.StartPeriod) * time.Second,
+		Retries:     spec.HealthCheck.Retries,
+	}
+}
+
 func (d *DockerService) RemoveContainer(ctx context.Context, containerID string) error {
 	if err := d.client.ContainerStop(ctx, containerID, container.StopOptions{}); err != nil {
-		return err
+		return containerError(err, containerID)
 	}
 
 	if err := d.client.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{
 		RemoveVolumes: true,
 		Force:         true,
 	}); err != nil {
-		return err
+		return containerError(err, containerID)
 	}
 
 	return nil
 }
 
+// containerError wraps ErrContainerNotFound into the error Docker returns for a missing container.
+func containerError(err error, containerID string) error {
+	if client.IsErrNotFound(err) {
+		return fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
+	return err
+}
+
 func (d *DockerService) GetContainerEnv(ctx context.Context, containerId string) (map[string]string, error) {
 	resp, err := d.client.ContainerInspect(ctx, containerId)
 	if err != nil {
@@ -117,3 +227,204 @@ func (d *DockerService) GetContainerEnv(ctx context.Context, containerId string)
 
 	return envMap, nil
 }
+
+func (d *DockerService) StartContainer(ctx context.Context, containerID string) error {
+	return d.client.ContainerStart(ctx, containerID, types.ContainerStartOptions{})
+}
+
+func (d *DockerService) StopContainer(ctx context.Context, containerID string) error {
+	return d.client.ContainerStop(ctx, containerID, container.StopOptions{})
+}
+
+func (d *DockerService) RestartContainer(ctx context.Context, containerID string) error {
+	return d.client.ContainerRestart(ctx, containerID, container.StopOptions{})
+}
+
+func (d *DockerService) RenameContainer(ctx context.Context, containerID string, name string) error {
+	return d.client.ContainerRename(ctx, containerID, name)
+}
+
+func (d *DockerService) InspectContainer(ctx context.Context, containerID string) (ContainerSummary, error) {
+	resp, err := d.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return ContainerSummary{}, containerError(err, containerID)
+	}
+
+	summary := ContainerSummary{
+		ID:   resp.ID,
+		Name: strings.TrimPrefix(resp.Name, "/"),
+	}
+
+	if resp.Config != nil {
+		summary.Image = resp.Config.Image
+		summary.Labels = resp.Config.Labels
+	}
+	if resp.State != nil {
+		summary.State = resp.State.Status
+		if resp.State.Health != nil {
+			summary.Health = resp.State.Health.Status
+		}
+		if started, err := time.Parse(time.RFC3339Nano, resp.State.StartedAt); err == nil {
+			summary.StartedAt = started
+		}
+		if finished, err := time.Parse(time.RFC3339Nano, resp.State.FinishedAt); err == nil {
+			summary.FinishedAt = finished
+		}
+	}
+	if created, err := time.Parse(time.RFC3339Nano, resp.Created); err == nil {
+		summary.CreatedAt = created
+	}
+
+	return summary, nil
+}
+
+// ListContainers returns every container on the host, including stopped ones.
+func (d *DockerService) ListContainers(ctx context.Context) ([]ContainerSummary, error) {
+	containers, err := d.client.ContainerList(ctx, types.ContainerListOptions{All: true})
+	if err != nil {
+		return []ContainerSummary{}, err
+	}
+
+	summaries := make([]ContainerSummary, 0, len(containers))
+	for _, c := range containers {
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+
+		summaries = append(summaries, ContainerSummary{
+			ID:        c.ID,
+			Name:      name,
+			Image:     c.Image,
+			State:     c.State,
+			Health:    healthFromStatus(c.Status),
+			Labels:    c.Labels,
+			CreatedAt: time.Unix(c.Created, 0),
+		})
+	}
+
+	return summaries, nil
+}
+
+// healthFromStatus reads the health of a listed container from its status, e.g. "Up 5 minutes (healthy)",
+// since listing doesn't report it separately.
+func healthFromStatus(status string) string {
+	switch {
+	case strings.HasSuffix(status, "(healthy)"):
+		return "healthy"
+	case strings.HasSuffix(status, "(unhealthy)"):
+		return "unhealthy"
+	case strings.HasSuffix(status, "(health: starting)"):
+		return "starting"
+	default:
+		return ""
+	}
+}
+
+// ListDanglingImages returns untagged images that are not referenced by any tag anymore.
+func (d *DockerService) ListDanglingImages(ctx context.Context) ([]ImageSummary, error) {
+	images, err := d.client.ImageList(ctx, types.ImageListOptions{Filters: filters.NewArgs(filters.Arg("dangling", "true"))})
+	if err != nil {
+		return []ImageSummary{}, err
+	}
+
+	summaries := make([]ImageSummary, 0, len(images))
+	for _, image := range images {
+		repositories := []string{}
+		for _, digest := range image.RepoDigests {
+			repository, _, _ := strings.Cut(digest, "@")
+			repositories = append(repositories, repository)
+		}
+
+		summaries = append(summaries, ImageSummary{
+			ID:           image.ID,
+			Size:         image.Size,
+			Repositories: repositories,
+			CreatedAt:    time.Unix(image.Created, 0),
+		})
+	}
+
+	return summaries, nil
+}
+
+func (d *DockerService) RemoveImage(ctx context.Context, imageID string) error {
+	_, err := d.client.ImageRemove(ctx, imageID, types.ImageRemoveOptions{PruneChildren: true})
+	return err
+}
+
+// StreamContainerLogs copies the logs of a container to stdout and stderr, demultiplexing the two streams.
+// With opts.Follow set it blocks until the container stops or ctx is cancelled.
+func (d *DockerService) StreamContainerLogs(ctx context.Context, containerID string, opts LogOptions, stdout io.Writer, stderr io.Writer) error {
+	reader, err := d.client.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       opts.Tail,
+		Since:      opts.Since,
+		Until:      opts.Until,
+		Timestamps: opts.Timestamps,
+		Follow:     opts.Follow,
+	})
+	if err != nil {
+		return err
+	}
+
+	defer reader.Close()
+
+	_, err = stdcopy.StdCopy(stdout, stderr, reader)
+	if err != nil && ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func (d *DockerService) GetContainerStats(ctx context.Context, containerID string) (ContainerStats, error) {
+	resp, err := d.client.ContainerStats(ctx, containerID, false)
+	if err != nil {
+		return ContainerStats{}, err
+	}
+
+	defer resp.Body.Close()
+
+	var stats types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return ContainerStats{}, err
+	}
+
+	// Same calculation as the docker CLI
+	cpuPercent := 0.0
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	if cpuDelta > 0 && systemDelta > 0 {
+		onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
+		if onlineCPUs == 0 {
+			onlineCPUs = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+		}
+		cpuPercent = (cpuDelta / systemDelta) * onlineCPUs * 100
+	}
+
+	var networkRx, networkTx uint64
+	for _, network := range stats.Networks {
+		networkRx += network.RxBytes
+		networkTx += network.TxBytes
+	}
+
+	return ContainerStats{
+		CPUPercent:  cpuPercent,
+		MemoryUsage: stats.MemoryStats.Usage,
+		MemoryLimit: stats.MemoryStats.Limit,
+		Pids:        stats.PidsStats.Current,
+		NetworkRx:   networkRx,
+		NetworkTx:   networkTx,
+		CollectedAt: stats.Read,
+	}, nil
+}
+
+// EncodeRegistryAuth encodes registry credentials the way ImagePull expects them in RegistryAuth.
+func EncodeRegistryAuth(username string, password string, serverAddress string) string {
+	encodedJSON, err := json.Marshal(registry.AuthConfig{Username: username, Password: password, ServerAddress: serverAddress})
+	if err != nil {
+		panic(err)
+	}
+
+	return base64.URLEncoding.EncodeToString(encodedJSON)
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// DomainResolver looks up the challenges that prove ownership of a custom domain.
+type DomainResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	// FetchHTTPChallenge returns the body served at types.DOMAIN_HTTP_CHALLENGE_PATH + token on hostname
+	FetchHTTPChallenge(ctx context.Context, hostname string, token string) (string, error)
+}
+
+// NetResolver queries DNS and fetches HTTP challenges over the network.
+type NetResolver struct {
+	resolver *net.Resolver
+	client   *http.Client
+}
+
+var _ DomainResolver = (*NetResolver)(nil)
+
+// NewNetResolver uses the system's DNS servers and connects to the domains themselves by default. dnsAddress and
+// httpAddress are host:port addresses that, when set, receive every DNS query and HTTP challenge instead, so that a
+// local DNS server and web server can stand in for the real ones.
+func NewNetResolver(dnsAddress string, httpAddress string) *NetResolver {
+	resolver := &net.Resolver{}
+	if dnsAddress != "" {
+		resolver.PreferGo = true
+		resolver.Dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, dnsAddress)
+		}
+	}
+
+	// The hostname is chosen by the user, so challenges never go through a proxy and never reach an address
+	// inside the network the engine runs in. httpAddress is configured by the operator and is trusted.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: config.DOMAIN_CHALLENGE_TIMEOUT, Control: publicAddressesOnly}).DialContext
+	if httpAddress != "" {
+		transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, httpAddress)
+		}
+	}
+
+	return &NetResolver{
+		resolver: resolver,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.DOMAIN_CHALLENGE_TIMEOUT,
+			// A redirect could lead anywhere, the challenge has to be served on the domain itself
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// publicAddressesOnly refuses connections to loopback, private, link-local and other non-public addresses. It runs
+// after the hostname is resolved, so a DNS record pointing at one of them is refused too.
+func publicAddressesOnly(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("refusing to connect to non-public address %s", host)
+	}
+
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which net.IP.IsPrivate doesn't cover
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func (r *NetResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return r.resolver.LookupTXT(ctx, name)
+}
+
+func (r *NetResolver) FetchHTTPChallenge(ctx context.Context, hostname string, token string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s%s%s", hostname, types.DOMAIN_HTTP_CHALLENGE_PATH, token), nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("challenge returned status %d", resp.StatusCode)
+	}
+
+	// The token is short, anything much larger isn't a challenge response
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+// VerifyDomain checks that the domain's challenge has been published, returning an error that says what's missing when it hasn't.
+func VerifyDomain(ctx context.Context, resolver DomainResolver, domain types.Domain) error {
+	token := *domain.VerificationToken
+
+	switch *domain.VerificationMethod {
+	case types.DOMAIN_VERIFICATION_HTTP:
+		body, err := resolver.FetchHTTPChallenge(ctx, *domain.Hostname, token)
+		if err != nil {
+			return fmt.Errorf("fetching %s: %w", domain.ChallengeURL(), err)
+		}
+
+		if strings.TrimSpace(body) != token {
+			return fmt.Errorf("%s does not serve the verification token", domain.ChallengeURL())
+		}
+	default:
+		records, err := resolver.LookupTXT(ctx, domain.TXTRecordName())
+		if err != nil {
+			return fmt.Errorf("looking up TXT record %s: %w", domain.TXTRecordName(), err)
+		}
+
+		found := false
+		for _, record := range records {
+			if strings.TrimSpace(record) == token {
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("TXT record %s does not contain the verification token", domain.TXTRecordName())
+		}
+	}
+
+	return nil
+}
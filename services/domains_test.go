@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+func testDomain(method string) types.Domain {
+	hostname, token := "app.example.com", "token-123"
+	return types.Domain{Hostname: &hostname, VerificationMethod: &method, VerificationToken: &token}
+}
+
+func TestVerifyDomainTXT(t *testing.T) {
+	domain := testDomain(types.DOMAIN_VERIFICATION_TXT)
+	resolver := NewFakeResolver()
+
+	if err := VerifyDomain(context.Background(), resolver, domain); err == nil {
+		t.Fatal("expected verification to fail without a TXT record")
+	}
+
+	resolver.PublishTXT(domain.TXTRecordName(), "something-else")
+	if err := VerifyDomain(context.Background(), resolver, domain); err == nil {
+		t.Fatal("expected verification to fail with the wrong token")
+	}
+
+	resolver.PublishTXT(domain.TXTRecordName(), " token-123 ")
+	if err := VerifyDomain(context.Background(), resolver, domain); err != nil {
+		t.Fatalf("expected verification to pass, got %s", err)
+	}
+}
+
+func TestVerifyDomainHTTP(t *testing.T) {
+	domain := testDomain(types.DOMAIN_VERIFICATION_HTTP)
+	resolver := NewFakeResolver()
+
+	if err := VerifyDomain(context.Background(), resolver, domain); err == nil {
+		t.Fatal("expected verification to fail without a challenge")
+	}
+
+	resolver.PublishHTTP(*domain.Hostname, *domain.VerificationToken, "wrong")
+	if err := VerifyDomain(context.Background(), resolver, domain); err == nil {
+		t.Fatal("expected verification to fail with the wrong token")
+	}
+
+	// The TXT record isn't enough for an HTTP challenge
+	resolver.PublishTXT(domain.TXTRecordName(), *domain.VerificationToken)
+	resolver.PublishHTTP(*domain.Hostname, *domain.VerificationToken, "token-123\n")
+	if err := VerifyDomain(context.Background(), resolver, domain); err != nil {
+		t.Fatalf("expected verification to pass, got %s", err)
+	}
+}
+
+func TestNetResolverRefusesNonPublicAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("token-123"))
+	}))
+	defer server.Close()
+
+	resolver := NewNetResolver("", "")
+	_, err := resolver.FetchHTTPChallenge(context.Background(), server.Listener.Addr().String(), "token-123")
+	if err == nil || !strings.Contains(err.Error(), "non-public address") {
+		t.Fatalf("expected the loopback address to be refused, got %v", err)
+	}
+
+	for _, address := range []string{"127.0.0.1:80", "10.0.0.1:80", "192.168.1.1:80", "169.254.169.254:80", "100.64.0.1:80", "[::1]:80", "[fe80::1]:80", "[fd00::1]:80", "0.0.0.0:80"} {
+		if err := publicAddressesOnly("tcp", address, nil); err == nil {
+			t.Errorf("expected %s to be refused", address)
+		}
+	}
+
+	if err := publicAddressesOnly("tcp", net.JoinHostPort("93.184.216.34", "80"), nil); err != nil {
+		t.Errorf("expected a public address to be allowed, got %s", err)
+	}
+}
+
+func TestNetResolverDoesNotFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, types.DOMAIN_HTTP_CHALLENGE_PATH) {
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+			return
+		}
+		w.Write([]byte("token-123"))
+	}))
+	defer server.Close()
+
+	// Every request goes to the test server, as with HTTP_CHALLENGE_ADDRESS
+	resolver := NewNetResolver("", server.Listener.Addr().String())
+	_, err := resolver.FetchHTTPChallenge(context.Background(), "app.example.com", "token-123")
+	if err == nil || !strings.Contains(err.Error(), "status 302") {
+		t.Fatalf("expected the redirect not to be followed, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FakeResolver is an in-memory DomainResolver, challenges are published by filling in its maps.
+type FakeResolver struct {
+	mu sync.Mutex
+	// TXT holds the TXT records by name
+	TXT map[string][]string
+	// HTTP holds the body served for each challenge by hostname + token
+	HTTP map[string]string
+}
+
+var _ DomainResolver = (*FakeResolver)(nil)
+
+func NewFakeResolver() *FakeResolver {
+	return &FakeResolver{TXT: make(map[string][]string), HTTP: make(map[string]string)}
+}
+
+func (f *FakeResolver) PublishTXT(name string, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.TXT[name] = append(f.TXT[name], value)
+}
+
+func (f *FakeResolver) PublishHTTP(hostname string, token string, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.HTTP[hostname+token] = body
+}
+
+func (f *FakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records, exists := f.TXT[name]
+	if !exists {
+		return nil, fmt.Errorf("lookup %s: no such host", name)
+	}
+	return records, nil
+}
+
+func (f *FakeResolver) FetchHTTPChallenge(ctx context.Context, hostname string, token string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, exists := f.HTTP[hostname+token]
+	if !exists {
+		return "", fmt.Errorf("challenge returned status 404")
+	}
+	return body, nil
+}
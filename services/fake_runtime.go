@@ -0,0 +1,346 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/utils"
+)
+
+// FakeRuntime is an in-memory ContainerRuntime. Containers are never actually run, they only move
+// between states, which lets the whole create/update/delete flow be exercised without a Docker daemon.
+type FakeRuntime struct {
+	// HealthStatus is reported by containers that have a health check, defaults to healthy
+	HealthStatus string
+	// Uptime is how long containers report to have been running when they are started, so that tests don't
+	// have to sit through the startup grace period or the garbage collector's
+	Uptime time.Duration
+
+	ingress      IngressProvider
+	mu           sync.Mutex
+	provisionErr error
+	containers   map[string]*FakeContainer
+	images       map[string]ImageSummary
+}
+
+type FakeContainer struct {
+	ContainerSummary
+	Spec   ContainerSpec
+	Env    map[string]string
+	Stdout []string
+	Stderr []string
+}
+
+var _ ContainerRuntime = (*FakeRuntime)(nil)
+
+func NewFakeRuntime(ingress IngressProvider) *FakeRuntime {
+	return &FakeRuntime{
+		ingress:    ingress,
+		containers: make(map[string]*FakeContainer),
+		images:     make(map[string]ImageSummary),
+	}
+}
+
+func (f *FakeRuntime) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (f *FakeRuntime) Ingress() IngressProvider {
+	return f.ingress
+}
+
+// FailProvisioning makes every following ProvisionContainer call return err, they succeed again once it's nil.
+// It's safe to call while tasks are running.
+func (f *FakeRuntime) FailProvisioning(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.provisionErr = err
+}
+
+func (f *FakeRuntime) ProvisionContainer(ctx context.Context, spec ContainerSpec) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.provisionErr != nil {
+		return "", f.provisionErr
+	}
+
+	for _, c := range f.containers {
+		if c.Name == spec.Name() {
+			return "", fmt.Errorf("conflict: the container name %q is already in use by container %q", spec.Name(), c.ID)
+		}
+	}
+
+	id, err := fakeContainerID()
+	if err != nil {
+		return "", err
+	}
+
+	health := ""
+	if spec.HealthCheck != nil {
+		health = "healthy"
+		if f.HealthStatus != "" {
+			health = f.HealthStatus
+		}
+	}
+
+	env := make(map[string]string)
+	for _, e := range spec.Env {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+
+	f.containers[id] = &FakeContainer{
+		ContainerSummary: ContainerSummary{
+			ID:        id,
+			Name:      spec.Name(),
+			Image:     spec.Image,
+			State:     "running",
+			Health:    health,
+			Labels:    utils.MergeMaps(spec.Labels(), f.ingress.Labels(spec)),
+			CreatedAt: time.Now().Add(-f.Uptime),
+			StartedAt: time.Now().Add(-f.Uptime),
+		},
+		Spec: spec,
+		Env:  env,
+	}
+
+	return id, nil
+}
+
+func (f *FakeRuntime) RemoveContainer(ctx context.Context, containerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.containers[containerID]; !ok {
+		return fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
+
+	delete(f.containers, containerID)
+	return nil
+}
+
+func (f *FakeRuntime) StartContainer(ctx context.Context, containerID string) error {
+	return f.SetContainerState(containerID, "running")
+}
+
+func (f *FakeRuntime) StopContainer(ctx context.Context, containerID string) error {
+	return f.SetContainerState(containerID, "exited")
+}
+
+func (f *FakeRuntime) RestartContainer(ctx context.Context, containerID string) error {
+	if err := f.SetContainerState(containerID, "exited"); err != nil {
+		return err
+	}
+	return f.SetContainerState(containerID, "running")
+}
+
+func (f *FakeRuntime) RenameContainer(ctx context.Context, containerID string, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.containers[containerID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
+
+	for _, other := range f.containers {
+		if other.ID != containerID && other.Name == name {
+			return fmt.Errorf("conflict: the container name %q is already in use by container %q", name, other.ID)
+		}
+	}
+
+	c.Name = name
+	return nil
+}
+
+func (f *FakeRuntime) InspectContainer(ctx context.Context, containerID string) (ContainerSummary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.containers[containerID]
+	if !ok {
+		return ContainerSummary{}, fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
+
+	return c.ContainerSummary, nil
+}
+
+func (f *FakeRuntime) ListContainers(ctx context.Context) ([]ContainerSummary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	summaries := make([]ContainerSummary, 0, len(f.containers))
+	for _, c := range f.containers {
+		summaries = append(summaries, c.ContainerSummary)
+	}
+
+	return summaries, nil
+}
+
+func (f *FakeRuntime) GetContainerEnv(ctx context.Context, containerID string) (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.containers[containerID]
+	if !ok {
+		return map[string]string{}, fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
+
+	return utils.MergeMaps(c.Env), nil
+}
+
+// StreamContainerLogs writes the lines recorded with AppendLogs. Filtering options are ignored,
+// with opts.Follow set it blocks until ctx is cancelled.
+func (f *FakeRuntime) StreamContainerLogs(ctx context.Context, containerID string, opts LogOptions, stdout io.Writer, stderr io.Writer) error {
+	f.mu.Lock()
+	c, ok := f.containers[containerID]
+	if !ok {
+		f.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
+	stdoutLines := append([]string{}, c.Stdout...)
+	stderrLines := append([]string{}, c.Stderr...)
+	f.mu.Unlock()
+
+	for _, line := range stdoutLines {
+		if _, err := io.WriteString(stdout, line+"\n"); err != nil {
+			return err
+		}
+	}
+	for _, line := range stderrLines {
+		if _, err := io.WriteString(stderr, line+"\n"); err != nil {
+			return err
+		}
+	}
+
+	if opts.Follow {
+		<-ctx.Done()
+	}
+
+	return nil
+}
+
+func (f *FakeRuntime) GetContainerStats(ctx context.Context, containerID string) (ContainerStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.containers[containerID]; !ok {
+		return ContainerStats{}, fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
+
+	return ContainerStats{CollectedAt: time.Now()}, nil
+}
+
+func (f *FakeRuntime) ListDanglingImages(ctx context.Context) ([]ImageSummary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	summaries := make([]ImageSummary, 0, len(f.images))
+	for _, image := range f.images {
+		summaries = append(summaries, image)
+	}
+
+	return summaries, nil
+}
+
+func (f *FakeRuntime) RemoveImage(ctx context.Context, imageID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.images[imageID]; !ok {
+		return fmt.Errorf("No such image: %s", imageID)
+	}
+
+	delete(f.images, imageID)
+	return nil
+}
+
+// Container returns a copy of a container's state.
+func (f *FakeRuntime) Container(containerID string) (FakeContainer, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.containers[containerID]
+	if !ok {
+		return FakeContainer{}, false
+	}
+
+	return *c, true
+}
+
+// SetContainerState simulates a container changing state outside of the engine, e.g. crashing.
+func (f *FakeRuntime) SetContainerState(containerID string, state string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.containers[containerID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
+
+	switch {
+	case state == "running" && c.State != "running":
+		c.StartedAt = time.Now().Add(-f.Uptime)
+	case state != "running" && c.State == "running":
+		c.FinishedAt = time.Now()
+	}
+	c.State = state
+	return nil
+}
+
+// SetContainerHealth simulates the result of a container's healthcheck.
+func (f *FakeRuntime) SetContainerHealth(containerID string, health string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.containers[containerID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
+
+	c.Health = health
+	return nil
+}
+
+func (f *FakeRuntime) AppendLogs(containerID string, stdout []string, stderr []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.containers[containerID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrContainerNotFound, containerID)
+	}
+
+	c.Stdout = append(c.Stdout, stdout...)
+	c.Stderr = append(c.Stderr, stderr...)
+	return nil
+}
+
+func (f *FakeRuntime) AddDanglingImage(image ImageSummary) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.images[image.ID] = image
+}
+
+func fakeContainerID() (string, error) {
+	first, err := utils.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+
+	second, err := utils.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+
+	return first + second, nil
+}
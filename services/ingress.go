@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+)
+
+const (
+	// DEFAULT_INGRESS_NETWORK is the Docker network containers are attached to so the proxy can reach them
+	DEFAULT_INGRESS_NETWORK string = "traefik_default"
+
+	// Formats the FileIngressProvider can write
+	INGRESS_FORMAT_TRAEFIK string = "traefik"
+	INGRESS_FORMAT_CADDY   string = "caddy"
+	INGRESS_FORMAT_NGINX   string = "nginx"
+)
+
+// IngressProvider tells the reverse proxy how to reach deployments. Label based providers describe the routes on
+// the containers themselves, file based ones write the routes of every deployment whenever Sync is called.
+type IngressProvider interface {
+	// Labels returns the routing labels a container of spec is created with
+	Labels(spec ContainerSpec) map[string]string
+	// Sync publishes the routes of every deployment that is serving, providers relying on labels ignore it
+	Sync(ctx context.Context, routes []IngressRoute) error
+}
+
+// IngressRoute is a deployment as file based providers see it. Spec describes it the same way as its containers,
+// Upstreams are the hosts of its serving containers on the ingress network.
+type IngressRoute struct {
+	Spec      ContainerSpec
+	Upstreams []string
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// renderCaddyfile writes the routes as a Caddyfile with a site block per hostname, Caddy provisions the certificates.
+// Requests are matched against the deployments of a hostname in the order Traefik would match them.
+func renderCaddyfile(routes []IngressRoute) ([]byte, []string, error) {
+	// Caddy expands {placeholders} in most values, e.g. {env.*} would read the proxy's environment
+	routes, warnings := leaveOut(INGRESS_FORMAT_CADDY, routes, func(spec ContainerSpec) string {
+		if value, ok := unquotableValue(spec, "\"\\{}"); ok {
+			return fmt.Sprintf("%q can't be written into a Caddyfile", value)
+		}
+		return ""
+	})
+
+	for _, route := range routes {
+		spec := route.Spec
+		features := map[string]bool{"ports": len(spec.Ports) != 0}
+		if spec.Middlewares != nil {
+			features["rateLimit"] = spec.Middlewares.RateLimit != nil
+			features["cors"] = spec.Middlewares.CORS != nil
+			features["redirect"] = spec.Middlewares.Redirect != nil
+		}
+		warnings = append(warnings, unsupportedFeatures(INGRESS_FORMAT_CADDY, spec, features)...)
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated by container_provisioning_engine, changes are overwritten\n")
+
+	hostnames, byHostname := routesByHostname(routes)
+	for _, hostname := range hostnames {
+		// Site addresses are written unquoted, subdomains and custom domains are validated as hostnames. Routes are
+		// matched in the order they're written in, the first handle block that matches serves the request
+		fmt.Fprintf(&b, "\n%s {\n\troute {\n", hostname)
+
+		for _, route := range byHostname[hostname] {
+			spec := route.Spec
+			matcher := ""
+
+			if matchers := caddyMatchers(spec); len(matchers) != 0 {
+				matcher = "@" + spec.ServiceName
+				fmt.Fprintf(&b, "\t\t%s {\n", matcher)
+				for _, m := range matchers {
+					fmt.Fprintf(&b, "\t\t\t%s\n", m)
+				}
+				b.WriteString("\t\t}\n")
+				matcher += " "
+			}
+
+			// route keeps the directives in the order they're written instead of Caddy's default order
+			fmt.Fprintf(&b, "\t\thandle %s{\n\t\t\troute {\n", matcher)
+			for _, directive := range caddyDirectives(route.IngressRoute) {
+				fmt.Fprintf(&b, "\t\t\t\t%s\n", strings.ReplaceAll(directive, "\n", "\n\t\t\t\t"))
+			}
+			b.WriteString("\t\t\t}\n\t\t}\n")
+		}
+
+		b.WriteString("\t}\n}\n")
+	}
+
+	return []byte(b.String()), warnings, nil
+}
+
+func caddyMatchers(spec ContainerSpec) []string {
+	matchers := []string{}
+	if spec.Routing == nil {
+		return matchers
+	}
+
+	// Traefik's PathPrefix matches any path starting with the prefix, not only its sub paths
+	if spec.Routing.PathPrefix != "" {
+		matchers = append(matchers, fmt.Sprintf("path %s", caddyQuote(spec.Routing.PathPrefix+"*")))
+	}
+	for _, name := range sortedKeys(spec.Routing.Headers) {
+		matchers = append(matchers, fmt.Sprintf("header %s %s", caddyQuote(name), caddyQuote(spec.Routing.Headers[name])))
+	}
+
+	return matchers
+}
+
+// caddyDirectives are the middlewares and the proxy of a deployment, in the order of the Traefik middleware chain.
+func caddyDirectives(route IngressRoute) []string {
+	spec := route.Spec
+	directives := []string{}
+
+	if middlewares := spec.Middlewares; middlewares != nil {
+		if allowList := middlewares.IPAllowList; allowList != nil {
+			denied := fmt.Sprintf("@%s_denied", spec.ServiceName)
+			directives = append(directives,
+				fmt.Sprintf("%s not remote_ip %s", denied, strings.Join(allowList.SourceRange, " ")),
+				fmt.Sprintf("respond %s 403", denied),
+			)
+		}
+
+		if basicAuth := middlewares.BasicAuth; basicAuth != nil {
+			realm := ""
+			if basicAuth.Realm != "" {
+				realm = " " + caddyQuote(basicAuth.Realm)
+			}
+
+			users := []string{}
+			for _, user := range basicAuth.Users {
+				users = append(users, fmt.Sprintf("\t%s %s", caddyQuote(user.Username), base64.StdEncoding.EncodeToString([]byte(user.PasswordHash))))
+			}
+			directives = append(directives, fmt.Sprintf("basicauth bcrypt%s {\n%s\n}", realm, strings.Join(users, "\n")))
+		}
+
+		if headers := middlewares.Headers; headers != nil {
+			for _, name := range sortedKeys(headers.Request) {
+				directives = append(directives, caddyHeader("request_header", name, headers.Request[name]))
+			}
+			for _, name := range sortedKeys(headers.Response) {
+				directives = append(directives, caddyHeader("header", name, headers.Response[name]))
+			}
+		}
+	}
+
+	if spec.Routing != nil && spec.Routing.StripPrefix {
+		directives = append(directives, fmt.Sprintf("uri strip_prefix %s", caddyQuote(spec.Routing.PathPrefix)))
+	}
+
+	upstreams := make([]string, 0, len(route.Upstreams))
+	for _, upstream := range route.Upstreams {
+		upstreams = append(upstreams, fmt.Sprintf("%s:%d", upstream, spec.Port))
+	}
+
+	return append(directives, "reverse_proxy "+strings.Join(upstreams, " "))
+}
+
+// caddyHeader sets a header, an empty value removes it like it does in Traefik.
+func caddyHeader(directive string, name string, value string) string {
+	if value == "" {
+		return fmt.Sprintf("%s %s", directive, caddyQuote("-"+name))
+	}
+	return fmt.Sprintf("%s %s %s", directive, caddyQuote(name), caddyQuote(value))
+}
+
+// caddyQuote writes value as a single token, routes with values containing quotes, backslashes or braces are left out.
+func caddyQuote(value string) string {
+	return `"` + value + `"`
+}
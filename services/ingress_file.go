@@ -0,0 +1,192 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// FileIngressProvider writes the routes of every deployment into a dynamic configuration file the proxy watches,
+// so the proxy doesn't need access to the Docker socket. Containers get no routing labels.
+type FileIngressProvider struct {
+	path   string
+	render func(routes []IngressRoute) ([]byte, []string, error)
+
+	mu      sync.Mutex
+	written []byte
+}
+
+var _ IngressProvider = (*FileIngressProvider)(nil)
+
+func NewFileIngressProvider(format string, path string) (*FileIngressProvider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("a path is required for the %s ingress file", format)
+	}
+
+	provider := &FileIngressProvider{path: path}
+
+	switch format {
+	case INGRESS_FORMAT_TRAEFIK, "":
+		provider.render = renderTraefikFile
+	case INGRESS_FORMAT_CADDY:
+		provider.render = renderCaddyfile
+	case INGRESS_FORMAT_NGINX:
+		provider.render = renderNginxConfig
+	default:
+		return nil, fmt.Errorf("unknown ingress file format %q", format)
+	}
+
+	return provider, nil
+}
+
+func (p *FileIngressProvider) Labels(spec ContainerSpec) map[string]string {
+	return map[string]string{}
+}
+
+// Sync renders the routes and replaces the file when the configuration changed. The file is replaced by a rename
+// so the proxy never reads a partially written configuration.
+func (p *FileIngressProvider) Sync(ctx context.Context, routes []IngressRoute) error {
+	routes = append([]IngressRoute{}, routes...)
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Spec.ServiceName < routes[j].Spec.ServiceName })
+
+	config, warnings, err := p.render(routes)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.written != nil && bytes.Equal(config, p.written) {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p.path), filepath.Base(p.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(config); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), p.path); err != nil {
+		return err
+	}
+
+	p.written = config
+
+	// Warnings are only logged when the configuration changes, not on every sync
+	for _, warning := range warnings {
+		log.Printf("ingress: %s\n", warning)
+	}
+
+	return nil
+}
+
+// hostRoute is a deployment on one of the hostnames it is served on.
+type hostRoute struct {
+	IngressRoute
+	Hostname string
+	Priority int
+}
+
+// routesByHostname groups the HTTP routes of the deployments by hostname. Routes of a hostname are ordered the way
+// Traefik matches them, highest priority first.
+func routesByHostname(routes []IngressRoute) ([]string, map[string][]hostRoute) {
+	byHostname := map[string][]hostRoute{}
+	for _, route := range routes {
+		if len(route.Upstreams) == 0 {
+			continue
+		}
+		for _, hostname := range route.Spec.Hostnames() {
+			byHostname[hostname] = append(byHostname[hostname], hostRoute{IngressRoute: route, Hostname: hostname, Priority: route.Spec.httpPriority(hostname)})
+		}
+	}
+
+	for _, hostRoutes := range byHostname {
+		sort.SliceStable(hostRoutes, func(i, j int) bool {
+			if hostRoutes[i].Priority != hostRoutes[j].Priority {
+				return hostRoutes[i].Priority > hostRoutes[j].Priority
+			}
+			return hostRoutes[i].Spec.ServiceName < hostRoutes[j].Spec.ServiceName
+		})
+	}
+
+	return sortedKeys(byHostname), byHostname
+}
+
+// leaveOut removes the routes reason returns a reason for, with a warning for each of them. Deployments a format can't
+// serve as configured are left out rather than served without e.g. their basic auth.
+func leaveOut(format string, routes []IngressRoute, reason func(spec ContainerSpec) string) ([]IngressRoute, []string) {
+	kept := []IngressRoute{}
+	warnings := []string{}
+	for _, route := range routes {
+		if why := reason(route.Spec); why != "" {
+			warnings = append(warnings, fmt.Sprintf("deployment %s is left out of the %s ingress file, %s", route.Spec.ServiceName, format, why))
+			continue
+		}
+		kept = append(kept, route)
+	}
+	return kept, warnings
+}
+
+// unquotableValue returns the first value of the spec written into a configuration file that contains a control
+// character or one of unquotable, the characters a format can't write into a quoted string as they are.
+func unquotableValue(spec ContainerSpec, unquotable string) (string, bool) {
+	values := spec.Hostnames()
+	if spec.Routing != nil {
+		values = append(values, spec.Routing.PathPrefix)
+		for name, value := range spec.Routing.Headers {
+			values = append(values, name, value)
+		}
+	}
+	if middlewares := spec.Middlewares; middlewares != nil {
+		if middlewares.BasicAuth != nil {
+			values = append(values, middlewares.BasicAuth.Realm)
+			for _, user := range middlewares.BasicAuth.Users {
+				values = append(values, user.Username)
+			}
+		}
+		if middlewares.Headers != nil {
+			for _, headers := range []map[string]string{middlewares.Headers.Request, middlewares.Headers.Response} {
+				for name, value := range headers {
+					values = append(values, name, value)
+				}
+			}
+		}
+	}
+
+	sort.Strings(values)
+	for _, value := range values {
+		if strings.ContainsAny(value, unquotable) || strings.IndexFunc(value, unicode.IsControl) != -1 {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// unsupportedFeatures returns a warning for every part of the spec in features that a format can't express.
+func unsupportedFeatures(format string, spec ContainerSpec, features map[string]bool) []string {
+	warnings := []string{}
+	for _, feature := range sortedKeys(features) {
+		if features[feature] {
+			warnings = append(warnings, fmt.Sprintf("%s of deployment %s is not supported by the %s ingress file and is ignored", feature, spec.ServiceName, format))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// renderNginxConfig writes the routes as nginx configuration to be included in the http context, with an upstream
+// per deployment and a server block per hostname. nginx picks the location with the longest matching prefix, which is
+// the order Traefik matches routes in unless a priority is set.
+// @TODO: Serve the hostnames over TLS, certificates have to be provisioned outside of nginx
+func renderNginxConfig(routes []IngressRoute) ([]byte, []string, error) {
+	// nginx expands $variables in quoted strings and has no way to escape them
+	routes, warnings := leaveOut(INGRESS_FORMAT_NGINX, routes, func(spec ContainerSpec) string {
+		switch {
+		case spec.Routing != nil && len(spec.Routing.Headers) != 0:
+			return "locations can't match on headers"
+		case spec.Middlewares != nil && spec.Middlewares.BasicAuth != nil:
+			return "basic auth is not supported"
+		}
+		if value, ok := unquotableValue(spec, "\"\\$"); ok {
+			return fmt.Sprintf("%q can't be written into an nginx configuration", value)
+		}
+		return ""
+	})
+
+	for _, route := range routes {
+		spec := route.Spec
+		features := map[string]bool{"ports": len(spec.Ports) != 0}
+		if spec.Routing != nil {
+			features["priority"] = spec.Routing.Priority != 0
+		}
+		if spec.Middlewares != nil {
+			features["cors"] = spec.Middlewares.CORS != nil
+			features["redirect"] = spec.Middlewares.Redirect != nil
+		}
+		warnings = append(warnings, unsupportedFeatures(INGRESS_FORMAT_NGINX, spec, features)...)
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated by container_provisioning_engine, changes are overwritten\n")
+
+	for _, route := range routes {
+		spec := route.Spec
+		if len(route.Upstreams) == 0 {
+			continue
+		}
+
+		if spec.Middlewares != nil && spec.Middlewares.RateLimit != nil {
+			fmt.Fprintf(&b, "\nlimit_req_zone $binary_remote_addr zone=%s:10m rate=%s;\n", nginxZoneName(spec), nginxRate(spec.Middlewares.RateLimit.Average, spec.Middlewares.RateLimit.Period))
+		}
+
+		fmt.Fprintf(&b, "\nupstream %s {\n", nginxUpstreamName(spec))
+		for _, upstream := range route.Upstreams {
+			fmt.Fprintf(&b, "\tserver %s:%d;\n", upstream, spec.Port)
+		}
+		b.WriteString("}\n")
+	}
+
+	hostnames, byHostname := routesByHostname(routes)
+	for _, hostname := range hostnames {
+		locations := []string{}
+		for _, route := range byHostname[hostname] {
+			location := "/"
+			if route.Spec.Routing != nil && route.Spec.Routing.PathPrefix != "" {
+				location = route.Spec.Routing.PathPrefix
+			}
+
+			var l strings.Builder
+			fmt.Fprintf(&l, "\n\tlocation %s {\n", nginxQuote(location))
+			for _, directive := range nginxDirectives(route.Spec) {
+				fmt.Fprintf(&l, "\t\t%s;\n", directive)
+			}
+			l.WriteString("\t}\n")
+			locations = append(locations, l.String())
+		}
+
+		if len(locations) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\nserver {\n\tlisten 80;\n\tserver_name %s;\n%s}\n", nginxQuote(hostname), strings.Join(locations, ""))
+	}
+
+	return []byte(b.String()), warnings, nil
+}
+
+// nginxDirectives are the middlewares and the proxy of a deployment's location.
+func nginxDirectives(spec ContainerSpec) []string {
+	directives := []string{}
+
+	if middlewares := spec.Middlewares; middlewares != nil {
+		if allowList := middlewares.IPAllowList; allowList != nil {
+			for _, source := range allowList.SourceRange {
+				directives = append(directives, "allow "+source)
+			}
+			directives = append(directives, "deny all")
+		}
+
+		if rateLimit := middlewares.RateLimit; rateLimit != nil {
+			directive := "limit_req zone=" + nginxZoneName(spec)
+			if rateLimit.Burst != 0 {
+				directive += fmt.Sprintf(" burst=%d nodelay", rateLimit.Burst)
+			}
+			directives = append(directives, directive)
+		}
+	}
+
+	// proxy_set_header in a location drops the ones inherited from the server, so the forwarded headers are set here
+	directives = append(directives,
+		"proxy_set_header Host $host",
+		"proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for",
+		"proxy_set_header X-Forwarded-Proto $scheme",
+	)
+
+	if spec.Middlewares != nil && spec.Middlewares.Headers != nil {
+		headers := spec.Middlewares.Headers
+		for _, name := range sortedKeys(headers.Request) {
+			if value := headers.Request[name]; !strings.Contains(value, "$") {
+				directives = append(directives, fmt.Sprintf("proxy_set_header %s %s", name, nginxQuote(value)))
+			}
+		}
+		for _, name := range sortedKeys(headers.Response) {
+			switch value := headers.Response[name]; {
+			case value == "":
+				directives = append(directives, "proxy_hide_header "+name)
+			case !strings.Contains(value, "$"):
+				directives = append(directives, fmt.Sprintf("add_header %s %s always", name, nginxQuote(value)))
+			}
+		}
+	}
+
+	if spec.Routing != nil && spec.Routing.StripPrefix {
+		directives = append(directives, fmt.Sprintf("rewrite ^%s/?(.*)$ /$1 break", regexp.QuoteMeta(spec.Routing.PathPrefix)))
+	}
+
+	return append(directives, "proxy_pass http://"+nginxUpstreamName(spec))
+}
+
+func nginxUpstreamName(spec ContainerSpec) string {
+	return "cpe_" + spec.ServiceName
+}
+
+func nginxZoneName(spec ContainerSpec) string {
+	return MiddlewareName(spec.ServiceName, "ratelimit")
+}
+
+// nginxRate converts average requests per period seconds into a rate, nginx only has per second and per minute rates.
+func nginxRate(average int, period int) string {
+	if period <= 1 {
+		return fmt.Sprintf("%dr/s", average)
+	}
+
+	perMinute := (average*60 + period - 1) / period
+	return fmt.Sprintf("%dr/m", perMinute)
+}
+
+// nginxQuote writes value as a single quoted string, routes with values containing quotes, backslashes or $ are left out.
+func nginxQuote(value string) string {
+	return `"` + value + `"`
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the ingress renderers")
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// goldenRoutes covers a plain deployment, one using most routing, middleware and port options, and two whose
+// middlewares some formats can't express.
+func goldenRoutes() []IngressRoute {
+	middlewares := &types.MiddlewareSpec{
+		IPAllowList: &types.IPAllowListMiddleware{SourceRange: []string{"10.0.0.0/8", "1.2.3.4"}},
+		RateLimit:   &types.RateLimitMiddleware{Average: 100, Period: 1, Burst: 50},
+		CORS:        &types.CORSMiddleware{AllowOrigins: []string{"https://a.com"}, AllowMethods: []string{"GET", "POST"}, AllowHeaders: []string{"X-A"}, AllowCredentials: true, MaxAge: 60},
+		Headers:     &types.HeadersMiddleware{Request: map[string]string{"X-Req": "1"}, Response: map[string]string{"X-Frame-Options": "DENY"}},
+		Redirect:    &types.RedirectMiddleware{Regex: `^https://www\.(.*)`, Replacement: "https://${1}", Permanent: true},
+	}
+	ports := []types.DeploymentPort{
+		{Name: ptr("pg"), Protocol: ptr(types.PORT_PROTOCOL_TCP), ContainerPort: ptr(5432), PublicPort: ptr(30000)},
+		{Name: ptr("pgs"), Protocol: ptr(types.PORT_PROTOCOL_TCP), ContainerPort: ptr(5432), TLS: ptr(types.PORT_TLS_PASSTHROUGH)},
+		{Name: ptr("mq"), Protocol: ptr(types.PORT_PROTOCOL_TCP), ContainerPort: ptr(8883), TLS: ptr(types.PORT_TLS_TERMINATE)},
+		{Name: ptr("dns"), Protocol: ptr(types.PORT_PROTOCOL_UDP), ContainerPort: ptr(53), PublicPort: ptr(30001)},
+	}
+
+	return []IngressRoute{
+		{Spec: ContainerSpec{DeploymentUUID: "u1", ServiceName: "plain", Port: 80}, Upstreams: []string{"aaaaaaaaaaaa"}},
+		{Spec: ContainerSpec{DeploymentUUID: "u2", ServiceName: "full", Port: 8080, Middlewares: middlewares, Routing: &types.RoutingSpec{Subdomain: "shop", PathPrefix: "/api", StripPrefix: true, Priority: 7}, Domains: []string{"a.example.com", "b.example.com"}, Ports: ports}, Upstreams: []string{"bbbbbbbbbbbb", "cccccccccccc"}},
+		{Spec: ContainerSpec{DeploymentUUID: "u3", ServiceName: "limited", Port: 80, Middlewares: &types.MiddlewareSpec{RateLimit: &types.RateLimitMiddleware{Average: 5, Period: 60}}}, Upstreams: []string{"dddddddddddd"}},
+		{Spec: ContainerSpec{DeploymentUUID: "u4", ServiceName: "private", Port: 80, Middlewares: &types.MiddlewareSpec{BasicAuth: &types.BasicAuthMiddleware{Users: []types.BasicAuthUser{{Username: "bob", PasswordHash: "$2a$10$hash"}}, Realm: "team"}}}, Upstreams: []string{"eeeeeeeeeeee"}},
+	}
+}
+
+func TestIngressRenderers(t *testing.T) {
+	renderers := map[string]func([]IngressRoute) ([]byte, []string, error){
+		"traefik.yml": renderTraefikFile,
+		"Caddyfile":   renderCaddyfile,
+		"nginx.conf":  renderNginxConfig,
+	}
+
+	for name, render := range renderers {
+		t.Run(name, func(t *testing.T) {
+			content, skipped, err := render(goldenRoutes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The warnings about unsupported features are appended to the file as comments, so they are covered too
+			for _, warning := range skipped {
+				content = append(content, []byte("# "+warning+"\n")...)
+			}
+
+			golden := filepath.Join("testdata", "ingress", name+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, content, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(content, expected) {
+				t.Errorf("%s doesn't match %s, run go test -update if the change is intended:\n%s", name, golden, content)
+			}
+		})
+	}
+}
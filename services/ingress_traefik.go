@@ -0,0 +1,251 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	TRAEFIK_ENTRYPOINT_NAME   string = "websecure"
+	TRAEFIK_CERTRESOLVER_NAME string = "tlsresolver"
+)
+
+// TraefikLabelProvider routes deployments through Traefik's Docker provider, which reads the labels of the containers.
+type TraefikLabelProvider struct{}
+
+var _ IngressProvider = TraefikLabelProvider{}
+
+func (TraefikLabelProvider) Labels(spec ContainerSpec) map[string]string {
+	labels := map[string]string{"traefik.enable": "true"}
+	spec.traefikConfig().writeLabels(labels)
+	return labels
+}
+
+// Sync is a no-op, Traefik picks up the labels as soon as a container starts.
+func (TraefikLabelProvider) Sync(ctx context.Context, routes []IngressRoute) error {
+	return nil
+}
+
+// traefikConfig is the dynamic configuration of one deployment, written as container labels by TraefikLabelProvider
+// and in Traefik's file format by the FileIngressProvider.
+type traefikConfig struct {
+	Routers     []traefikRouter
+	Middlewares []traefikMiddleware
+	Services    []traefikService
+}
+
+type traefikRouter struct {
+	// Protocol is http, tcp or udp
+	Protocol     string
+	Name         string
+	EntryPoints  []string
+	Rule         string
+	Priority     int
+	Middlewares  []string
+	Service      string
+	CertResolver string
+	Passthrough  bool
+}
+
+// traefikMiddleware options are keyed as in Traefik's file format, labels use the lowercased keys.
+// Values are strings, ints, bools, string lists or string maps.
+type traefikMiddleware struct {
+	Name    string
+	Kind    string
+	Options map[string]interface{}
+}
+
+// traefikService load balances over the containers of a deployment, Port is the port of the containers.
+type traefikService struct {
+	Protocol string
+	Name     string
+	Port     int
+}
+
+func (spec ContainerSpec) traefikConfig() traefikConfig {
+	config := traefikConfig{
+		Services: []traefikService{{Protocol: "http", Name: spec.ServiceName, Port: spec.Port}},
+	}
+
+	config.Middlewares = spec.traefikMiddlewares()
+	chain := make([]string, 0, len(config.Middlewares))
+	for _, middleware := range config.Middlewares {
+		chain = append(chain, middleware.Name)
+	}
+
+	config.Routers = append(config.Routers, spec.traefikRouter(spec.ServiceName, spec.RoutingHostname(), chain))
+
+	// Every custom domain gets its own router so that each one gets its own certificate
+	for i, domain := range spec.Domains {
+		config.Routers = append(config.Routers, spec.traefikRouter(DomainRouterName(spec.ServiceName, i), domain, chain))
+	}
+
+	routers, services := spec.traefikPorts()
+	config.Routers = append(config.Routers, routers...)
+	config.Services = append(config.Services, services...)
+
+	return config
+}
+
+func (spec ContainerSpec) traefikRouter(name string, hostname string, middlewares []string) traefikRouter {
+	router := traefikRouter{
+		Protocol:     "http",
+		Name:         name,
+		EntryPoints:  []string{TRAEFIK_ENTRYPOINT_NAME},
+		Rule:         spec.httpRule(hostname),
+		Middlewares:  middlewares,
+		Service:      spec.ServiceName,
+		CertResolver: TRAEFIK_CERTRESOLVER_NAME,
+	}
+	if spec.Routing != nil {
+		router.Priority = spec.Routing.Priority
+	}
+	return router
+}
+
+// httpRule is the Traefik rule of the deployment on hostname.
+func (spec ContainerSpec) httpRule(hostname string) string {
+	rule := fmt.Sprintf("Host(`%s`)", hostname)
+	if spec.Routing != nil {
+		if matchers := spec.Routing.Matchers(); matchers != "" {
+			rule = fmt.Sprintf("%s && %s", rule, matchers)
+		}
+	}
+	return rule
+}
+
+// httpPriority is the priority Traefik gives the deployment's router on hostname, which defaults to the length of the rule.
+func (spec ContainerSpec) httpPriority(hostname string) int {
+	if spec.Routing != nil && spec.Routing.Priority != 0 {
+		return spec.Routing.Priority
+	}
+	return len(spec.httpRule(hostname))
+}
+
+// DomainRouterName is the Traefik router of the deployment's index-th custom domain.
+func DomainRouterName(serviceName string, index int) string {
+	return fmt.Sprintf("%s_domain_%d", serviceName, index)
+}
+
+func (config traefikConfig) writeLabels(labels map[string]string) {
+	for _, router := range config.Routers {
+		prefix := fmt.Sprintf("traefik.%s.routers.%s.", router.Protocol, router.Name)
+
+		labels[prefix+"entrypoints"] = strings.Join(router.EntryPoints, ",")
+		labels[prefix+"service"] = router.Service
+		if router.Rule != "" {
+			labels[prefix+"rule"] = router.Rule
+		}
+		if router.Priority != 0 {
+			labels[prefix+"priority"] = fmt.Sprintf("%d", router.Priority)
+		}
+		if len(router.Middlewares) != 0 {
+			labels[prefix+"middlewares"] = strings.Join(router.Middlewares, ",")
+		}
+		if router.CertResolver != "" {
+			labels[prefix+"tls.certresolver"] = router.CertResolver
+		}
+		if router.Passthrough {
+			labels[prefix+"tls.passthrough"] = "true"
+		}
+	}
+
+	for _, middleware := range config.Middlewares {
+		prefix := fmt.Sprintf("traefik.http.middlewares.%s.%s.", middleware.Name, strings.ToLower(middleware.Kind))
+
+		for key, value := range middleware.Options {
+			switch value := value.(type) {
+			case map[string]string:
+				for name, v := range value {
+					labels[fmt.Sprintf("%s%s.%s", prefix, strings.ToLower(key), name)] = v
+				}
+			case []string:
+				labels[prefix+strings.ToLower(key)] = strings.Join(value, ",")
+			default:
+				labels[prefix+strings.ToLower(key)] = fmt.Sprintf("%v", value)
+			}
+		}
+	}
+
+	for _, service := range config.Services {
+		labels[fmt.Sprintf("traefik.%s.services.%s.loadbalancer.server.port", service.Protocol, service.Name)] = fmt.Sprintf("%d", service.Port)
+	}
+}
+
+// renderTraefikFile writes the routes in Traefik's file provider format. JSON is valid YAML, so the file can be named
+// either way.
+func renderTraefikFile(routes []IngressRoute) ([]byte, []string, error) {
+	sections := map[string]map[string]map[string]interface{}{}
+	section := func(protocol string, kind string) map[string]interface{} {
+		if sections[protocol] == nil {
+			sections[protocol] = map[string]map[string]interface{}{}
+		}
+		if sections[protocol][kind] == nil {
+			sections[protocol][kind] = map[string]interface{}{}
+		}
+		return sections[protocol][kind]
+	}
+
+	for _, route := range routes {
+		config := route.Spec.traefikConfig()
+
+		for _, router := range config.Routers {
+			definition := map[string]interface{}{"entryPoints": router.EntryPoints, "service": router.Service}
+			if router.Rule != "" {
+				definition["rule"] = router.Rule
+			}
+			if router.Priority != 0 {
+				definition["priority"] = router.Priority
+			}
+			if len(router.Middlewares) != 0 {
+				definition["middlewares"] = router.Middlewares
+			}
+			if router.CertResolver != "" {
+				definition["tls"] = map[string]interface{}{"certResolver": router.CertResolver}
+			}
+			if router.Passthrough {
+				definition["tls"] = map[string]interface{}{"passthrough": true}
+			}
+			section(router.Protocol, "routers")[router.Name] = definition
+		}
+
+		for _, middleware := range config.Middlewares {
+			section("http", "middlewares")[middleware.Name] = map[string]interface{}{middleware.Kind: middleware.Options}
+		}
+
+		for _, service := range config.Services {
+			servers := make([]map[string]string, 0, len(route.Upstreams))
+			for _, upstream := range route.Upstreams {
+				if service.Protocol == "http" {
+					servers = append(servers, map[string]string{"url": fmt.Sprintf("http://%s:%d", upstream, service.Port)})
+				} else {
+					servers = append(servers, map[string]string{"address": fmt.Sprintf("%s:%d", upstream, service.Port)})
+				}
+			}
+			section(service.Protocol, "services")[service.Name] = map[string]interface{}{"loadBalancer": map[string]interface{}{"servers": servers}}
+		}
+	}
+
+	// Rules contain && which is escaped by default
+	var config bytes.Buffer
+	encoder := json.NewEncoder(&config)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(sections)
+
+	return config.Bytes(), nil, err
+}
+
+// sortedKeys returns the keys of a map in order, so rendered configuration only changes when the routes do.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
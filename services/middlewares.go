@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+)
+
+// MiddlewareName is the Traefik middleware of a deployment. Middlewares are declared on the deployment's own
+// containers, so the service name prefix is what keeps two deployments' rate limits or auth apart in Traefik.
+func MiddlewareName(serviceName string, middleware string) string {
+	return fmt.Sprintf("%s_%s", serviceName, middleware)
+}
+
+// traefikMiddlewares returns the deployment's middlewares in the order requests pass through them: requests are
+// filtered before they're authenticated, and the path is only stripped right before the request reaches the container.
+func (spec ContainerSpec) traefikMiddlewares() []traefikMiddleware {
+	chain := []traefikMiddleware{}
+	add := func(middleware string, kind string, options map[string]interface{}) {
+		chain = append(chain, traefikMiddleware{Name: MiddlewareName(spec.ServiceName, middleware), Kind: kind, Options: options})
+	}
+
+	if middlewares := spec.Middlewares; middlewares != nil {
+		if allowList := middlewares.IPAllowList; allowList != nil {
+			add("ipallowlist", "ipWhiteList", map[string]interface{}{"sourceRange": allowList.SourceRange})
+		}
+
+		if rateLimit := middlewares.RateLimit; rateLimit != nil {
+			options := map[string]interface{}{
+				"average": rateLimit.Average,
+				"period":  fmt.Sprintf("%ds", rateLimit.Period),
+			}
+			if rateLimit.Burst != 0 {
+				options["burst"] = rateLimit.Burst
+			}
+			add("ratelimit", "rateLimit", options)
+		}
+
+		// Preflight requests carry no credentials, so CORS is answered before basic auth
+		if cors := middlewares.CORS; cors != nil {
+			options := map[string]interface{}{
+				"accessControlAllowOriginList": cors.AllowOrigins,
+				"addVaryHeader":                true,
+			}
+			if len(cors.AllowMethods) != 0 {
+				options["accessControlAllowMethods"] = cors.AllowMethods
+			}
+			if len(cors.AllowHeaders) != 0 {
+				options["accessControlAllowHeaders"] = cors.AllowHeaders
+			}
+			if cors.AllowCredentials {
+				options["accessControlAllowCredentials"] = true
+			}
+			if cors.MaxAge != 0 {
+				options["accessControlMaxAge"] = cors.MaxAge
+			}
+			add("cors", "headers", options)
+		}
+
+		if basicAuth := middlewares.BasicAuth; basicAuth != nil {
+			users := make([]string, 0, len(basicAuth.Users))
+			for _, user := range basicAuth.Users {
+				users = append(users, fmt.Sprintf("%s:%s", user.Username, user.PasswordHash))
+			}
+
+			options := map[string]interface{}{"users": users}
+			if basicAuth.Realm != "" {
+				options["realm"] = basicAuth.Realm
+			}
+			add("basicauth", "basicAuth", options)
+		}
+
+		if headers := middlewares.Headers; headers != nil {
+			options := map[string]interface{}{}
+			if len(headers.Request) != 0 {
+				options["customRequestHeaders"] = headers.Request
+			}
+			if len(headers.Response) != 0 {
+				options["customResponseHeaders"] = headers.Response
+			}
+			add("headers", "headers", options)
+		}
+
+		if redirect := middlewares.Redirect; redirect != nil {
+			add("redirect", "redirectRegex", map[string]interface{}{
+				"regex":       redirect.Regex,
+				"replacement": redirect.Replacement,
+				"permanent":   redirect.Permanent,
+			})
+		}
+	}
+
+	if spec.Routing != nil && spec.Routing.StripPrefix {
+		add("stripprefix", "stripPrefix", map[string]interface{}{"prefixes": []string{spec.Routing.PathPrefix}})
+	}
+
+	return chain
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// PortEntrypointName is the Traefik entrypoint of a public port of the pool, e.g. tcp-30000.
+func PortEntrypointName(protocol string, publicPort int) string {
+	return fmt.Sprintf("%s-%d", strings.ToLower(protocol), publicPort)
+}
+
+// PortRouterName is the Traefik TCP or UDP router of a deployment's port, which is also the name of its service.
+func PortRouterName(serviceName string, port types.DeploymentPort) string {
+	return fmt.Sprintf("%s_%s_%s", serviceName, strings.ToLower(*port.Protocol), *port.Name)
+}
+
+// traefikPorts returns a router and a load balanced service for every TCP and UDP port of the spec.
+func (spec ContainerSpec) traefikPorts() ([]traefikRouter, []traefikService) {
+	routers := []traefikRouter{}
+	services := []traefikService{}
+
+	for _, port := range spec.Ports {
+		name := PortRouterName(spec.ServiceName, port)
+		protocol := strings.ToLower(*port.Protocol)
+
+		router := traefikRouter{Protocol: protocol, Name: name, Service: name}
+		services = append(services, traefikService{Protocol: protocol, Name: name, Port: *port.ContainerPort})
+
+		switch {
+		case *port.Protocol == types.PORT_PROTOCOL_UDP:
+			router.EntryPoints = []string{PortEntrypointName(*port.Protocol, *port.PublicPort)}
+		// Without TLS there's no SNI to route on, so the port gets its own entrypoint
+		case port.TLS == nil:
+			router.EntryPoints = []string{PortEntrypointName(*port.Protocol, *port.PublicPort)}
+			router.Rule = "HostSNI(`*`)"
+		default:
+			router.EntryPoints = []string{TRAEFIK_ENTRYPOINT_NAME}
+			router.Rule = fmt.Sprintf("HostSNI(`%s`)", port.SNIHostname(spec.ServiceName))
+			router.Passthrough = *port.TLS == types.PORT_TLS_PASSTHROUGH
+			if *port.TLS == types.PORT_TLS_TERMINATE {
+				router.CertResolver = TRAEFIK_CERTRESOLVER_NAME
+			}
+		}
+
+		routers = append(routers, router)
+	}
+
+	return routers, services
+}
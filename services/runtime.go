@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/types"
+)
+
+// ErrContainerNotFound is wrapped by the errors of calls on a container that doesn't exist, which a retried task
+// removing it treats as done.
+var ErrContainerNotFound = errors.New("no such container")
+
+// ContainerRuntime is everything the engine needs from the host running the containers.
+// DockerService is the production implementation, FakeRuntime keeps containers in memory.
+type ContainerRuntime interface {
+	Ping(ctx context.Context) error
+	// Ingress is the provider routing requests to the containers
+	Ingress() IngressProvider
+
+	ProvisionContainer(ctx context.Context, spec ContainerSpec) (string, error)
+	RemoveContainer(ctx context.Context, containerID string) error
+	StartContainer(ctx context.Context, containerID string) error
+	StopContainer(ctx context.Context, containerID string) error
+	RestartContainer(ctx context.Context, containerID string) error
+	RenameContainer(ctx context.Context, containerID string, name string) error
+	InspectContainer(ctx context.Context, containerID string) (ContainerSummary, error)
+	ListContainers(ctx context.Context) ([]ContainerSummary, error)
+
+	GetContainerEnv(ctx context.Context, containerID string) (map[string]string, error)
+	StreamContainerLogs(ctx context.Context, containerID string, opts LogOptions, stdout io.Writer, stderr io.Writer) error
+	GetContainerStats(ctx context.Context, containerID string) (ContainerStats, error)
+
+	ListDanglingImages(ctx context.Context) ([]ImageSummary, error)
+	RemoveImage(ctx context.Context, imageID string) error
+}
+
+type ContainerSpec struct {
+	DeploymentUUID string
+	ServiceName    string
+	Replica        int
+	// ContainerName overrides the name of the container, which defaults to InstanceName(ServiceName, Replica).
+	// Routing labels always use ServiceName, so every replica is a server of the same Traefik service.
+	ContainerName string
+	Image         string
+	Env           []string
+	// Secrets are written to types.SecretFilePath(name) before the container starts, so that they stay out of Env
+	Secrets      map[string][]byte
+	Port         int
+	RegistryAuth string
+	Resources    types.ResourceSpec
+	HealthCheck  *types.HealthCheck
+	// Domains are the verified custom hostnames served next to RoutingHostname()
+	Domains     []string
+	Routing     *types.RoutingSpec
+	Middlewares *types.MiddlewareSpec
+	// Ports are the TCP and UDP ports exposed next to the HTTP port
+	Ports []types.DeploymentPort
+}
+
+func (spec ContainerSpec) Name() string {
+	if spec.ContainerName != "" {
+		return spec.ContainerName
+	}
+	return InstanceName(spec.ServiceName, spec.Replica)
+}
+
+// InstanceName is the container name of a replica. The first replica keeps the plain service name, and as
+// subdomains are validated as DNS labels, which have no underscores, the other replicas can't take another
+// deployment's name.
+func InstanceName(serviceName string, replica int) string {
+	if replica == 0 {
+		return serviceName
+	}
+	return fmt.Sprintf("%s_%d", serviceName, replica)
+}
+
+// StartupGrace is how long a container of this spec must keep running before it's ready. Containers with a
+// health check are ready as soon as it passes.
+func (spec ContainerSpec) StartupGrace() time.Duration {
+	if spec.HealthCheck != nil {
+		return 0
+	}
+	return config.STARTUP_GRACE_PERIOD
+}
+
+// HealthyTimeout is how long WaitForContainer should give a container of this spec to become healthy.
+func (spec ContainerSpec) HealthyTimeout() time.Duration {
+	if spec.HealthCheck == nil {
+		return config.HEALTHY_TIMEOUT
+	}
+	return spec.HealthCheck.Deadline()
+}
+
+func (spec ContainerSpec) Hostname() string {
+	return fmt.Sprintf("%s.%s", spec.ServiceName, config.DEFAULT_HOSTNAME)
+}
+
+// RoutingHostname is the hostname the deployment is served on, which is another deployment's when they share it.
+func (spec ContainerSpec) RoutingHostname() string {
+	if spec.Routing != nil && spec.Routing.Subdomain != "" {
+		return fmt.Sprintf("%s.%s", spec.Routing.Subdomain, config.DEFAULT_HOSTNAME)
+	}
+	return spec.Hostname()
+}
+
+// Hostnames are every hostname the deployment is served on, custom domains follow RoutingHostname().
+func (spec ContainerSpec) Hostnames() []string {
+	return append([]string{spec.RoutingHostname()}, spec.Domains...)
+}
+
+// Labels returns the labels marking the container as the engine's, routing labels are added by the IngressProvider.
+func (spec ContainerSpec) Labels() map[string]string {
+	return map[string]string{
+		LABEL_MANAGED:         "true",
+		LABEL_DEPLOYMENT_UUID: spec.DeploymentUUID,
+		LABEL_REPLICA:         fmt.Sprintf("%d", spec.Replica),
+	}
+}
+
+type ContainerSummary struct {
+	ID    string
+	Name  string
+	Image string
+	State string
+	// Health is the status reported by the container's healthcheck, empty when it has none
+	Health    string
+	Labels    map[string]string
+	CreatedAt time.Time
+	// StartedAt and FinishedAt are when the container was last started and stopped, only InspectContainer sets them
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+type ImageSummary struct {
+	ID   string
+	Size int64
+	// Repositories the image was pulled from, a dangling image keeps them after it lost its tags
+	Repositories []string
+	CreatedAt    time.Time
+}
+
+type LogOptions struct {
+	Tail       string
+	Since      string
+	Until      string
+	Timestamps bool
+	Follow     bool
+}
+
+type ContainerStats struct {
+	CPUPercent  float64   `json:"cpuPercent"`
+	MemoryUsage uint64    `json:"memoryUsage"`
+	MemoryLimit uint64    `json:"memoryLimit"`
+	Pids        uint64    `json:"pids"`
+	NetworkRx   uint64    `json:"networkRx"`
+	NetworkTx   uint64    `json:"networkTx"`
+	CollectedAt time.Time `json:"collectedAt"`
+}
+
+// UnhealthyError is returned by WaitForContainer when the container itself failed, rather than the runtime.
+type UnhealthyError struct {
+	Reason string
+}
+
+func (e UnhealthyError) Error() string {
+	return e.Reason
+}
+
+// WaitForContainer polls the container until it is running and, if it has a healthcheck, healthy. A container without
+// a healthcheck has to have been running for grace, so that one crashing right after it starts isn't taken as ready.
+// It fails as soon as the container exits or reports unhealthy, or once timeout elapses.
+func WaitForContainer(ctx context.Context, runtime ContainerRuntime, containerID string, timeout time.Duration, grace time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(CONTAINER_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		cont, err := runtime.InspectContainer(ctx, containerID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case cont.State == "running" && cont.Health == "healthy":
+			return nil
+		case cont.State == "running" && cont.Health == "" && time.Since(cont.StartedAt) >= grace:
+			return nil
+		case cont.State == "exited" || cont.State == "dead":
+			return UnhealthyError{fmt.Sprintf("container %s %s before becoming healthy", containerID, cont.State)}
+		case cont.Health == "unhealthy":
+			return UnhealthyError{fmt.Sprintf("container %s is unhealthy", containerID)}
+		}
+
+		select {
+		case <-ctx.Done():
+			return UnhealthyError{fmt.Sprintf("container %s did not become healthy within %s", containerID, timeout)}
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitForContainerGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	runtime := NewFakeRuntime(TraefikLabelProvider{})
+
+	containerId, err := runtime.ProvisionContainer(ctx, ContainerSpec{DeploymentUUID: "d", ServiceName: "web", Image: "nginx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The container crashes right after it started, before the grace period is over
+	go func() {
+		time.Sleep(CONTAINER_POLL_INTERVAL / 2)
+		runtime.SetContainerState(containerId, "exited")
+	}()
+
+	err = WaitForContainer(ctx, runtime, containerId, time.Minute, time.Hour)
+	var unhealthy UnhealthyError
+	if !errors.As(err, &unhealthy) {
+		t.Fatalf("expected the crash within the grace period to fail the wait, got %v", err)
+	}
+
+	runtime.Uptime = time.Hour
+	if err := runtime.StartContainer(ctx, containerId); err != nil {
+		t.Fatal(err)
+	}
+	if err := WaitForContainer(ctx, runtime, containerId, time.Minute, time.Minute); err != nil {
+		t.Fatalf("expected a container running for longer than the grace period to be ready, got %s", err)
+	}
+}
@@ -1,6 +1,12 @@
 package types
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
 
 type Deployment struct {
 	ID     *int    `db:"id" json:"-"`
@@ -10,15 +16,104 @@ type Deployment struct {
 	ImageTag  *string `db:"image_tag" json:"imageTag"`
 	Subdomain *string `db:"sub_domain" json:"subDomain"`
 
-	ContainerId *string `db:"container_id" json:"containerId"`
-	Port        *int    `db:"port" json:"-"`
+	Port     *int `db:"port" json:"-"`
+	Replicas *int `db:"replicas" json:"replicas"`
+
+	Status       *string `db:"status" json:"status"`
+	StatusReason *string `db:"status_reason" json:"statusReason"`
+
+	ResourceSpec `json:"resources"`
+	HealthCheck  *HealthCheck    `db:"health_check" json:"healthCheck"`
+	Routing      *RoutingSpec    `db:"routing" json:"routing"`
+	Middlewares  *MiddlewareSpec `db:"middlewares" json:"middlewares"`
+
+	// EnvConfig is nil for deployments created before it was stored, their env only lives in the containers
+	EnvConfig EnvConfig `db:"env_config" json:"envConfig"`
+
+	// ReservedMemory and ReservedCPUs are what an update in progress will use, they count against the user's quota
+	// until the deployment leaves UPDATING
+	ReservedMemory *int64   `db:"reserved_memory" json:"-"`
+	ReservedCPUs   *float64 `db:"reserved_cpus" json:"-"`
 
-	Status *string `db:"status" json:"status"`
+	// PendingUpdate is the configuration the queued updates move the deployment to, set until the last of them is done
+	PendingUpdate *DeploymentConfig `db:"pending_update" json:"-"`
+
+	// Instances is only loaded when a single deployment is requested
+	Instances []DeploymentInstance `db:"-" json:"instances,omitempty"`
 
 	CreatedAt *time.Time `db:"created_at" json:"-"`
 	UpdatedAt *time.Time `db:"updated_at" json:"-"`
 }
 
+// ReplicaCount returns the number of containers the deployment should run.
+func (d Deployment) ReplicaCount() int {
+	if d.Replicas == nil {
+		return 1
+	}
+	return *d.Replicas
+}
+
+// Redacted returns the deployment as it is returned by the API, without the values of its env config and the password hashes of its middlewares.
+func (d Deployment) Redacted() Deployment {
+	d.EnvConfig = d.EnvConfig.Redacted()
+	d.Middlewares = d.Middlewares.Redacted()
+	return d
+}
+
+// Config returns the configuration the deployment runs once its queued updates are done. Updates build on it rather
+// than on the stored columns, which only change when an update task finishes.
+func (d Deployment) Config() DeploymentConfig {
+	if d.PendingUpdate != nil {
+		return *d.PendingUpdate
+	}
+
+	return DeploymentConfig{ImageTag: *d.ImageTag, Subdomain: *d.Subdomain, Port: *d.Port, Replicas: d.ReplicaCount(), EnvConfig: d.EnvConfig, Resources: d.ResourceSpec, HealthCheck: d.HealthCheck, Routing: d.Routing, Middlewares: d.Middlewares}
+}
+
+// DeploymentConfig is the full configuration of a deployment as an update sets it.
+type DeploymentConfig struct {
+	// Token identifies the update that queued the configuration, only its task takes the deployment out of UPDATING
+	Token string `json:"token"`
+
+	ImageTag    string          `json:"imageTag"`
+	Subdomain   string          `json:"subdomain"`
+	Port        int             `json:"port"`
+	Replicas    int             `json:"replicas"`
+	EnvConfig   EnvConfig       `json:"envConfig"`
+	Resources   ResourceSpec    `json:"resources"`
+	HealthCheck *HealthCheck    `json:"healthCheck"`
+	Routing     *RoutingSpec    `json:"routing"`
+	Middlewares *MiddlewareSpec `json:"middlewares"`
+}
+
+// DeploymentConfig is stored as a jsonb column.
+func (c DeploymentConfig) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *DeploymentConfig) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, c)
+	case string:
+		return json.Unmarshal([]byte(src), c)
+	default:
+		return fmt.Errorf("cannot scan %T into DeploymentConfig", src)
+	}
+}
+
+// DeploymentInstance is one of the containers running a deployment.
+type DeploymentInstance struct {
+	ID           *int `db:"id" json:"-"`
+	DeploymentId *int `db:"deployment_id" json:"-"`
+
+	Replica     *int    `db:"replica" json:"replica"`
+	ContainerId *string `db:"container_id" json:"containerId"`
+
+	CreatedAt *time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt *time.Time `db:"updated_at" json:"-"`
+}
+
 type DeploymentAttributes struct {
 	UUID     string `db:"uuid" json:"uuid"`
 	UserUUID string `json:"userUUID"`
@@ -26,26 +121,64 @@ type DeploymentAttributes struct {
 	Subdomain string `json:"subdomain" db:"sub_domain"`
 	ImageTag  string `json:"imageTag" db:"image_tag"`
 
-	ContainerId *string `db:"container_id" json:"containerId"`
-	Port        *int    `db:"port" json:"-"`
+	Port     *int `db:"port" json:"-"`
+	Replicas int  `db:"replicas" json:"replicas"`
+
+	Status       string  `db:"status" json:"status"`
+	StatusReason *string `db:"status_reason" json:"statusReason"`
+
+	ResourceSpec `json:"resources"`
+	HealthCheck  *HealthCheck    `db:"health_check" json:"healthCheck"`
+	Routing      *RoutingSpec    `db:"routing" json:"routing"`
+	Middlewares  *MiddlewareSpec `db:"middlewares" json:"middlewares"`
+
+	EnvConfig EnvConfig `db:"env_config" json:"envConfig"`
+}
+
+// dnsLabelPattern matches a lowercase DNS label. Subdomains end up in router rules, label keys and container names,
+// so they can't contain anything else.
+var dnsLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
 
-	Status string `db:"status" json:"status"`
+func ValidateSubdomain(subdomain string) error {
+	if !dnsLabelPattern.MatchString(subdomain) {
+		return fmt.Errorf("%s is not a valid subdomain, it must be a lowercase DNS label", subdomain)
+	}
+	return nil
 }
 
 type dockerAuth struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// Replace overwrites a different credential the user already stored for the image's registry
+	Replace bool `json:"replace"`
 }
 type CreateDeploymentRequest struct {
-	Subdomain  string            `json:"subdomain" binding:"required"`
-	ImageTag   string            `json:"imageTag" binding:"required"`
-	EnvConfig  map[string]string `json:"envConfig"`
-	DockerAuth *dockerAuth       `json:"dockerAuth"`
+	Subdomain   string            `json:"subdomain" binding:"required"`
+	ImageTag    string            `json:"imageTag" binding:"required"`
+	EnvConfig   map[string]string `json:"envConfig"`
+	DockerAuth  *dockerAuth       `json:"dockerAuth"`
+	Resources   *ResourceSpec     `json:"resources"`
+	Replicas    *int              `json:"replicas" binding:"omitempty,min=1"`
+	HealthCheck *HealthCheck      `json:"healthCheck"`
+	Routing     *RoutingSpec      `json:"routing"`
+	Middlewares *MiddlewareSpec   `json:"middlewares"`
 }
 
 type UpdateDeploymentRequest struct {
-	Subdomain  *string            `json:"subdomain"`
-	ImageTag   *string            `json:"imageTag"`
-	EnvConfig  *map[string]string `json:"envConfig"`
-	DockerAuth *dockerAuth        `json:"dockerAuth"`
+	Subdomain *string `json:"subdomain"`
+	ImageTag  *string `json:"imageTag"`
+	// EnvConfig is merged into the stored env config, keys set to null are removed
+	EnvConfig   map[string]*string `json:"envConfig"`
+	DockerAuth  *dockerAuth        `json:"dockerAuth"`
+	Resources   *ResourceSpec      `json:"resources"`
+	Replicas    *int               `json:"replicas" binding:"omitempty,min=1"`
+	HealthCheck *HealthCheck       `json:"healthCheck"`
+	// Routing replaces the stored routing, an empty object resets it
+	Routing *RoutingSpec `json:"routing"`
+	// Middlewares replaces the stored middlewares, an empty object removes them
+	Middlewares *MiddlewareSpec `json:"middlewares"`
+}
+
+type ScaleDeploymentRequest struct {
+	Replicas int `json:"replicas" binding:"required,min=1"`
 }
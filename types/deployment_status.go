@@ -0,0 +1,56 @@
+package types
+
+import "fmt"
+
+const (
+	DEPLOYMENT_STATUS_PENDING      string = "PENDING"
+	DEPLOYMENT_STATUS_PROVISIONING string = "PROVISIONING"
+	DEPLOYMENT_STATUS_READY        string = "READY"
+	DEPLOYMENT_STATUS_UPDATING     string = "UPDATING"
+	DEPLOYMENT_STATUS_FAILED       string = "FAILED"
+	DEPLOYMENT_STATUS_STOPPED      string = "STOPPED"
+	DEPLOYMENT_STATUS_CRASHED      string = "CRASHED"
+	DEPLOYMENT_STATUS_DELETING     string = "DELETING"
+)
+
+// deploymentTransitions lists the statuses a deployment can move to from each status.
+// Staying in the same status is always allowed so that retried tasks are idempotent.
+var deploymentTransitions = map[string][]string{
+	DEPLOYMENT_STATUS_PENDING:      {DEPLOYMENT_STATUS_PROVISIONING, DEPLOYMENT_STATUS_FAILED, DEPLOYMENT_STATUS_DELETING},
+	DEPLOYMENT_STATUS_PROVISIONING: {DEPLOYMENT_STATUS_READY, DEPLOYMENT_STATUS_FAILED, DEPLOYMENT_STATUS_DELETING},
+	DEPLOYMENT_STATUS_READY:        {DEPLOYMENT_STATUS_UPDATING, DEPLOYMENT_STATUS_FAILED, DEPLOYMENT_STATUS_STOPPED, DEPLOYMENT_STATUS_CRASHED, DEPLOYMENT_STATUS_DELETING},
+	DEPLOYMENT_STATUS_UPDATING:     {DEPLOYMENT_STATUS_READY, DEPLOYMENT_STATUS_FAILED, DEPLOYMENT_STATUS_DELETING},
+	DEPLOYMENT_STATUS_FAILED:       {DEPLOYMENT_STATUS_PROVISIONING, DEPLOYMENT_STATUS_READY, DEPLOYMENT_STATUS_UPDATING, DEPLOYMENT_STATUS_STOPPED, DEPLOYMENT_STATUS_DELETING},
+	DEPLOYMENT_STATUS_STOPPED:      {DEPLOYMENT_STATUS_READY, DEPLOYMENT_STATUS_FAILED, DEPLOYMENT_STATUS_DELETING},
+	DEPLOYMENT_STATUS_CRASHED:      {DEPLOYMENT_STATUS_READY, DEPLOYMENT_STATUS_UPDATING, DEPLOYMENT_STATUS_FAILED, DEPLOYMENT_STATUS_STOPPED, DEPLOYMENT_STATUS_DELETING},
+	DEPLOYMENT_STATUS_DELETING:     {},
+}
+
+// InProgress reports whether a task is working on a deployment in status, which then can't be started, stopped or restarted.
+func InProgress(status string) bool {
+	return status == DEPLOYMENT_STATUS_PENDING || status == DEPLOYMENT_STATUS_PROVISIONING || status == DEPLOYMENT_STATUS_UPDATING
+}
+
+type InvalidTransitionError struct {
+	From string
+	To   string
+}
+
+func (e InvalidTransitionError) Error() string {
+	return fmt.Sprintf("deployment cannot move from %s to %s", e.From, e.To)
+}
+
+// CheckTransition returns an InvalidTransitionError when a deployment in status from can't move to status to.
+func CheckTransition(from string, to string) error {
+	if from == to {
+		return nil
+	}
+
+	for _, allowed := range deploymentTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+
+	return InvalidTransitionError{From: from, To: to}
+}
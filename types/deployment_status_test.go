@@ -0,0 +1,34 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckTransition(t *testing.T) {
+	allowed := [][2]string{
+		{DEPLOYMENT_STATUS_PENDING, DEPLOYMENT_STATUS_PROVISIONING},
+		{DEPLOYMENT_STATUS_READY, DEPLOYMENT_STATUS_UPDATING},
+		{DEPLOYMENT_STATUS_UPDATING, DEPLOYMENT_STATUS_UPDATING},
+		{DEPLOYMENT_STATUS_UPDATING, DEPLOYMENT_STATUS_READY},
+		{DEPLOYMENT_STATUS_FAILED, DEPLOYMENT_STATUS_PROVISIONING},
+		{DEPLOYMENT_STATUS_CRASHED, DEPLOYMENT_STATUS_DELETING},
+	}
+	for _, transition := range allowed {
+		if err := CheckTransition(transition[0], transition[1]); err != nil {
+			t.Errorf("expected %s to %s to be allowed, got %s", transition[0], transition[1], err)
+		}
+	}
+
+	forbidden := [][2]string{
+		{DEPLOYMENT_STATUS_DELETING, DEPLOYMENT_STATUS_READY},
+		{DEPLOYMENT_STATUS_DELETING, DEPLOYMENT_STATUS_UPDATING},
+		{DEPLOYMENT_STATUS_UPDATING, DEPLOYMENT_STATUS_CRASHED},
+	}
+	for _, transition := range forbidden {
+		var transitionErr InvalidTransitionError
+		if err := CheckTransition(transition[0], transition[1]); !errors.As(err, &transitionErr) {
+			t.Errorf("expected %s to %s to be refused, got %v", transition[0], transition[1], err)
+		}
+	}
+}
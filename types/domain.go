@@ -0,0 +1,101 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
+)
+
+const (
+	DOMAIN_VERIFICATION_TXT  string = "TXT"
+	DOMAIN_VERIFICATION_HTTP string = "HTTP"
+
+	// The TXT record holding the token is looked up at DOMAIN_TXT_CHALLENGE_PREFIX.<hostname>
+	DOMAIN_TXT_CHALLENGE_PREFIX string = "_cpe-challenge"
+	// The token is fetched from http://<hostname>DOMAIN_HTTP_CHALLENGE_PATH<token>
+	DOMAIN_HTTP_CHALLENGE_PATH string = "/.well-known/cpe-challenge/"
+)
+
+var hostnamePattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,62}$`)
+
+// Domain is a custom hostname a deployment is served on once its ownership has been verified.
+type Domain struct {
+	ID           *int    `db:"id" json:"-"`
+	UUID         *string `db:"uuid" json:"uuid"`
+	DeploymentId *int    `db:"deployment_id" json:"-"`
+
+	Hostname           *string    `db:"hostname" json:"hostname"`
+	VerificationMethod *string    `db:"verification_method" json:"verificationMethod"`
+	VerificationToken  *string    `db:"verification_token" json:"-"`
+	VerifiedAt         *time.Time `db:"verified_at" json:"verifiedAt"`
+
+	// Challenge is only set while the domain is unverified
+	Challenge *DomainChallenge `db:"-" json:"challenge,omitempty"`
+
+	CreatedAt *time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt *time.Time `db:"updated_at" json:"-"`
+}
+
+// DomainChallenge tells the user where to publish the verification token.
+type DomainChallenge struct {
+	Type  string `json:"type"`
+	Name  string `json:"name,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Value string `json:"value"`
+}
+
+type CreateDomainRequest struct {
+	Hostname string `json:"hostname" binding:"required"`
+	// Method defaults to TXT
+	Method string `json:"method" binding:"omitempty,oneof=TXT HTTP"`
+}
+
+func (d Domain) Verified() bool {
+	return d.VerifiedAt != nil
+}
+
+func (d Domain) TXTRecordName() string {
+	return fmt.Sprintf("%s.%s", DOMAIN_TXT_CHALLENGE_PREFIX, *d.Hostname)
+}
+
+func (d Domain) ChallengeURL() string {
+	return fmt.Sprintf("http://%s%s%s", *d.Hostname, DOMAIN_HTTP_CHALLENGE_PATH, *d.VerificationToken)
+}
+
+// WithChallenge fills in Challenge for unverified domains.
+func (d Domain) WithChallenge() Domain {
+	if d.Verified() {
+		return d
+	}
+
+	switch *d.VerificationMethod {
+	case DOMAIN_VERIFICATION_HTTP:
+		d.Challenge = &DomainChallenge{Type: DOMAIN_VERIFICATION_HTTP, URL: d.ChallengeURL(), Value: *d.VerificationToken}
+	default:
+		d.Challenge = &DomainChallenge{Type: DOMAIN_VERIFICATION_TXT, Name: d.TXTRecordName(), Value: *d.VerificationToken}
+	}
+
+	return d
+}
+
+// NormalizeHostname lowercases a hostname and drops the trailing dot of a fully qualified name.
+func NormalizeHostname(hostname string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".")
+}
+
+// ValidateCustomHostname checks that a normalized hostname can be attached to a deployment.
+// Hostnames under the engine's own domain are handed out through subdomains and can't be claimed.
+func ValidateCustomHostname(hostname string) error {
+	if len(hostname) > 253 || !hostnamePattern.MatchString(hostname) {
+		return fmt.Errorf("%s is not a valid hostname", hostname)
+	}
+
+	if hostname == config.DEFAULT_HOSTNAME || strings.HasSuffix(hostname, "."+config.DEFAULT_HOSTNAME) {
+		return fmt.Errorf("hostnames under %s can't be used as custom domains", config.DEFAULT_HOSTNAME)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EnvConfig maps environment variable names to their values, stored as a jsonb column.
+type EnvConfig map[string]string
+
+// REDACTED_ENV_VALUE replaces env values in API responses, they often hold credentials
+const REDACTED_ENV_VALUE string = "[redacted]"
+
+// ParseEnv builds an EnvConfig from KEY=VALUE pairs as they are passed to the container.
+func ParseEnv(envArray []string) EnvConfig {
+	env := EnvConfig{}
+	for _, pair := range envArray {
+		key, value, _ := strings.Cut(pair, "=")
+		env[key] = value
+	}
+
+	return env
+}
+
+// Array returns the variables as KEY=VALUE pairs sorted by key.
+func (e EnvConfig) Array() []string {
+	envArray := make([]string, 0, len(e))
+	for key, value := range e {
+		envArray = append(envArray, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(envArray)
+
+	return envArray
+}
+
+// Redacted returns the variables with their values replaced by REDACTED_ENV_VALUE. Secret references are kept,
+// they only name the secret.
+func (e EnvConfig) Redacted() EnvConfig {
+	if e == nil {
+		return nil
+	}
+
+	redacted := make(EnvConfig, len(e))
+	for key, value := range e {
+		if _, ok := SecretReference(value); ok {
+			redacted[key] = value
+			continue
+		}
+		redacted[key] = REDACTED_ENV_VALUE
+	}
+
+	return redacted
+}
+
+func (e EnvConfig) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return json.Marshal(e)
+}
+
+func (e *EnvConfig) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(src, e)
+	case string:
+		return json.Unmarshal([]byte(src), e)
+	default:
+		return fmt.Errorf("cannot scan %T into EnvConfig", src)
+	}
+}
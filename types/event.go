@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+const (
+	EVENT_CONTAINER_MISSING        string = "CONTAINER_MISSING"
+	EVENT_CONTAINER_RESTARTED      string = "CONTAINER_RESTARTED"
+	EVENT_CONTAINER_RESTART_FAILED string = "CONTAINER_RESTART_FAILED"
+	EVENT_CONTAINER_CRASH_LOOP     string = "CONTAINER_CRASH_LOOP"
+	EVENT_UPDATE_ROLLED_BACK       string = "UPDATE_ROLLED_BACK"
+	EVENT_HEALTH_CHECK_FAILED      string = "HEALTH_CHECK_FAILED"
+)
+
+type DeploymentEvent struct {
+	ID             *int    `db:"id" json:"-"`
+	DeploymentUUID *string `db:"deployment_uuid" json:"deploymentUUID"`
+
+	Type    *string `db:"type" json:"type"`
+	Message *string `db:"message" json:"message"`
+
+	CreatedAt *time.Time `db:"created_at" json:"createdAt"`
+}
@@ -0,0 +1,117 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
+)
+
+const (
+	HEALTH_CHECK_HTTP string = "HTTP"
+	HEALTH_CHECK_TCP  string = "TCP"
+	HEALTH_CHECK_CMD  string = "CMD"
+)
+
+// healthCheckPathPattern only allows URL path and query characters that are safe inside the single quotes
+// of the CMD-SHELL test
+var healthCheckPathPattern = regexp.MustCompile(`^/[A-Za-z0-9._~/%?=&+,:@-]*$`)
+
+// HealthCheck is run inside the deployment's containers by Docker. HTTP and TCP checks target
+// localhost on Port, which defaults to the deployment's port. Durations are in seconds.
+type HealthCheck struct {
+	Type string `json:"type" binding:"required,oneof=HTTP TCP CMD"`
+
+	Path    string   `json:"path,omitempty"`
+	Port    *int     `json:"port,omitempty" binding:"omitempty,min=1,max=65535"`
+	Command []string `json:"command,omitempty"`
+
+	Interval    int `json:"interval" binding:"omitempty,min=1"`
+	Timeout     int `json:"timeout" binding:"omitempty,min=1"`
+	Retries     int `json:"retries" binding:"omitempty,min=1"`
+	StartPeriod int `json:"startPeriod" binding:"omitempty,min=0"`
+}
+
+// WithDefaults fills in the timings that are not set.
+func (h HealthCheck) WithDefaults() HealthCheck {
+	if h.Interval == 0 {
+		h.Interval = 10
+	}
+	if h.Timeout == 0 {
+		h.Timeout = 5
+	}
+	if h.Retries == 0 {
+		h.Retries = 3
+	}
+	if h.Type == HEALTH_CHECK_HTTP && h.Path == "" {
+		h.Path = "/"
+	}
+
+	return h
+}
+
+func (h HealthCheck) Validate() error {
+	// Every value is bounded on its own first, so that the deadline can't overflow
+	limit := int(config.MAX_HEALTH_CHECK_DEADLINE / time.Second)
+	if h.Interval > limit || h.Timeout > limit || h.Retries > limit || h.StartPeriod > limit || h.Deadline() > config.MAX_HEALTH_CHECK_DEADLINE {
+		return fmt.Errorf("a health check can take at most %s to fail a container, lower its interval, timeout, retries or start period", config.MAX_HEALTH_CHECK_DEADLINE)
+	}
+
+	switch h.Type {
+	case HEALTH_CHECK_HTTP:
+		if !healthCheckPathPattern.MatchString(h.Path) {
+			return errors.New("path of an HTTP health check must start with / and can only contain letters, digits and the characters -._~/%?=&+,:@")
+		}
+	case HEALTH_CHECK_CMD:
+		if len(h.Command) == 0 {
+			return errors.New("a CMD health check requires a command")
+		}
+	}
+
+	return nil
+}
+
+// Test returns the Docker healthcheck test. HTTP and TCP checks rely on the tools usually found in images,
+// falling back from curl to wget and from nc to bash's /dev/tcp.
+func (h HealthCheck) Test(defaultPort int) []string {
+	port := defaultPort
+	if h.Port != nil {
+		port = *h.Port
+	}
+
+	switch h.Type {
+	case HEALTH_CHECK_HTTP:
+		url := fmt.Sprintf("http://localhost:%d%s", port, h.Path)
+		return []string{"CMD-SHELL", fmt.Sprintf("curl -fsS -o /dev/null '%s' || wget -q -O /dev/null '%s' || exit 1", url, url)}
+	case HEALTH_CHECK_TCP:
+		return []string{"CMD-SHELL", fmt.Sprintf("nc -z localhost %d || bash -c 'echo > /dev/tcp/localhost/%d' || exit 1", port, port)}
+	default:
+		return append([]string{"CMD"}, h.Command...)
+	}
+}
+
+// Deadline is how long a container can take to become healthy before it is considered failed,
+// the start period plus every retry running into its timeout.
+func (h HealthCheck) Deadline() time.Duration {
+	return time.Duration(h.StartPeriod+(h.Interval+h.Timeout)*(h.Retries+1)) * time.Second
+}
+
+// HealthCheck is stored as a jsonb column.
+func (h HealthCheck) Value() (driver.Value, error) {
+	return json.Marshal(h)
+}
+
+func (h *HealthCheck) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, h)
+	case string:
+		return json.Unmarshal([]byte(src), h)
+	default:
+		return fmt.Errorf("cannot scan %T into HealthCheck", src)
+	}
+}
@@ -0,0 +1,26 @@
+package types
+
+import "testing"
+
+func TestHealthCheckValidateBoundsDeadline(t *testing.T) {
+	cases := map[string]struct {
+		healthCheck HealthCheck
+		valid       bool
+	}{
+		"defaults":              {HealthCheck{Type: HEALTH_CHECK_TCP}, true},
+		"long start period":     {HealthCheck{Type: HEALTH_CHECK_TCP, StartPeriod: 120}, true},
+		"start period too long": {HealthCheck{Type: HEALTH_CHECK_TCP, StartPeriod: 3600}, false},
+		"too many retries":      {HealthCheck{Type: HEALTH_CHECK_TCP, Interval: 30, Timeout: 30, Retries: 10}, false},
+		"overflowing interval":  {HealthCheck{Type: HEALTH_CHECK_TCP, Interval: 1 << 40}, false},
+	}
+
+	for name, c := range cases {
+		err := c.healthCheck.WithDefaults().Validate()
+		if c.valid && err != nil {
+			t.Errorf("%s: expected the health check to be valid, got %s", name, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected the health check to be refused", name)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var httpMethodPattern = regexp.MustCompile(`^[A-Z]+$`)
+
+// MiddlewareSpec configures the Traefik middlewares requests pass through before they reach a deployment.
+// Every middleware that is set is chained onto the deployment's routers.
+type MiddlewareSpec struct {
+	IPAllowList *IPAllowListMiddleware `json:"ipAllowList,omitempty"`
+	RateLimit   *RateLimitMiddleware   `json:"rateLimit,omitempty"`
+	CORS        *CORSMiddleware        `json:"cors,omitempty"`
+	BasicAuth   *BasicAuthMiddleware   `json:"basicAuth,omitempty"`
+	Headers     *HeadersMiddleware     `json:"headers,omitempty"`
+	Redirect    *RedirectMiddleware    `json:"redirect,omitempty"`
+}
+
+// IPAllowListMiddleware rejects requests from addresses outside of SourceRange, a list of IPs and CIDR ranges.
+type IPAllowListMiddleware struct {
+	SourceRange []string `json:"sourceRange" binding:"required,min=1"`
+}
+
+// RateLimitMiddleware allows Average requests per Period seconds from each client IP, with bursts of up to Burst requests.
+type RateLimitMiddleware struct {
+	Average int `json:"average" binding:"required,min=1"`
+	Period  int `json:"period,omitempty" binding:"omitempty,min=1"`
+	Burst   int `json:"burst,omitempty" binding:"omitempty,min=1"`
+}
+
+type CORSMiddleware struct {
+	AllowOrigins     []string `json:"allowOrigins" binding:"required,min=1"`
+	AllowMethods     []string `json:"allowMethods,omitempty"`
+	AllowHeaders     []string `json:"allowHeaders,omitempty"`
+	AllowCredentials bool     `json:"allowCredentials,omitempty"`
+	MaxAge           int      `json:"maxAge,omitempty" binding:"omitempty,min=0"`
+}
+
+type BasicAuthMiddleware struct {
+	Users []BasicAuthUser `json:"users" binding:"required,min=1,dive"`
+	Realm string          `json:"realm,omitempty"`
+}
+
+type BasicAuthUser struct {
+	Username string `json:"username" binding:"required"`
+	// Password is replaced by its bcrypt hash before the spec is stored
+	Password     string `json:"password,omitempty"`
+	PasswordHash string `json:"passwordHash,omitempty"`
+}
+
+// HeadersMiddleware adds headers to requests and responses, an empty value removes the header instead.
+type HeadersMiddleware struct {
+	Request  map[string]string `json:"request,omitempty"`
+	Response map[string]string `json:"response,omitempty"`
+}
+
+// RedirectMiddleware redirects requests whose URL matches Regex to Replacement, which can refer to the regex's groups as ${1}.
+type RedirectMiddleware struct {
+	Regex       string `json:"regex" binding:"required"`
+	Replacement string `json:"replacement" binding:"required"`
+	Permanent   bool   `json:"permanent,omitempty"`
+}
+
+func (m MiddlewareSpec) IsZero() bool {
+	return m.IPAllowList == nil && m.RateLimit == nil && m.CORS == nil && m.BasicAuth == nil && m.Headers == nil && m.Redirect == nil
+}
+
+// WithDefaults fills in the rate limit period and replaces basic auth passwords by their hashes.
+func (m MiddlewareSpec) WithDefaults() (MiddlewareSpec, error) {
+	if m.RateLimit != nil && m.RateLimit.Period == 0 {
+		rateLimit := *m.RateLimit
+		rateLimit.Period = 1
+		m.RateLimit = &rateLimit
+	}
+
+	if m.BasicAuth != nil {
+		basicAuth := *m.BasicAuth
+		basicAuth.Users = make([]BasicAuthUser, len(m.BasicAuth.Users))
+		for i, user := range m.BasicAuth.Users {
+			if user.Password != "" {
+				hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+				if err != nil {
+					return MiddlewareSpec{}, err
+				}
+				user.PasswordHash = string(hash)
+				user.Password = ""
+			}
+			basicAuth.Users[i] = user
+		}
+		m.BasicAuth = &basicAuth
+	}
+
+	return m, nil
+}
+
+// Redacted returns the middlewares as they are returned by the API, without the password hashes of basic auth.
+func (m *MiddlewareSpec) Redacted() *MiddlewareSpec {
+	if m == nil || m.BasicAuth == nil {
+		return m
+	}
+
+	redacted := *m
+	basicAuth := *m.BasicAuth
+	basicAuth.Users = make([]BasicAuthUser, len(m.BasicAuth.Users))
+	for i, user := range m.BasicAuth.Users {
+		basicAuth.Users[i] = BasicAuthUser{Username: user.Username}
+	}
+	redacted.BasicAuth = &basicAuth
+
+	return &redacted
+}
+
+// Validate rejects values Traefik wouldn't accept, as well as values that can't be written into a label,
+// where lists are separated by commas.
+func (m MiddlewareSpec) Validate() error {
+	if m.IPAllowList != nil {
+		for _, source := range m.IPAllowList.SourceRange {
+			if _, _, err := net.ParseCIDR(source); err != nil && net.ParseIP(source) == nil {
+				return fmt.Errorf("%s is not a valid IP or CIDR range", source)
+			}
+		}
+	}
+
+	if m.CORS != nil {
+		for _, origin := range m.CORS.AllowOrigins {
+			if origin == "" || strings.ContainsAny(origin, ", ") {
+				return fmt.Errorf("%q is not a valid origin", origin)
+			}
+			// Browsers refuse credentialed responses to any origin, Traefik would echo the request's origin instead
+			if origin == "*" && m.CORS.AllowCredentials {
+				return errors.New("allowCredentials can't be combined with the * origin, list the allowed origins instead")
+			}
+		}
+		for _, method := range m.CORS.AllowMethods {
+			if !httpMethodPattern.MatchString(method) {
+				return fmt.Errorf("%s is not a valid method", method)
+			}
+		}
+		for _, header := range m.CORS.AllowHeaders {
+			if !headerNamePattern.MatchString(header) {
+				return fmt.Errorf("%s is not a valid header name", header)
+			}
+		}
+	}
+
+	if m.BasicAuth != nil {
+		for _, user := range m.BasicAuth.Users {
+			if strings.ContainsAny(user.Username, ":, ") {
+				return fmt.Errorf("username %s can't contain colons, commas or spaces", user.Username)
+			}
+			if user.PasswordHash == "" {
+				return fmt.Errorf("user %s requires a password", user.Username)
+			}
+			if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+				return fmt.Errorf("passwordHash of user %s must be a bcrypt hash", user.Username)
+			}
+		}
+	}
+
+	if m.Headers != nil {
+		for _, headers := range []map[string]string{m.Headers.Request, m.Headers.Response} {
+			for name, value := range headers {
+				if !headerNamePattern.MatchString(name) {
+					return fmt.Errorf("%s is not a valid header name", name)
+				}
+				if strings.ContainsAny(value, "\r\n") {
+					return fmt.Errorf("the value of header %s can't contain line breaks", name)
+				}
+			}
+		}
+	}
+
+	if m.Redirect != nil {
+		if _, err := regexp.Compile(m.Redirect.Regex); err != nil {
+			return errors.New("regex of the redirect is not a valid regular expression")
+		}
+	}
+
+	return nil
+}
+
+// MiddlewareSpec is stored as a jsonb column.
+func (m MiddlewareSpec) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+func (m *MiddlewareSpec) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, m)
+	case string:
+		return json.Unmarshal([]byte(src), m)
+	default:
+		return fmt.Errorf("cannot scan %T into MiddlewareSpec", src)
+	}
+}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMiddlewareValidate(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		middlewares MiddlewareSpec
+		valid       bool
+	}{
+		"ip allow list":                 {MiddlewareSpec{IPAllowList: &IPAllowListMiddleware{SourceRange: []string{"10.0.0.0/8", "192.168.1.1"}}}, true},
+		"invalid ip":                    {MiddlewareSpec{IPAllowList: &IPAllowListMiddleware{SourceRange: []string{"10.0.0.300"}}}, false},
+		"cors":                          {MiddlewareSpec{CORS: &CORSMiddleware{AllowOrigins: []string{"https://example.com"}, AllowMethods: []string{"GET"}, AllowHeaders: []string{"X-Request-Id"}}}, true},
+		"origin list in one value":      {MiddlewareSpec{CORS: &CORSMiddleware{AllowOrigins: []string{"https://a.com,https://b.com"}}}, false},
+		"credentials for any origin":    {MiddlewareSpec{CORS: &CORSMiddleware{AllowOrigins: []string{"*"}, AllowCredentials: true}}, false},
+		"lowercase method":              {MiddlewareSpec{CORS: &CORSMiddleware{AllowOrigins: []string{"*"}, AllowMethods: []string{"get"}}}, false},
+		"allowed header name with dot":  {MiddlewareSpec{CORS: &CORSMiddleware{AllowOrigins: []string{"*"}, AllowHeaders: []string{"X.Request"}}}, false},
+		"basic auth":                    {MiddlewareSpec{BasicAuth: &BasicAuthMiddleware{Users: []BasicAuthUser{{Username: "admin", PasswordHash: string(hash)}}}}, true},
+		"username with colon":           {MiddlewareSpec{BasicAuth: &BasicAuthMiddleware{Users: []BasicAuthUser{{Username: "ad:min", PasswordHash: string(hash)}}}}, false},
+		"password that isn't hashed":    {MiddlewareSpec{BasicAuth: &BasicAuthMiddleware{Users: []BasicAuthUser{{Username: "admin", PasswordHash: "password"}}}}, false},
+		"headers":                       {MiddlewareSpec{Headers: &HeadersMiddleware{Request: map[string]string{"X-Forwarded-Proto": "https"}, Response: map[string]string{"Server": ""}}}, true},
+		"request header name with dot":  {MiddlewareSpec{Headers: &HeadersMiddleware{Request: map[string]string{"X.Forwarded": "https"}}}, false},
+		"response header name with dot": {MiddlewareSpec{Headers: &HeadersMiddleware{Response: map[string]string{"X.Powered-By": ""}}}, false},
+		"header value with newline":     {MiddlewareSpec{Headers: &HeadersMiddleware{Response: map[string]string{"X-Frame-Options": "DENY\r\nSet-Cookie: a=b"}}}, false},
+		"redirect":                      {MiddlewareSpec{Redirect: &RedirectMiddleware{Regex: "^http://(.*)", Replacement: "https://${1}"}}, true},
+		"invalid redirect regex":        {MiddlewareSpec{Redirect: &RedirectMiddleware{Regex: "^http://(", Replacement: "https://"}}, false},
+	}
+
+	for name, c := range cases {
+		err := c.middlewares.Validate()
+		if c.valid && err != nil {
+			t.Errorf("%s: expected the middlewares to be valid, got %s", name, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected the middlewares to be refused", name)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
+)
+
+const (
+	PORT_PROTOCOL_TCP string = "TCP"
+	PORT_PROTOCOL_UDP string = "UDP"
+
+	// Traefik passes the TLS connection through to the container, which terminates it itself
+	PORT_TLS_PASSTHROUGH string = "passthrough"
+	// Traefik terminates TLS with a certificate from the certresolver and forwards plain TCP
+	PORT_TLS_TERMINATE string = "terminate"
+)
+
+var ErrPortPoolExhausted = errors.New("no public ports are left")
+
+// DeploymentPort exposes a non-HTTP port of a deployment's containers. TCP ports with TLS are routed by SNI on the
+// shared TLS entrypoint, every other port is given a public port of its own from the pool, each of which has a
+// Traefik entrypoint.
+type DeploymentPort struct {
+	ID           *int    `db:"id" json:"-"`
+	UUID         *string `db:"uuid" json:"uuid"`
+	DeploymentId *int    `db:"deployment_id" json:"-"`
+
+	Name          *string `db:"name" json:"name"`
+	Protocol      *string `db:"protocol" json:"protocol"`
+	ContainerPort *int    `db:"container_port" json:"containerPort"`
+	TLS           *string `db:"tls" json:"tls"`
+	PublicPort    *int    `db:"public_port" json:"publicPort"`
+
+	// Hostname is the SNI hostname clients of a TLS port connect to, only set in responses
+	Hostname string `db:"-" json:"hostname,omitempty"`
+
+	CreatedAt *time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt *time.Time `db:"updated_at" json:"-"`
+}
+
+type CreatePortRequest struct {
+	Name          string `json:"name" binding:"required"`
+	Protocol      string `json:"protocol" binding:"required,oneof=TCP UDP"`
+	ContainerPort int    `json:"containerPort" binding:"required,min=1,max=65535"`
+	TLS           string `json:"tls" binding:"omitempty,oneof=passthrough terminate"`
+}
+
+func (r CreatePortRequest) Validate() error {
+	if !dnsLabelPattern.MatchString(r.Name) {
+		return fmt.Errorf("%s is not a valid port name, it must be a lowercase DNS label", r.Name)
+	}
+
+	if r.TLS != "" && r.Protocol != PORT_PROTOCOL_TCP {
+		return errors.New("tls is only supported for TCP ports")
+	}
+
+	return nil
+}
+
+// SNIHostname is the hostname a TLS port of the deployment on subdomain is routed on.
+func (p DeploymentPort) SNIHostname(subdomain string) string {
+	return fmt.Sprintf("%s.%s.%s", *p.Name, subdomain, config.DEFAULT_HOSTNAME)
+}
+
+// WithHostname returns the port with the hostname clients connect to filled in.
+func (p DeploymentPort) WithHostname(subdomain string) DeploymentPort {
+	if p.TLS != nil {
+		p.Hostname = p.SNIHostname(subdomain)
+	}
+	return p
+}
@@ -0,0 +1,159 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
+)
+
+type Quota struct {
+	MaxDeployments int     `json:"maxDeployments" db:"max_deployments" binding:"min=0"`
+	MaxMemory      int64   `json:"maxMemory" db:"max_memory" binding:"min=0"`
+	MaxCPUs        float64 `json:"maxCPUs" db:"max_cpus" binding:"min=0"`
+	MaxPublicPorts int     `json:"maxPublicPorts" db:"max_public_ports" binding:"min=0"`
+}
+
+type Usage struct {
+	Deployments int     `json:"deployments"`
+	Memory      int64   `json:"memory"`
+	CPUs        float64 `json:"cpus"`
+	// PublicPorts are the ports taken from the public port pool, TLS ports routed by SNI don't count
+	PublicPorts int `json:"publicPorts"`
+}
+
+// QuotaExceededError lists every limit of a quota that a change would exceed.
+type QuotaExceededError struct {
+	Exceeded []string
+}
+
+func (e QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded: %s", strings.Join(e.Exceeded, ", "))
+}
+
+type UsageReport struct {
+	Quota Quota `json:"quota"`
+	Usage Usage `json:"usage"`
+}
+
+// Quota returns the user's quota, falling back to the engine defaults for limits that are not set.
+func (u User) Quota() Quota {
+	quota := Quota{
+		MaxDeployments: config.DEFAULT_MAX_DEPLOYMENTS,
+		MaxMemory:      config.DEFAULT_MAX_MEMORY,
+		MaxCPUs:        config.DEFAULT_MAX_CPUS,
+		MaxPublicPorts: config.DEFAULT_MAX_PUBLIC_PORTS,
+	}
+
+	if u.MaxDeployments != nil {
+		quota.MaxDeployments = *u.MaxDeployments
+	}
+	if u.MaxMemory != nil {
+		quota.MaxMemory = *u.MaxMemory
+	}
+	if u.MaxCPUs != nil {
+		quota.MaxCPUs = *u.MaxCPUs
+	}
+	if u.MaxPublicPorts != nil {
+		quota.MaxPublicPorts = *u.MaxPublicPorts
+	}
+
+	return quota
+}
+
+// ResourceUsage is what a single deployment running replicas containers with the given resources counts against a quota.
+// Deployments created before resource defaults existed are counted at the default limits.
+func ResourceUsage(resources ResourceSpec, replicas int) Usage {
+	resources = resources.WithDefaults()
+
+	return Usage{
+		Deployments: 1,
+		Memory:      *resources.MemoryLimit * int64(replicas),
+		CPUs:        resources.CPUs() * float64(replicas),
+	}
+}
+
+// Usage is what the deployment counts against its user's quota. While it's being updated that is the larger of
+// what it uses now and what the update reserved.
+func (d Deployment) Usage() Usage {
+	return d.Reservation(ResourceUsage(d.ResourceSpec, d.ReplicaCount()))
+}
+
+// Reservation is what an update using usage reserves. An update queued behind others keeps their reservation when
+// it's larger, the earlier updates still run before it.
+func (d Deployment) Reservation(usage Usage) Usage {
+	if d.ReservedMemory != nil && *d.ReservedMemory > usage.Memory {
+		usage.Memory = *d.ReservedMemory
+	}
+	if d.ReservedCPUs != nil && *d.ReservedCPUs > usage.CPUs {
+		usage.CPUs = *d.ReservedCPUs
+	}
+
+	return usage
+}
+
+func DeploymentsUsage(deployments []Deployment) Usage {
+	usage := Usage{}
+	for _, deployment := range deployments {
+		usage = usage.Add(deployment.Usage())
+	}
+
+	return usage
+}
+
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		Deployments: u.Deployments + other.Deployments,
+		Memory:      u.Memory + other.Memory,
+		CPUs:        u.CPUs + other.CPUs,
+		PublicPorts: u.PublicPorts + other.PublicPorts,
+	}
+}
+
+func (u Usage) Subtract(other Usage) Usage {
+	return Usage{
+		Deployments: u.Deployments - other.Deployments,
+		Memory:      u.Memory - other.Memory,
+		CPUs:        u.CPUs - other.CPUs,
+		PublicPorts: u.PublicPorts - other.PublicPorts,
+	}
+}
+
+// Check returns a QuotaExceededError describing every limit of the quota that the usage exceeds.
+func (q Quota) Check(usage Usage) error {
+	exceeded := []string{}
+
+	if usage.Deployments > q.MaxDeployments {
+		exceeded = append(exceeded, fmt.Sprintf("deployments (%d of %d)", usage.Deployments, q.MaxDeployments))
+	}
+	if usage.Memory > q.MaxMemory {
+		exceeded = append(exceeded, fmt.Sprintf("memory (%d of %d bytes)", usage.Memory, q.MaxMemory))
+	}
+	// Allow for floating point error when summing fractional CPUs
+	if usage.CPUs > q.MaxCPUs+1e-9 {
+		exceeded = append(exceeded, fmt.Sprintf("cpus (%g of %g)", usage.CPUs, q.MaxCPUs))
+	}
+
+	if usage.PublicPorts > q.MaxPublicPorts {
+		exceeded = append(exceeded, fmt.Sprintf("public ports (%d of %d)", usage.PublicPorts, q.MaxPublicPorts))
+	}
+
+	if len(exceeded) == 0 {
+		return nil
+	}
+
+	return QuotaExceededError{Exceeded: exceeded}
+}
+
+// CheckDeployments checks the usage of deployments with the one with uuid, or a new one when uuid is empty, using usage.
+func (q Quota) CheckDeployments(deployments []Deployment, uuid string, usage Usage) error {
+	total := usage
+	for _, deployment := range deployments {
+		if uuid != "" && *deployment.UUID == uuid {
+			continue
+		}
+		total = total.Add(deployment.Usage())
+	}
+
+	return q.Check(total)
+}
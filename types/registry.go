@@ -0,0 +1,81 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const DEFAULT_REGISTRY_HOST string = "docker.io"
+
+// RegistryCredential authenticates a user's image pulls from one registry, the token is encrypted at rest and never returned.
+type RegistryCredential struct {
+	ID     *int    `db:"id" json:"-"`
+	UUID   *string `db:"uuid" json:"uuid"`
+	UserId *int    `db:"user_id" json:"-"`
+
+	Host         *string `db:"host" json:"host"`
+	Username     *string `db:"username" json:"username"`
+	Ciphertext   []byte  `db:"ciphertext" json:"-"`
+	EncryptedKey []byte  `db:"encrypted_key" json:"-"`
+
+	CreatedAt *time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt *time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+type CreateRegistryCredentialRequest struct {
+	Host     string `json:"host" binding:"required"`
+	Username string `json:"username" binding:"required"`
+	Token    string `json:"token" binding:"required"`
+}
+
+type UpdateRegistryCredentialRequest struct {
+	Username string `json:"username" binding:"required"`
+	Token    string `json:"token" binding:"required"`
+}
+
+// RegistryCredentialAdditionalData binds the sealed token of a credential to its owner and host.
+func RegistryCredentialAdditionalData(userId int, host string) []byte {
+	return []byte(fmt.Sprintf("registry:%d:%s", userId, host))
+}
+
+// ImageRegistryHost returns the registry an image reference is pulled from, following the rules of the Docker CLI:
+// the first path component is a host if it contains a '.' or ':' or is localhost, otherwise the image is on Docker Hub.
+func ImageRegistryHost(image string) string {
+	first, _, hasPath := strings.Cut(image, "/")
+	if !hasPath || (!strings.ContainsAny(first, ".:") && first != "localhost") {
+		return DEFAULT_REGISTRY_HOST
+	}
+
+	return NormalizeRegistryHost(first)
+}
+
+// NormalizeRegistryHost lowercases a registry host and maps the aliases of Docker Hub to DEFAULT_REGISTRY_HOST.
+func NormalizeRegistryHost(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://"), "/"))
+
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return DEFAULT_REGISTRY_HOST
+	}
+
+	return host
+}
+
+// ImageRepository returns the repository of an image reference without its tag or digest, in the short form Docker
+// reports in the repo digests of an image, e.g. nginx for docker.io/library/nginx:1.25.
+func ImageRepository(image string) string {
+	repository, _, _ := strings.Cut(image, "@")
+	if colon := strings.LastIndex(repository, ":"); colon > strings.LastIndex(repository, "/") {
+		repository = repository[:colon]
+	}
+
+	if ImageRegistryHost(repository) != DEFAULT_REGISTRY_HOST {
+		return repository
+	}
+
+	if first, rest, hasPath := strings.Cut(repository, "/"); hasPath && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		repository = rest
+	}
+	return strings.TrimPrefix(repository, "library/")
+}
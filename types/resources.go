@@ -0,0 +1,107 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/SwarnimWalavalkar/container_provisioning_engine/config"
+)
+
+const DEFAULT_CPU_PERIOD int64 = 100000
+
+type Ulimit struct {
+	Name string `json:"name" binding:"required"`
+	Soft int64  `json:"soft" binding:"min=-1"`
+	Hard int64  `json:"hard" binding:"min=-1"`
+}
+
+// Ulimits is stored as a jsonb column.
+type Ulimits []Ulimit
+
+func (u Ulimits) Value() (driver.Value, error) {
+	if u == nil {
+		return nil, nil
+	}
+	return json.Marshal(u)
+}
+
+func (u *Ulimits) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*u = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(src, u)
+	case string:
+		return json.Unmarshal([]byte(src), u)
+	default:
+		return fmt.Errorf("cannot scan %T into Ulimits", src)
+	}
+}
+
+// ResourceSpec limits the host resources a deployment's container can use. Nil fields are unlimited,
+// except for memory and CPU which get defaults so that every deployment counts against its user's quota.
+type ResourceSpec struct {
+	CPUShares *int64 `db:"cpu_shares" json:"cpuShares" binding:"omitempty,min=2"`
+	// CPUQuota is the CPU time in microseconds the container can use every CPUPeriod, e.g. 50000 of 100000 is half a CPU
+	CPUQuota  *int64 `db:"cpu_quota" json:"cpuQuota" binding:"omitempty,min=1000"`
+	CPUPeriod *int64 `db:"cpu_period" json:"cpuPeriod" binding:"omitempty,min=1000,max=1000000"`
+
+	// Memory sizes are in bytes
+	MemoryLimit       *int64 `db:"memory_limit" json:"memoryLimit" binding:"omitempty,min=6291456"`
+	MemoryReservation *int64 `db:"memory_reservation" json:"memoryReservation" binding:"omitempty,min=0"`
+
+	PidsLimit *int64 `db:"pids_limit" json:"pidsLimit" binding:"omitempty,min=1"`
+
+	Ulimits Ulimits `db:"ulimits" json:"ulimits" binding:"omitempty,dive"`
+}
+
+// WithDefaults fills in the memory limit and CPU quota when they are not set. The default quota is scaled to a
+// CPUPeriod that is set, so that it allows the same share of a CPU.
+func (r ResourceSpec) WithDefaults() ResourceSpec {
+	if r.MemoryLimit == nil {
+		memoryLimit := config.DEFAULT_DEPLOYMENT_MEMORY_LIMIT
+		r.MemoryLimit = &memoryLimit
+	}
+
+	if r.CPUQuota == nil {
+		cpuQuota := config.DEFAULT_DEPLOYMENT_CPU_QUOTA
+		if r.CPUPeriod != nil {
+			cpuQuota = cpuQuota * *r.CPUPeriod / DEFAULT_CPU_PERIOD
+		}
+		r.CPUQuota = &cpuQuota
+	}
+
+	return r
+}
+
+// Validate checks the constraints between fields that can't be expressed with binding tags.
+func (r ResourceSpec) Validate() error {
+	if r.MemoryReservation != nil && r.MemoryLimit != nil && *r.MemoryReservation > *r.MemoryLimit {
+		return errors.New("memoryReservation must not be greater than memoryLimit")
+	}
+
+	for _, ulimit := range r.Ulimits {
+		if ulimit.Hard != -1 && (ulimit.Soft == -1 || ulimit.Soft > ulimit.Hard) {
+			return fmt.Errorf("soft limit of ulimit %s must not be greater than its hard limit", ulimit.Name)
+		}
+	}
+
+	return nil
+}
+
+// CPUs returns the number of CPUs the quota allows, or 0 when the deployment has no CPU quota.
+func (r ResourceSpec) CPUs() float64 {
+	if r.CPUQuota == nil {
+		return 0
+	}
+
+	period := DEFAULT_CPU_PERIOD
+	if r.CPUPeriod != nil {
+		period = *r.CPUPeriod
+	}
+
+	return float64(*r.CPUQuota) / float64(period)
+}
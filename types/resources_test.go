@@ -0,0 +1,15 @@
+package types
+
+import "testing"
+
+func TestWithDefaultsKeepsCPUPeriod(t *testing.T) {
+	period := int64(50000)
+	resources := ResourceSpec{CPUPeriod: &period}.WithDefaults()
+
+	if resources.CPUPeriod == nil || *resources.CPUPeriod != period {
+		t.Fatalf("expected the CPU period to be kept, got %v", resources.CPUPeriod)
+	}
+	if *resources.CPUQuota != 50000 || resources.CPUs() != 1 {
+		t.Errorf("expected the default quota to allow one CPU, got %d (%g CPUs)", *resources.CPUQuota, resources.CPUs())
+	}
+}
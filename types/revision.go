@@ -0,0 +1,57 @@
+package types
+
+import "time"
+
+// DeploymentRevision is an immutable record of a configuration that was applied to a deployment.
+// Replicas are left out, scaling a deployment doesn't create a revision.
+type DeploymentRevision struct {
+	ID           *int `db:"id" json:"-"`
+	DeploymentId *int `db:"deployment_id" json:"-"`
+	Revision     *int `db:"revision" json:"revision"`
+
+	ImageTag  *string   `db:"image_tag" json:"imageTag"`
+	Subdomain *string   `db:"sub_domain" json:"subDomain"`
+	Port      *int      `db:"port" json:"port"`
+	EnvConfig EnvConfig `db:"env_config" json:"envConfig"`
+
+	ResourceSpec `json:"resources"`
+	HealthCheck  *HealthCheck    `db:"health_check" json:"healthCheck"`
+	Routing      *RoutingSpec    `db:"routing" json:"routing"`
+	Middlewares  *MiddlewareSpec `db:"middlewares" json:"middlewares"`
+
+	// RolledBackFrom is the revision this one was copied from when it was created by a rollback
+	RolledBackFrom *int `db:"rolled_back_from" json:"rolledBackFrom"`
+
+	CreatedBy *string    `db:"created_by" json:"createdBy"`
+	CreatedAt *time.Time `db:"created_at" json:"createdAt"`
+}
+
+// Redacted returns the revision as it is returned by the API, without the values of its env config and the password hashes of its middlewares.
+func (r DeploymentRevision) Redacted() DeploymentRevision {
+	r.EnvConfig = r.EnvConfig.Redacted()
+	r.Middlewares = r.Middlewares.Redacted()
+	return r
+}
+
+type DeploymentRevisionAttributes struct {
+	DeploymentUUID string
+
+	ImageTag  string
+	Subdomain string
+	Port      int
+	EnvConfig EnvConfig
+
+	ResourceSpec
+	HealthCheck *HealthCheck
+	Routing     *RoutingSpec
+	Middlewares *MiddlewareSpec
+
+	RolledBackFrom *int
+	// CreatedBy is the uuid of the user that requested the change
+	CreatedBy *string
+}
+
+type RollbackDeploymentRequest struct {
+	Revision   int         `json:"revision" binding:"required,min=1"`
+	DockerAuth *dockerAuth `json:"dockerAuth"`
+}
@@ -0,0 +1,130 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// headerNamePattern leaves the '.' of HTTP tokens out, header names become part of Traefik label keys where it separates segments
+var headerNamePattern = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+^_|~-]+$`)
+
+// pathPrefixPattern only allows characters that need no quoting in a Traefik rule or a Caddy or nginx configuration
+var pathPrefixPattern = regexp.MustCompile(`^/[A-Za-z0-9._~/-]*$`)
+
+// RoutingSpec narrows down the requests that reach a deployment so that several deployments can share a hostname,
+// e.g. /api served by one deployment and everything else by another.
+type RoutingSpec struct {
+	// Subdomain is the hostname the deployment is served on, it defaults to the deployment's own subdomain
+	// and can be the subdomain of another deployment of the same user
+	Subdomain  string `json:"subdomain,omitempty"`
+	PathPrefix string `json:"pathPrefix,omitempty"`
+	// StripPrefix removes PathPrefix from the path before the request is passed to the container
+	StripPrefix bool              `json:"stripPrefix,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	// Priority decides between routers matching the same request, Traefik prefers longer rules by default
+	Priority int `json:"priority,omitempty" binding:"omitempty,min=1"`
+}
+
+func (r RoutingSpec) IsZero() bool {
+	return r.Subdomain == "" && r.PathPrefix == "" && !r.StripPrefix && len(r.Headers) == 0 && r.Priority == 0
+}
+
+func (r RoutingSpec) Validate() error {
+	if r.Subdomain != "" {
+		if err := ValidateSubdomain(r.Subdomain); err != nil {
+			return err
+		}
+	}
+
+	if r.PathPrefix != "" && !pathPrefixPattern.MatchString(r.PathPrefix) {
+		return errors.New("pathPrefix must start with / and can only contain letters, digits and the characters -._~/")
+	}
+
+	if r.StripPrefix && r.PathPrefix == "" {
+		return errors.New("stripPrefix requires a pathPrefix")
+	}
+
+	for name, value := range r.Headers {
+		if !headerNamePattern.MatchString(name) {
+			return fmt.Errorf("%s is not a valid header name", name)
+		}
+		if strings.ContainsAny(value, "`\r\n") {
+			return fmt.Errorf("the value of header %s can't contain backticks or line breaks", name)
+		}
+	}
+
+	return nil
+}
+
+// ForSubdomain returns the routing of a deployment on subdomain, leaving out a Subdomain equal to it. It's nil when nothing is left.
+func (r *RoutingSpec) ForSubdomain(subdomain string) *RoutingSpec {
+	if r == nil {
+		return nil
+	}
+
+	routing := *r
+	if routing.Subdomain == subdomain {
+		routing.Subdomain = ""
+	}
+	if routing.IsZero() {
+		return nil
+	}
+	return &routing
+}
+
+// Matchers returns the Traefik rule matchers added to the Host matcher, empty when every path and header matches.
+func (r RoutingSpec) Matchers() string {
+	matchers := []string{}
+	if r.PathPrefix != "" {
+		matchers = append(matchers, fmt.Sprintf("PathPrefix(`%s`)", r.PathPrefix))
+	}
+
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		matchers = append(matchers, fmt.Sprintf("Headers(`%s`, `%s`)", name, r.Headers[name]))
+	}
+
+	return strings.Join(matchers, " && ")
+}
+
+// RoutingSubdomain returns the subdomain the deployment is served on.
+func (d Deployment) RoutingSubdomain() string {
+	if d.Routing != nil && d.Routing.Subdomain != "" {
+		return d.Routing.Subdomain
+	}
+	return *d.Subdomain
+}
+
+// RoutingMatchers returns the matchers of the deployment's routing, deployments on the same subdomain must differ in them.
+func (d Deployment) RoutingMatchers() string {
+	if d.Routing == nil {
+		return ""
+	}
+	return d.Routing.Matchers()
+}
+
+// RoutingSpec is stored as a jsonb column.
+func (r RoutingSpec) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+func (r *RoutingSpec) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, r)
+	case string:
+		return json.Unmarshal([]byte(src), r)
+	default:
+		return fmt.Errorf("cannot scan %T into RoutingSpec", src)
+	}
+}
@@ -0,0 +1,31 @@
+package types
+
+import "testing"
+
+func TestRoutingValidate(t *testing.T) {
+	cases := map[string]struct {
+		routing RoutingSpec
+		valid   bool
+	}{
+		"path prefix":                {RoutingSpec{PathPrefix: "/api/v1", StripPrefix: true}, true},
+		"header":                     {RoutingSpec{Headers: map[string]string{"X-Tenant": "acme"}}, true},
+		"invalid subdomain":          {RoutingSpec{Subdomain: "Web_App"}, false},
+		"relative path prefix":       {RoutingSpec{PathPrefix: "api"}, false},
+		"path prefix with backtick":  {RoutingSpec{PathPrefix: "/api`"}, false},
+		"strip prefix without path":  {RoutingSpec{StripPrefix: true}, false},
+		"header name with dot":       {RoutingSpec{Headers: map[string]string{"X.Tenant": "acme"}}, false},
+		"header name with space":     {RoutingSpec{Headers: map[string]string{"X Tenant": "acme"}}, false},
+		"header value with backtick": {RoutingSpec{Headers: map[string]string{"X-Tenant": "a`b"}}, false},
+		"header value with newline":  {RoutingSpec{Headers: map[string]string{"X-Tenant": "a\nb"}}, false},
+	}
+
+	for name, c := range cases {
+		err := c.routing.Validate()
+		if c.valid && err != nil {
+			t.Errorf("%s: expected the routing to be valid, got %s", name, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected the routing to be refused", name)
+		}
+	}
+}
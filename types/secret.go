@@ -0,0 +1,73 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// SECRETS_DIR is where the secrets a deployment refers to are written in its containers
+const SECRETS_DIR string = "/run/secrets"
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// An env value of exactly ${secret:NAME} is replaced by the value of the user's secret NAME when a container is provisioned
+var secretReferencePattern = regexp.MustCompile(`^\$\{secret:([A-Za-z_][A-Za-z0-9_.-]*)\}$`)
+
+// Secret is a named value encrypted at rest, the value itself is never returned by the API.
+type Secret struct {
+	ID     *int `db:"id" json:"-"`
+	UserId *int `db:"user_id" json:"-"`
+
+	Name         *string `db:"name" json:"name"`
+	Ciphertext   []byte  `db:"ciphertext" json:"-"`
+	EncryptedKey []byte  `db:"encrypted_key" json:"-"`
+
+	CreatedAt *time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt *time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+type CreateSecretRequest struct {
+	Name  string `json:"name" binding:"required,max=128"`
+	Value string `json:"value" binding:"required"`
+}
+
+type UpdateSecretRequest struct {
+	Value string `json:"value" binding:"required"`
+}
+
+func ValidSecretName(name string) bool {
+	return secretNamePattern.MatchString(name)
+}
+
+// SecretAdditionalData binds the sealed value of a secret to its owner and name, so that it can't be moved to another row.
+func SecretAdditionalData(userId int, name string) []byte {
+	return []byte(fmt.Sprintf("secret:%d:%s", userId, name))
+}
+
+// SecretFilePath is the path of the file the secret name is written to in a container.
+func SecretFilePath(name string) string {
+	return fmt.Sprintf("%s/%s", SECRETS_DIR, name)
+}
+
+// SecretReference returns the name of the secret an env value refers to.
+func SecretReference(value string) (string, bool) {
+	match := secretReferencePattern.FindStringSubmatch(value)
+	if match == nil {
+		return "", false
+	}
+
+	return match[1], true
+}
+
+// SecretReferences returns the names of every secret the env config refers to.
+func (e EnvConfig) SecretReferences() []string {
+	names := []string{}
+	for _, value := range e {
+		if name, ok := SecretReference(value); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
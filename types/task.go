@@ -0,0 +1,30 @@
+package types
+
+import "time"
+
+type TaskRecord struct {
+	ID     *int    `db:"id" json:"-"`
+	UserId *int    `db:"user_id" json:"-"`
+	UUID   *string `db:"uuid" json:"uuid"`
+
+	DeploymentUUID *string `db:"deployment_uuid" json:"deploymentUUID"`
+
+	Type    *string `db:"type" json:"type"`
+	Payload []byte  `db:"payload" json:"-"`
+
+	Status *string `db:"status" json:"status"`
+
+	Attempts    *int       `db:"attempts" json:"attempts"`
+	MaxAttempts *int       `db:"max_attempts" json:"maxAttempts"`
+	RunAt       *time.Time `db:"run_at" json:"runAt"`
+	LastError   *string    `db:"last_error" json:"lastError"`
+
+	// LeaseExpiresAt is when a RUNNING task can be claimed again, its worker renews it while the task runs
+	LeaseExpiresAt *time.Time `db:"lease_expires_at" json:"-"`
+
+	StartedAt  *time.Time `db:"started_at" json:"startedAt"`
+	FinishedAt *time.Time `db:"finished_at" json:"finishedAt"`
+
+	CreatedAt *time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt *time.Time `db:"updated_at" json:"updatedAt"`
+}
@@ -3,10 +3,16 @@ package types
 import "time"
 
 type User struct {
-	ID        *int       `db:"id" json:"-"`
-	UUID      *string    `db:"uuid" json:"uuid"`
-	Username  *string    `db:"username" json:"username"`
-	ApiKey    *string    `db:"api_key" json:"-"`
+	ID       *int    `db:"id" json:"-"`
+	UUID     *string `db:"uuid" json:"uuid"`
+	Username *string `db:"username" json:"username"`
+	ApiKey   *string `db:"api_key" json:"-"`
+
+	MaxDeployments *int     `db:"max_deployments" json:"-"`
+	MaxMemory      *int64   `db:"max_memory" json:"-"`
+	MaxCPUs        *float64 `db:"max_cpus" json:"-"`
+	MaxPublicPorts *int     `db:"max_public_ports" json:"-"`
+
 	CreatedAt *time.Time `db:"created_at" json:"-"`
 	UpdatedAt *time.Time `db:"updated_at" json:"-"`
 }
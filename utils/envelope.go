@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// SecretsKey decodes SECRETS_KEY, the base64 encoded 32 byte master key that data encrypted at rest is sealed with.
+func SecretsKey() ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(os.Getenv("SECRETS_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SECRETS_KEY: %w", err)
+	}
+
+	if len(key) != 32 {
+		return nil, errors.New("SECRETS_KEY must be 32 bytes encoded as base64")
+	}
+
+	return key, nil
+}
+
+// SealEnvelope encrypts plaintext with a fresh data key, and the data key with masterKey, both with AES-256-GCM.
+// Only the encrypted data key is stored next to the ciphertext, so rotating the master key means re-encrypting data keys only.
+// additionalData binds the ciphertext to the row it's stored in, OpenEnvelope fails unless it's given the same.
+func SealEnvelope(masterKey []byte, plaintext []byte, additionalData []byte) (ciphertext []byte, encryptedKey []byte, err error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+
+	ciphertext, err = sealGCM(dataKey, plaintext, additionalData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encryptedKey, err = sealGCM(masterKey, dataKey, additionalData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ciphertext, encryptedKey, nil
+}
+
+func OpenEnvelope(masterKey []byte, ciphertext []byte, encryptedKey []byte, additionalData []byte) ([]byte, error) {
+	dataKey, err := openGCM(masterKey, encryptedKey, additionalData)
+	if err != nil {
+		return nil, err
+	}
+
+	return openGCM(dataKey, ciphertext, additionalData)
+}
+
+// sealGCM returns the nonce followed by the sealed plaintext.
+func sealGCM(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func openGCM(key []byte, sealed []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	key := newKey(t)
+	additionalData := []byte("secret:1:DB_PASSWORD")
+
+	ciphertext, encryptedKey, err := SealEnvelope(key, []byte("hunter2"), additionalData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, []byte("hunter2")) {
+		t.Fatal("expected the plaintext not to appear in the ciphertext")
+	}
+
+	plaintext, err := OpenEnvelope(key, ciphertext, encryptedKey, additionalData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "hunter2" {
+		t.Errorf("expected hunter2, got %s", plaintext)
+	}
+}
+
+func TestOpenEnvelopeFailures(t *testing.T) {
+	key := newKey(t)
+	additionalData := []byte("secret:1:DB_PASSWORD")
+
+	ciphertext, encryptedKey, err := SealEnvelope(key, []byte("hunter2"), additionalData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := append([]byte{}, ciphertext...)
+	tampered[len(tampered)-1] ^= 1
+
+	cases := map[string]struct {
+		key            []byte
+		ciphertext     []byte
+		additionalData []byte
+	}{
+		"another user":        {key, ciphertext, []byte("secret:2:DB_PASSWORD")},
+		"another secret name": {key, ciphertext, []byte("secret:1:API_TOKEN")},
+		"tampered ciphertext": {key, tampered, additionalData},
+		"wrong key":           {newKey(t), ciphertext, additionalData},
+	}
+
+	for name, c := range cases {
+		if _, err := OpenEnvelope(c.key, c.ciphertext, encryptedKey, c.additionalData); err == nil {
+			t.Errorf("%s: expected the envelope not to open", name)
+		}
+	}
+}
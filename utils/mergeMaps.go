@@ -11,3 +11,18 @@ func MergeMaps(maps ...map[string]string) map[string]string {
 
 	return result
 }
+
+// PatchMap returns a copy of base with every key of patch set to its value, or removed when the value is nil.
+func PatchMap(base map[string]string, patch map[string]*string) map[string]string {
+	result := MergeMaps(base)
+
+	for k, v := range patch {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		result[k] = *v
+	}
+
+	return result
+}